@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to the commands reserved to the system admins
+
+const (
+	subcommandAdmin = "admin"
+
+	adminActionTestConfig = "test-config"
+
+	// Keywords of the search used to test the GIF provider
+	testConfigKeywords = "hello"
+)
+
+func getAdminUsage(trigger string) string {
+	return fmt.Sprintf("Usage (system admins only):\n"+
+		"* `/%s admin test-config`: check the plugin configuration and test the GIF provider, the report is sent as a direct message", trigger)
+}
+
+// executeCommandAdmin handles the /gif admin subcommand
+func (p *Plugin) executeCommandAdmin(snapshot *pluginSnapshot, parameters []string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	if !p.API.HasPermissionTo(args.UserId, model.PermissionManageSystem) {
+		return ephemeralResponse("Only system admins can use this command."), nil
+	}
+	if len(parameters) != 1 || parameters[0] != adminActionTestConfig {
+		return ephemeralResponse(getAdminUsage(getCommandTrigger(args.Command))), nil
+	}
+
+	channel, appErr := p.API.GetDirectChannel(args.UserId, snapshot.botID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	report := p.generateConfigurationReport()
+	if _, appErr = p.API.CreatePost(&model.Post{ChannelId: channel.Id, UserId: snapshot.botID, Message: report}); appErr != nil {
+		return nil, appErr
+	}
+	return ephemeralResponse("The configuration report was sent to you as a direct message."), nil
+}
+
+// generateConfigurationReport returns a Markdown report of the issues of the configuration saved in the System Console
+// and of a test search with the GIF provider. The saved configuration is loaded again, because the plugin keeps using
+// the previous one when it is invalid.
+func (p *Plugin) generateConfigurationReport() string {
+	lines := []string{"#### GIF commands configuration report"}
+	config := new(pluginConf.Configuration)
+	if err := p.API.LoadPluginConfiguration(config); err != nil {
+		return strings.Join(append(lines, "- :x: **Configuration**: could not be loaded: "+err.Error()), "\n")
+	}
+	issues := checkConfiguration(config)
+	for _, issue := range issues {
+		icon := ":warning:"
+		if issue.Blocking {
+			icon = ":x:"
+		}
+		lines = append(lines, fmt.Sprintf("- %s **%s**: %s", icon, issue.Setting, issue.Message))
+	}
+	if len(issues) == 0 {
+		lines = append(lines, "- :white_check_mark: **Settings**: no problem found")
+	}
+	lines = append(lines, "- "+p.testGifProvider(config))
+	return strings.Join(lines, "\n")
+}
+
+// checkConfiguration prepares a loaded configuration like OnConfigurationChange, and returns all its issues.
+// The auto-reply rules and the commands that can't be parsed are reported as blocking issues.
+func checkConfiguration(config *pluginConf.Configuration) []pluginConf.ValidationIssue {
+	issues := []pluginConf.ValidationIssue{}
+	autoReplyRules, err := pluginConf.ParseAutoReplyRules(config.AutoReplyRules)
+	if err != nil {
+		issues = append(issues, pluginConf.ValidationIssue{Setting: "Automatic GIF replies", Message: err.Error(), Blocking: true})
+	}
+	config.ParsedAutoReplyRules = autoReplyRules
+	if err = computeCommandTriggers(config); err != nil {
+		issues = append(issues, pluginConf.ValidationIssue{Setting: "Commands", Message: err.Error(), Blocking: true})
+	}
+	return append(issues, validateConfiguration(config)...)
+}
+
+// testGifProvider searches a GIF with a provider created from the configuration, and describes the result
+func (p *Plugin) testGifProvider(config *pluginConf.Configuration) string {
+	gifProvider, appErr := provider.GifProviderGenerator(*config, p.errorGenerator, p.rootURL)
+	if appErr != nil {
+		return ":x: **GIF Provider**: could not be created: " + formatAppError(appErr)
+	}
+	ctx, cancel := newSearchContext()
+	defer cancel()
+	cursor := ""
+	start := time.Now()
+	gif, appErr := gifProvider.GetGif(ctx, provider.Query{Keywords: testConfigKeywords, Budget: getRenditionBudget(config, "")}, &cursor)
+	duration := time.Since(start).Round(time.Millisecond)
+	if appErr != nil {
+		return fmt.Sprintf(":x: **GIF Provider**: the test search for '%s' failed after %s: %s", testConfigKeywords, duration, formatAppError(appErr))
+	}
+	if gif == nil {
+		return fmt.Sprintf(":warning: **GIF Provider**: the test search for '%s' found no GIF in %s", testConfigKeywords, duration)
+	}
+	return fmt.Sprintf(":white_check_mark: **GIF Provider**: %s found a GIF for '%s' in %s: %s", config.Provider, testConfigKeywords, duration, gif.URL)
+}
+
+func formatAppError(appErr *model.AppError) string {
+	if appErr.DetailedError != "" {
+		return appErr.Message + " (" + appErr.DetailedError + ")"
+	}
+	return appErr.Message
+}
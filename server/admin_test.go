@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	pluginError "github.com/moussetc/mattermost-plugin-giphy/server/internal/error"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+const testDirectChannelID = "direct-channel"
+
+func mockGifProviderGenerator(t *testing.T, gifProvider provider.GifProvider, appErr *model.AppError) {
+	defaultGenerator := provider.GifProviderGenerator
+	provider.GifProviderGenerator = func(configuration pluginConf.Configuration, errorGenerator pluginError.PluginError, rootURL string) (provider.GifProvider, *model.AppError) {
+		return gifProvider, appErr
+	}
+	t.Cleanup(func() { provider.GifProviderGenerator = defaultGenerator })
+}
+
+// mockSavedConfiguration changes the configuration loaded from the System Console, without publishing it
+func mockSavedConfiguration(api *plugintest.API, config pluginConf.Configuration) {
+	for _, call := range api.ExpectedCalls {
+		if call.Method == "LoadPluginConfiguration" {
+			call.ReturnArguments = mock.Arguments{mockLoadConfig(config)}
+		}
+	}
+}
+
+func TestExecuteCommandAdminShouldBeReservedToSystemAdmins(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionTo", testUserID, model.PermissionManageSystem).Return(false)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif admin test-config", UserId: testUserID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "Only system admins")
+	api.AssertNotCalled(t, "CreatePost", mock.Anything)
+}
+
+func TestExecuteCommandAdminTestConfigShouldSendReportAsDirectMessage(t *testing.T) {
+	api, p := initMockAPI()
+	mockGifProviderGenerator(t, &mockGifProvider{testGifURL}, nil)
+	config := generateMockPluginConfig()
+	config.RenditionFallbacks = "tinygif"
+	mockSavedConfiguration(api, config)
+	api.On("HasPermissionTo", testUserID, model.PermissionManageSystem).Return(true)
+	api.On("GetDirectChannel", testUserID, p.getSnapshot().botID).Return(&model.Channel{Id: testDirectChannelID}, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif admin test-config", UserId: testUserID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "direct message")
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == testDirectChannelID &&
+			post.UserId == p.getSnapshot().botID &&
+			strings.Contains(post.Message, ":warning: **Fallback display styles**: unknown display style 'tinygif'") &&
+			strings.Contains(post.Message, ":white_check_mark: **GIF Provider**") &&
+			strings.Contains(post.Message, testGifURL)
+	}))
+}
+
+func TestGenerateConfigurationReportShouldReportProviderErrors(t *testing.T) {
+	_, p := initMockAPI()
+	mockGifProviderGenerator(t, &mockGifProviderFail{"invalid API key"}, nil)
+
+	report := p.generateConfigurationReport()
+
+	assert.Contains(t, report, ":white_check_mark: **Settings**: no problem found")
+	assert.Contains(t, report, ":x: **GIF Provider**: the test search for 'hello' failed")
+	assert.Contains(t, report, "invalid API key")
+}
+
+func TestGenerateConfigurationReportShouldReportBlockingIssues(t *testing.T) {
+	api, p := initMockAPI()
+	mockGifProviderGenerator(t, nil, &model.AppError{Message: "apiKey cannot be empty for Giphy Provider"})
+	config := generateMockPluginConfig()
+	config.APIKey = ""
+	mockSavedConfiguration(api, config)
+
+	report := p.generateConfigurationReport()
+
+	assert.Contains(t, report, ":x: **API Key**: an API key is required for giphy")
+	assert.Contains(t, report, ":x: **GIF Provider**: could not be created")
+}
+
+func TestGenerateConfigurationReportShouldReportInvalidRulesAndCommands(t *testing.T) {
+	api, p := initMockAPI()
+	mockGifProviderGenerator(t, &mockGifProvider{testGifURL}, nil)
+	config := generateMockPluginConfig()
+	config.AutoReplyRules = "[{"
+	config.InstantCommandTrigger = "gifs"
+	mockSavedConfiguration(api, config)
+
+	report := p.generateConfigurationReport()
+
+	assert.Contains(t, report, ":x: **Automatic GIF replies**")
+	assert.Contains(t, report, ":x: **Commands**: the commands to post and preview GIFs must be different: /gifs")
+	assert.Contains(t, report, ":white_check_mark: **GIF Provider**")
+}
+
+func TestGenerateConfigurationReportShouldTestTheSavedConfiguration(t *testing.T) {
+	api, p := initMockAPI()
+	var testedConfig pluginConf.Configuration
+	defaultGenerator := provider.GifProviderGenerator
+	provider.GifProviderGenerator = func(configuration pluginConf.Configuration, errorGenerator pluginError.PluginError, rootURL string) (provider.GifProvider, *model.AppError) {
+		testedConfig = configuration
+		return &mockGifProvider{testGifURL}, nil
+	}
+	t.Cleanup(func() { provider.GifProviderGenerator = defaultGenerator })
+	config := generateMockPluginConfig()
+	config.Provider = "tenor"
+	config.APIKey = "newAPIKey"
+	mockSavedConfiguration(api, config)
+
+	report := p.generateConfigurationReport()
+
+	assert.Equal(t, "newAPIKey", testedConfig.APIKey)
+	assert.Contains(t, report, ":white_check_mark: **GIF Provider**: tenor found a GIF")
+	assert.Equal(t, "giphy", p.getSnapshot().configuration.Provider)
+}
+
+func TestGenerateConfigurationReportShouldReportLoadErrors(t *testing.T) {
+	api, p := initMockAPI()
+	for _, call := range api.ExpectedCalls {
+		if call.Method == "LoadPluginConfiguration" {
+			call.ReturnArguments = mock.Arguments{errors.New("invalid JSON")}
+		}
+	}
+
+	report := p.generateConfigurationReport()
+
+	assert.Contains(t, report, ":x: **Configuration**: could not be loaded: invalid JSON")
+}
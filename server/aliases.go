@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to the GIF aliases shared by the members of a team, ex: `/gif :deploy-success`
+
+const (
+	subcommandAlias = "alias"
+
+	aliasActionAdd    = "add"
+	aliasActionRemove = "remove"
+	aliasActionList   = "list"
+
+	aliasKeyPrefix = "alias_"
+	// Prefix of the key of the alias names of a team
+	aliasIndexKeyPrefix = "aliases_"
+	// Prefix of the keywords that designate an alias instead of a search
+	aliasTokenPrefix = ":"
+	// Maximum number of GIFs per alias, the oldest ones are removed first
+	maxAliasGifs = 100
+	// Provider of the alias GIFs, that were added by their URL instead of being found by a GIF provider
+	aliasGifProvider = "alias"
+)
+
+var aliasNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Characters refused in the URLs of the alias GIFs, since they could end the Markdown link or image of the GIF posts
+const aliasURLForbiddenCharacters = "()[]<>!@"
+
+func getAliasUsage(trigger string) string {
+	return fmt.Sprintf("Usage:\n"+
+		"* `/%[1]s alias add <name> <GIF URL>`: add a GIF to the alias of this team, ex: `/%[1]s alias add deploy-success https://media.giphy.com/media/xyz/giphy.gif`\n"+
+		"* `/%[1]s alias remove <name> [GIF URL]`: remove a GIF from the alias, or the whole alias\n"+
+		"* `/%[1]s alias list [name]`: list the aliases of this team, or the GIFs of an alias\n"+
+		"* `/%[1]s :<name>`: post a random GIF of the alias\n"+
+		"Alias names may contain up to 32 lowercase letters, digits, `-` and `_`.", trigger)
+}
+
+// parseAliasToken returns the alias name if the keywords designate an alias
+func parseAliasToken(keywords string) (string, bool) {
+	if !strings.HasPrefix(keywords, aliasTokenPrefix) {
+		return "", false
+	}
+	name := strings.ToLower(strings.TrimPrefix(keywords, aliasTokenPrefix))
+	return name, aliasNamePattern.MatchString(name)
+}
+
+func aliasKey(teamID, name string) string {
+	return aliasKeyPrefix + teamID + "_" + name
+}
+
+func aliasIndexKey(teamID string) string {
+	return aliasIndexKeyPrefix + teamID
+}
+
+// updateAliasIndex atomically applies an update to the sorted alias names of the team
+func (p *Plugin) updateAliasIndex(teamID string, update func(names []string) []string) *model.AppError {
+	return p.updateKVIndex(aliasIndexKey(teamID), "GIF aliases", update)
+}
+
+// addAliasName inserts the alias name in the sorted names, if it is not already there
+func addAliasName(names []string, name string) []string {
+	i := sort.SearchStrings(names, name)
+	if i < len(names) && names[i] == name {
+		return names
+	}
+	return append(names[:i], append([]string{name}, names[i:]...)...)
+}
+
+func (p *Plugin) getAliasGifs(teamID, name string) ([]*savedGif, *model.AppError) {
+	gifs, appErr := p.getSavedGifs(aliasKey(teamID, name))
+	for _, gif := range gifs {
+		gif.Provider = aliasGifProvider
+	}
+	return gifs, appErr
+}
+
+// executeCommandAlias handles the /gif alias subcommand
+func (p *Plugin) executeCommandAlias(snapshot *pluginSnapshot, parameters []string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	trigger := getCommandTrigger(args.Command)
+	if len(parameters) == 0 {
+		return ephemeralResponse(getAliasUsage(trigger)), nil
+	}
+	if !p.API.HasPermissionToTeam(args.UserId, args.TeamId, model.PermissionViewTeam) {
+		return nil, p.errorGenerator.FromMessage("Only the team members can manage the team's aliases")
+	}
+	action, parameters := parameters[0], parameters[1:]
+	if action == aliasActionList && len(parameters) == 0 {
+		return p.listAliases(args)
+	}
+	if len(parameters) == 0 {
+		return ephemeralResponse(getAliasUsage(trigger)), nil
+	}
+	name := strings.ToLower(strings.TrimPrefix(parameters[0], aliasTokenPrefix))
+	if !aliasNamePattern.MatchString(name) {
+		return ephemeralResponse("Invalid alias name '" + escapeMarkdown(parameters[0]) + "'.\n" + getAliasUsage(trigger)), nil
+	}
+	switch {
+	case action == aliasActionAdd && len(parameters) == 2:
+		return p.addAliasGif(name, parameters[1], args)
+	case action == aliasActionRemove && len(parameters) <= 2:
+		return p.removeAliasGif(name, strings.Join(parameters[1:], ""), args)
+	case action == aliasActionList && len(parameters) == 1:
+		return p.listAliasGifs(name, args)
+	}
+	return ephemeralResponse(getAliasUsage(trigger)), nil
+}
+
+func (p *Plugin) addAliasGif(name, gifURL string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	parsedURL, err := url.Parse(gifURL)
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return ephemeralResponse("Invalid GIF URL '" + escapeMarkdown(gifURL) + "': only http and https URLs are allowed."), nil
+	}
+	if strings.ContainsAny(gifURL, aliasURLForbiddenCharacters) || strings.IndexFunc(gifURL, unicode.IsSpace) >= 0 {
+		return ephemeralResponse("Invalid GIF URL '" + escapeMarkdown(gifURL) + "': the URL must not contain spaces or any of `" + aliasURLForbiddenCharacters + "`."), nil
+	}
+	gif := &savedGif{
+		URL:      gifURL,
+		Keywords: aliasTokenPrefix + name,
+		Provider: aliasGifProvider,
+		SavedAt:  model.GetMillis(),
+	}
+	appErr := p.updateSavedGifs(aliasKey(args.TeamId, name), func(gifs []*savedGif) []*savedGif {
+		return prependSavedGif(gifs, gif, maxAliasGifs)
+	})
+	if appErr != nil {
+		return nil, appErr
+	}
+	if appErr = p.updateAliasIndex(args.TeamId, func(names []string) []string { return addAliasName(names, name) }); appErr != nil {
+		return nil, appErr
+	}
+	return ephemeralResponse("GIF added to the alias `" + aliasTokenPrefix + name + "`."), nil
+}
+
+func (p *Plugin) removeAliasGif(name, gifURL string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	key := aliasKey(args.TeamId, name)
+	if gifURL == "" {
+		if appErr := p.API.KVDelete(key); appErr != nil {
+			return nil, appErr
+		}
+		if appErr := p.updateAliasIndex(args.TeamId, func(names []string) []string { return removeIndexEntry(names, name) }); appErr != nil {
+			return nil, appErr
+		}
+		return ephemeralResponse("Alias `" + aliasTokenPrefix + name + "` removed."), nil
+	}
+	found, empty := false, false
+	appErr := p.updateSavedGifs(key, func(gifs []*savedGif) []*savedGif {
+		found = false
+		result := []*savedGif{}
+		for _, gif := range gifs {
+			if gif.URL == gifURL {
+				found = true
+			} else {
+				result = append(result, gif)
+			}
+		}
+		empty = len(result) == 0
+		return result
+	})
+	if appErr != nil {
+		return nil, appErr
+	}
+	// An alias without GIFs does not exist anymore
+	if empty {
+		if appErr = p.updateAliasIndex(args.TeamId, func(names []string) []string { return removeIndexEntry(names, name) }); appErr != nil {
+			return nil, appErr
+		}
+	}
+	if !found {
+		return ephemeralResponse("This GIF is not part of the alias `" + aliasTokenPrefix + name + "`."), nil
+	}
+	return ephemeralResponse("GIF removed from the alias `" + aliasTokenPrefix + name + "`."), nil
+}
+
+func (p *Plugin) listAliases(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	names, appErr := p.getKVIndex(aliasIndexKey(args.TeamId), "GIF aliases")
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(names) == 0 {
+		return ephemeralResponse("There is no GIF alias in this team yet.\n" + getAliasUsage(getCommandTrigger(args.Command))), nil
+	}
+	tokens := make([]string, len(names))
+	for i, name := range names {
+		tokens[i] = "`" + aliasTokenPrefix + name + "`"
+	}
+	return ephemeralResponse("GIF aliases of this team: " + strings.Join(tokens, ", ")), nil
+}
+
+func (p *Plugin) listAliasGifs(name string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	gifs, appErr := p.getAliasGifs(args.TeamId, name)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(gifs) == 0 {
+		return ephemeralResponse("There is no GIF alias `" + aliasTokenPrefix + name + "` in this team."), nil
+	}
+	lines := []string{"GIFs of the alias `" + aliasTokenPrefix + name + "`:"}
+	for _, gif := range gifs {
+		lines = append(lines, "* "+gif.URL)
+	}
+	return ephemeralResponse(strings.Join(lines, "\n")), nil
+}
+
+// executeCommandGifFromAlias posts a random GIF of the alias, or previews the GIFs of the alias
+func (p *Plugin) executeCommandGifFromAlias(snapshot *pluginSnapshot, name, caption string, withPreview bool, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	// There is no command line when the alias comes from the dialog of the Respond button
+	trigger := getCommandTrigger(args.Command)
+	if trigger == "" {
+		trigger = snapshot.configuration.CommandTriggerGifWithPreview
+		if trigger == "" {
+			trigger = snapshot.configuration.CommandTriggerGif
+		}
+	}
+	emptyMessage := "There is no GIF alias `" + aliasTokenPrefix + name + "` in this team, use `/" + trigger + " alias add` to create it."
+	if withPreview {
+		return p.executeCommandCollectionPreview(snapshot, sourceAlias, aliasTokenPrefix+name, "", caption, emptyMessage, args)
+	}
+	if !p.API.HasPermissionToTeam(args.UserId, args.TeamId, model.PermissionViewTeam) {
+		return nil, p.errorGenerator.FromMessage("Only the team members can use the team's aliases")
+	}
+	gifs, appErr := p.getAliasGifs(args.TeamId, name)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(gifs) == 0 {
+		return ephemeralResponse(emptyMessage), nil
+	}
+	gif := gifs[int(randomFloat()*float64(len(gifs)))]
+	p.recordRecentGif(snapshot, args.UserId, gif)
+	p.recordChannelGif(snapshot, args.ChannelId, gif)
+	return generateGifCommandResponse(snapshot, gif, caption), nil
+}
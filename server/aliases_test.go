@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+const testTeamID = "gifs-team"
+
+var testAliasGifs = []*savedGif{
+	{URL: "https://gif.fr/ship/1", Keywords: ":deploy-success"},
+	{URL: "https://gif.fr/ship/2", Keywords: ":deploy-success"},
+}
+
+func mockAliasIndex(api *plugintest.API, names ...string) {
+	data, _ := json.Marshal(names)
+	api.On("KVGet", aliasIndexKey(testTeamID)).Return(data, nil)
+}
+
+func matchAliasIndex(expectedNames ...string) interface{} {
+	return mock.MatchedBy(func(data []byte) bool {
+		var names []string
+		return json.Unmarshal(data, &names) == nil && assert.ObjectsAreEqual(expectedNames, names)
+	})
+}
+
+func TestAddAliasNameShouldKeepTheNamesSorted(t *testing.T) {
+	assert.Equal(t, []string{"deploy"}, addAliasName(nil, "deploy"))
+	assert.Equal(t, []string{"a", "deploy", "z"}, addAliasName([]string{"a", "z"}, "deploy"))
+	assert.Equal(t, []string{"a", "deploy"}, addAliasName([]string{"a", "deploy"}, "deploy"))
+}
+
+func TestParseAliasToken(t *testing.T) {
+	for keywords, expected := range map[string]string{
+		":deploy-success": "deploy-success",
+		":Friday_Mood":    "friday_mood",
+		":tada:":          "",
+		"deploy":          "",
+		":":               "",
+		":two words":      "",
+	} {
+		name, isAlias := parseAliasToken(keywords)
+		assert.Equal(t, expected != "", isAlias, keywords)
+		if isAlias {
+			assert.Equal(t, expected, name)
+		}
+	}
+}
+
+func TestExecuteCommandAliasAddShouldSaveGifInTeamAlias(t *testing.T) {
+	api, p := initMockAPI()
+	key := aliasKey(testTeamID, "deploy-success")
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	api.On("KVGet", key).Return(nil, nil)
+	api.On("KVCompareAndSet", key, mock.Anything, mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil &&
+			len(gifs) == 1 &&
+			gifs[0].URL == testGifURL &&
+			gifs[0].Keywords == ":deploy-success"
+	})).Return(true, nil)
+	mockAliasIndex(api, "cats", "weekend")
+	api.On("KVCompareAndSet", aliasIndexKey(testTeamID), mock.Anything, matchAliasIndex("cats", "deploy-success", "weekend")).Return(true, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif alias add Deploy-Success " + testGifURL, UserId: testUserID, TeamId: testTeamID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "added")
+	api.AssertNumberOfCalls(t, "KVCompareAndSet", 2)
+}
+
+func TestExecuteCommandAliasAddShouldRejectInvalidInput(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+
+	for _, command := range []string{
+		"/gif alias add deploy javascript:alert(1)",
+		"/gif alias add deploy /relative/url.gif",
+		"/gif alias add deploy!! " + testGifURL,
+		"/gif alias add deploy https://a.com/x.gif)@channel![x](https://b.com/y)",
+		"/gif alias add deploy https://a.com/<script>.gif",
+	} {
+		response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: command, UserId: testUserID, TeamId: testTeamID})
+
+		assert.Nil(t, err)
+		assert.Contains(t, response.Text, "Invalid", command)
+	}
+	api.AssertNotCalled(t, "KVCompareAndSet", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExecuteCommandAliasShouldRequireTeamMembership(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(false)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif alias list", UserId: testUserID, TeamId: testTeamID})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, response)
+	api.AssertNotCalled(t, "KVGet", mock.Anything)
+}
+
+func TestExecuteCommandAliasRemoveShouldRemoveOneGif(t *testing.T) {
+	api, p := initMockAPI()
+	key := aliasKey(testTeamID, "deploy-success")
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	api.On("KVGet", key).Return(mockStoredGifs(testAliasGifs), nil)
+	api.On("KVCompareAndSet", key, mock.Anything, mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil && len(gifs) == 1 && gifs[0].URL == "https://gif.fr/ship/2"
+	})).Return(true, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif alias remove deploy-success https://gif.fr/ship/1", UserId: testUserID, TeamId: testTeamID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "removed")
+	api.AssertNotCalled(t, "KVGet", aliasIndexKey(testTeamID))
+}
+
+func TestExecuteCommandAliasRemoveShouldUnlistTheAliasWithoutGifs(t *testing.T) {
+	api, p := initMockAPI()
+	key := aliasKey(testTeamID, "deploy-success")
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	api.On("KVGet", key).Return(mockStoredGifs(testAliasGifs[:1]), nil)
+	api.On("KVCompareAndSet", key, mock.Anything, mock.Anything).Return(true, nil)
+	mockAliasIndex(api, "cats", "deploy-success")
+	api.On("KVCompareAndSet", aliasIndexKey(testTeamID), mock.Anything, matchAliasIndex("cats")).Return(true, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif alias remove deploy-success https://gif.fr/ship/1", UserId: testUserID, TeamId: testTeamID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "removed")
+	api.AssertNumberOfCalls(t, "KVCompareAndSet", 2)
+}
+
+func TestExecuteCommandAliasRemoveShouldDeleteAndUnlistTheAlias(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	api.On("KVDelete", aliasKey(testTeamID, "deploy-success")).Return(nil)
+	mockAliasIndex(api, "cats", "deploy-success")
+	api.On("KVCompareAndSet", aliasIndexKey(testTeamID), mock.Anything, matchAliasIndex("cats")).Return(true, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif alias remove deploy-success", UserId: testUserID, TeamId: testTeamID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "removed")
+	api.AssertNumberOfCalls(t, "KVCompareAndSet", 1)
+}
+
+func TestExecuteCommandAliasListShouldOnlyListTeamAliases(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	mockAliasIndex(api, "cats", "deploy-success")
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif alias list", UserId: testUserID, TeamId: testTeamID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "`:cats`, `:deploy-success`")
+	api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+}
+
+func TestExecuteCommandGifWithAliasShouldPostRandomAliasGif(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	randomFloat = func() float64 { return 0.5 }
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	api.On("KVGet", aliasKey(testTeamID, "deploy-success")).Return(mockStoredGifs(testAliasGifs), nil)
+	mockRecentGifs(api, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif :deploy-success \"We did it\"", UserId: testUserID, TeamId: testTeamID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.Equal(t, model.CommandResponseTypeInChannel, response.ResponseType)
+	assert.Contains(t, response.Text, "https://gif.fr/ship/2")
+	assert.Contains(t, response.Text, "We did it")
+	assert.NotContains(t, response.Text, "*test*")
+	assert.Equal(t, aliasGifProvider, response.Attachments[0].Actions[0].Integration.Context[contextProvider])
+}
+
+func TestExecuteCommandGifFromAliasShouldNameTheConfiguredTriggerWithoutCommand(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	api.On("KVGet", aliasKey(testTeamID, "nope")).Return(nil, nil)
+
+	response, err := p.executeCommandGifFromAlias(p.getSnapshot(), "nope", "", false, &model.CommandArgs{UserId: testUserID, TeamId: testTeamID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "`/"+triggerGifs+" alias add`")
+}
+
+func TestExecuteCommandGifWithUnknownAliasShouldExplain(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	api.On("KVGet", aliasKey(testTeamID, "nope")).Return(nil, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gifs :nope", UserId: testUserID, TeamId: testTeamID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+	assert.Contains(t, response.Text, "alias add")
+}
+
+func TestExecuteCommandGifWithPreviewAndAliasShouldBrowseAliasGifs(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+	api.On("KVGet", aliasKey(testTeamID, "deploy-success")).Return(mockStoredGifs(testAliasGifs), nil)
+	var preview *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		preview = args.Get(1).(*model.Post)
+	})
+
+	_, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gifs :deploy-success", UserId: testUserID, TeamId: testTeamID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, preview)
+	assert.Contains(t, preview.Message, "https://gif.fr/ship/1")
+	context := preview.Attachments()[0].Actions[0].Integration.Context
+	assert.Equal(t, sourceAlias, context[contextSource])
+	assert.Equal(t, ":deploy-success", context[contextKeywords])
+}
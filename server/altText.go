@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to the accessible description of the GIFs (Markdown alternative text)
+
+// Maximum number of GIFs without description skipped in a row when a description is required
+const maxSkippedGifsWithoutDescription = 10
+
+var markdownAltTextReplacer = strings.NewReplacer("[", "(", "]", ")", "\\", "", "*", "", "_", " ", "`", "'")
+
+// getGifDescription returns the description of a GIF given by the provider, or its title if it has no description
+func getGifDescription(gif *provider.GifResult) string {
+	description := gif.AltText
+	if strings.TrimSpace(description) == "" {
+		description = gif.Title
+	}
+	return strings.Join(strings.Fields(description), " ")
+}
+
+// generateGifAltText returns the alternative text of a GIF in a Markdown image
+func generateGifAltText(altTextMode, keywords, description string) string {
+	if altTextMode != pluginConf.AltTextModeKeywords && altTextMode != "" && description != "" {
+		return escapeMarkdownText(description)
+	}
+	return "GIF for '" + escapeMarkdown(keywords) + "'"
+}
+
+// escapeMarkdownText removes the characters of a text that would break the Markdown of the post, and neutralizes its mentions
+func escapeMarkdownText(text string) string {
+	return neutralizeMentions(markdownAltTextReplacer.Replace(strings.Join(strings.Fields(text), " ")))
+}
+
+// searchGif returns the GIF that matches the query from the provider, or from the federated providers for a preview.
+// If the configuration requires GIF descriptions, the GIFs without description are skipped.
+// No GIF is returned once the search of the context made all its calls to the provider.
+func searchGif(ctx context.Context, snapshot *pluginSnapshot, query provider.Query, cursor *string, preview bool) (*provider.GifResult, *model.AppError) {
+	gifProvider := snapshot.getSearchProvider(preview)
+	if !snapshot.configuration.RequireGifDescription {
+		if !takeProviderCall(ctx) {
+			return nil, nil
+		}
+		return gifProvider.GetGif(ctx, query, cursor)
+	}
+	for skipped := 0; skipped <= maxSkippedGifsWithoutDescription; skipped++ {
+		if !takeProviderCall(ctx) {
+			return nil, nil
+		}
+		gif, appErr := gifProvider.GetGif(ctx, query, cursor)
+		if appErr != nil || gif == nil || getGifDescription(gif) != "" {
+			return gif, appErr
+		}
+		if *cursor == "" {
+			break
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+// mockGifProviderSequence provides the GIFs in order, the cursor being the index of the next GIF
+type mockGifProviderSequence struct {
+	gifs []*provider.GifResult
+}
+
+func (m *mockGifProviderSequence) GetGif(ctx context.Context, query provider.Query, cursor *string) (*provider.GifResult, *model.AppError) {
+	index, _ := strconv.Atoi(*cursor)
+	if index >= len(m.gifs) {
+		*cursor = ""
+		return nil, nil
+	}
+	*cursor = strconv.Itoa(index + 1)
+	if index+1 == len(m.gifs) {
+		*cursor = ""
+	}
+	return m.gifs[index], nil
+}
+
+func (m *mockGifProviderSequence) GetAttributionMessage() string {
+	return "test"
+}
+
+func TestGetGifDescription(t *testing.T) {
+	assert.Equal(t, "A cat jumps", getGifDescription(&provider.GifResult{AltText: " A cat\njumps ", Title: "Cat GIF"}))
+	assert.Equal(t, "Cat GIF", getGifDescription(&provider.GifResult{Title: "Cat GIF"}))
+	assert.Equal(t, "", getGifDescription(&provider.GifResult{}))
+}
+
+func TestGenerateGifCaptionAltText(t *testing.T) {
+	testCases := []struct {
+		altTextMode string
+		description string
+		expected    string
+		notExpected string
+	}{
+		{altTextMode: "", description: testDescription, expected: "![GIF for 'kitty'](" + testGifURL + ")", notExpected: testDescription},
+		{altTextMode: pluginConf.AltTextModeKeywords, description: testDescription, expected: "![GIF for 'kitty'](" + testGifURL + ")", notExpected: testDescription},
+		{altTextMode: pluginConf.AltTextModeProvider, description: testDescription, expected: "![" + testDescription + "](" + testGifURL + ")", notExpected: "> "},
+		{altTextMode: pluginConf.AltTextModeProvider, description: "", expected: "![GIF for 'kitty'](" + testGifURL + ")"},
+		{altTextMode: pluginConf.AltTextModeProvider, description: "Cat [in] a *box*", expected: "![Cat (in) a box](" + testGifURL + ")"},
+		{altTextMode: pluginConf.AltTextModeProviderCaption, description: testDescription, expected: "> " + testDescription + " \n"},
+	}
+	for _, testCase := range testCases {
+		caption := generateGifCaption(pluginConf.DisplayModeEmbedded, testCase.altTextMode, "", testKeywords, "", testGifURL, testCase.description, "test")
+		assert.Contains(t, caption, testCase.expected, testCase.altTextMode)
+		if testCase.notExpected != "" {
+			assert.NotContains(t, caption, testCase.notExpected, testCase.altTextMode)
+		}
+	}
+}
+
+func TestSearchGifShouldSkipGifsWithoutDescriptionWhenRequired(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.RequireGifDescription = true
+	setMockGifProvider(p, &mockGifProviderSequence{gifs: []*provider.GifResult{
+		{URL: "https://gif.fr/1"},
+		{URL: "https://gif.fr/2", Title: "  "},
+		{URL: "https://gif.fr/3", Title: "Kitty"},
+	}})
+	cursor := ""
+
+	gif, err := searchGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, &cursor, false)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, gif)
+	assert.Equal(t, "https://gif.fr/3", gif.URL)
+}
+
+func TestSearchGifShouldReturnNoGifWhenNoneHasADescription(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.RequireGifDescription = true
+	setMockGifProvider(p, &mockGifProviderSequence{gifs: []*provider.GifResult{{URL: "https://gif.fr/1"}, {URL: "https://gif.fr/2"}}})
+	cursor := ""
+
+	gif, err := searchGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, &cursor, false)
+
+	assert.Nil(t, err)
+	assert.Nil(t, gif)
+}
+
+func TestHandleSendShouldUseTheDescriptionAsAltText(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.AltTextMode = pluginConf.AltTextModeProvider
+	setMockGifProvider(p, newMockGifProvider())
+	mockRecentGifs(api, nil)
+	api.On("DeleteEphemeralPost", testUserID, testPostID).Return(nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	request := generateTestIntegrationRequest()
+	request.Description = testDescription
+
+	(&defaultHTTPHandler{}).handleSend(p, p.getSnapshot(), httptest.NewRecorder(), request)
+
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return strings.Contains(post.Message, "!["+testDescription+"]("+testGifURL+")")
+	}))
+}
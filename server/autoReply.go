@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+)
+
+// Contains what's related to the automatic GIF replies to messages matching the configured rules
+
+const autoReplyCooldownKeyPrefix = "autoreply_"
+
+// randomFloat returns a number in [0, 1), used to apply the probability of the rules
+var randomFloat = rand.Float64
+
+// MessageHasBeenPosted replies with a GIF to the messages matching an automatic reply rule
+func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	snapshot := p.getSnapshot()
+	rules := snapshot.configuration.ParsedAutoReplyRules
+	if len(rules) == 0 || !canAutoReplyTo(snapshot, post) {
+		return
+	}
+
+	teamID := ""
+	for _, rule := range rules {
+		if !rule.Matches(post.Message) {
+			continue
+		}
+		if rule.TeamID != "" && teamID == "" {
+			channel, appErr := p.API.GetChannel(post.ChannelId)
+			if appErr != nil {
+				p.API.LogWarn("Unable to get the channel of the post for automatic GIF replies", "error", appErr.Error())
+				return
+			}
+			teamID = channel.TeamId
+		}
+		if !rule.AppliesTo(post.ChannelId, teamID) || randomFloat() >= rule.Probability || !p.startAutoReplyCooldown(rule, post.ChannelId) {
+			continue
+		}
+		p.autoReply(snapshot, rule, post)
+		// Only one GIF per message
+		return
+	}
+}
+
+// canAutoReplyTo prevents reply loops by ignoring the posts of the plugin bot, of other bots and of the system
+func canAutoReplyTo(snapshot *pluginSnapshot, post *model.Post) bool {
+	if post.UserId == snapshot.botID || post.IsSystemMessage() {
+		return false
+	}
+	if fromBot, ok := post.GetProp("from_bot").(string); ok && fromBot == "true" {
+		return false
+	}
+	if fromWebhook, ok := post.GetProp("from_webhook").(string); ok && fromWebhook == "true" {
+		return false
+	}
+	return true
+}
+
+// startAutoReplyCooldown returns false if the rule is still in cooldown for this channel. The cooldown is stored in the KV store
+// with an atomic write, so that only one server of a cluster replies.
+func (p *Plugin) startAutoReplyCooldown(rule *pluginConf.AutoReplyRule, channelID string) bool {
+	if rule.CooldownSeconds == 0 {
+		return true
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(rule.Phrase))
+	key := fmt.Sprintf("%s%s_%x", autoReplyCooldownKeyPrefix, channelID, hash.Sum32())
+	started, appErr := p.API.KVSetWithOptions(key, []byte{1}, model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: int64(rule.CooldownSeconds),
+	})
+	if appErr != nil {
+		p.API.LogWarn("Unable to store the cooldown of an automatic GIF reply", "error", appErr.Error())
+		return false
+	}
+	return started
+}
+
+// autoReply posts a GIF in the thread of the post
+func (p *Plugin) autoReply(snapshot *pluginSnapshot, rule *pluginConf.AutoReplyRule, post *model.Post) {
+	ctx, cancel := newSearchContext()
+	defer cancel()
+	cursor := ""
+	gif, appErr := p.searchNewGif(ctx, snapshot, p.newQuery(snapshot, rule.Keywords, p.getUserLanguage(snapshot, post.UserId, ""), ""), &cursor, post.ChannelId, nil, false)
+	if appErr != nil {
+		p.API.LogWarn("Unable to get a GIF for an automatic reply", "error", appErr.Error())
+		return
+	}
+	if gif == nil {
+		return
+	}
+	rootID := post.RootId
+	if rootID == "" {
+		rootID = post.Id
+	}
+	reply := generateGifPost(snapshot.configuration, snapshot.botID, rule.Keywords, "", gif.URL, getGifDescription(gif), post.ChannelId, rootID, snapshot.gifProvider.GetAttributionMessage())
+	if _, appErr = p.API.CreatePost(reply); appErr != nil {
+		p.API.LogWarn("Unable to post an automatic GIF reply", "error", appErr.Error())
+		return
+	}
+	p.recordChannelGif(snapshot, post.ChannelId, newSearchResultGif(gif, rule.Keywords))
+}
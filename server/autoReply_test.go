@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+func initMockAPIWithAutoReplyRules(t *testing.T, rulesJSON string) (*plugintest.API, *Plugin) {
+	api, p := initMockAPI()
+	rules, err := pluginConf.ParseAutoReplyRules(rulesJSON)
+	assert.Nil(t, err)
+	p.getSnapshot().configuration.ParsedAutoReplyRules = rules
+	setMockGifProvider(p, newMockGifProvider())
+	randomFloat = func() float64 { return 0.5 }
+	return api, p
+}
+
+func TestMessageHasBeenPostedShouldReplyInThreadWhenRuleMatches(t *testing.T) {
+	api, p := initMockAPIWithAutoReplyRules(t, `[{"phrase": "ship it", "keywords": "ship", "cooldown_seconds": 60}]`)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(true, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+
+	p.MessageHasBeenPosted(nil, &model.Post{Id: testPostID, UserId: testUserID, ChannelId: testChannelID, Message: "Tests are green, ship it!"})
+
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.UserId == p.getSnapshot().botID &&
+			post.ChannelId == testChannelID &&
+			post.RootId == testPostID &&
+			strings.Contains(post.Message, "ship")
+	}))
+}
+
+func TestMessageHasBeenPostedShouldIgnoreBotPosts(t *testing.T) {
+	api, p := initMockAPIWithAutoReplyRules(t, `[{"phrase": "ship it", "keywords": "ship"}]`)
+
+	p.MessageHasBeenPosted(nil, &model.Post{Id: testPostID, UserId: p.getSnapshot().botID, ChannelId: testChannelID, Message: "ship it"})
+	otherBotPost := &model.Post{Id: testPostID, UserId: "otherBot", ChannelId: testChannelID, Message: "ship it"}
+	otherBotPost.AddProp("from_bot", "true")
+	p.MessageHasBeenPosted(nil, otherBotPost)
+
+	api.AssertNumberOfCalls(t, "CreatePost", 0)
+}
+
+func TestMessageHasBeenPostedShouldNotReplyDuringCooldown(t *testing.T) {
+	api, p := initMockAPIWithAutoReplyRules(t, `[{"phrase": "ship it", "keywords": "ship", "cooldown_seconds": 60}]`)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(false, nil)
+
+	p.MessageHasBeenPosted(nil, &model.Post{Id: testPostID, UserId: testUserID, ChannelId: testChannelID, Message: "ship it"})
+
+	api.AssertNumberOfCalls(t, "CreatePost", 0)
+}
+
+func TestMessageHasBeenPostedShouldApplyProbability(t *testing.T) {
+	api, p := initMockAPIWithAutoReplyRules(t, `[{"phrase": "ship it", "keywords": "ship", "probability": 0.1}]`)
+
+	p.MessageHasBeenPosted(nil, &model.Post{Id: testPostID, UserId: testUserID, ChannelId: testChannelID, Message: "ship it"})
+
+	api.AssertNumberOfCalls(t, "CreatePost", 0)
+}
+
+func TestMessageHasBeenPostedShouldApplyTeamScope(t *testing.T) {
+	api, p := initMockAPIWithAutoReplyRules(t, `[{"phrase": "ship it", "keywords": "ship", "team_id": "otherTeam"}]`)
+	api.On("GetChannel", testChannelID).Return(&model.Channel{Id: testChannelID, TeamId: "team"}, nil)
+
+	p.MessageHasBeenPosted(nil, &model.Post{Id: testPostID, UserId: testUserID, ChannelId: testChannelID, Message: "ship it"})
+
+	api.AssertNumberOfCalls(t, "CreatePost", 0)
+}
+
+func TestMessageHasBeenPostedShouldReplyInExistingThread(t *testing.T) {
+	api, p := initMockAPIWithAutoReplyRules(t, `[{"phrase": "ship it", "keywords": "ship"}]`)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+
+	p.MessageHasBeenPosted(nil, &model.Post{Id: testPostID, RootId: testRootID, UserId: testUserID, ChannelId: testChannelID, Message: "ship it"})
+
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.RootId == testRootID }))
+}
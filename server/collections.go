@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to the GIFs saved by the plugin (favorites, etc.) that can be browsed like search results
+
+// Sources of the GIFs displayed in a preview post
+const (
+	sourceSearch    = ""
+	sourceFavorites = "favorites"
+	sourceAlias     = "alias"
+	sourceRecent    = "recent"
+)
+
+const (
+	// Number of attempts to update a KV list before giving up because of concurrent updates
+	kvUpdateAttempts = 5
+)
+
+// savedGif is a GIF stored by the plugin
+type savedGif struct {
+	URL string
+	// ID of the GIF at the provider, empty if unknown
+	ID          string
+	Keywords    string
+	Description string
+	Provider    string
+	// Attribution message of the provider, empty to use the one of the configured provider
+	Attribution string
+	SavedAt     int64
+	// URL given by the provider to report that the GIF was posted, only kept during a preview session
+	ShareReportURL string `json:"-"`
+}
+
+// collectionCursor is the position of a preview post among the GIFs of a collection that match a filter
+type collectionCursor struct {
+	Filter   string `json:"filter"`
+	Position int    `json:"position"`
+}
+
+func (g *savedGif) matches(filter string) bool {
+	return strings.Contains(strings.ToLower(g.Keywords), strings.ToLower(filter))
+}
+
+func encodeCollectionCursor(cursor collectionCursor) string {
+	data, _ := json.Marshal(cursor)
+	return string(data)
+}
+
+// nextCollectionGif returns the GIF at the position of the cursor among the GIFs that match the cursor filter,
+// or nil if there is none, and moves the cursor to the next GIF (or empties it if there are no more GIFs)
+func nextCollectionGif(gifs []*savedGif, cursor *string) (*savedGif, error) {
+	var position collectionCursor
+	if *cursor != "" {
+		if err := json.Unmarshal([]byte(*cursor), &position); err != nil {
+			return nil, err
+		}
+	}
+	var found *savedGif
+	matchCount := 0
+	for _, gif := range gifs {
+		if !gif.matches(position.Filter) {
+			continue
+		}
+		if matchCount == position.Position {
+			found = gif
+		} else if matchCount > position.Position {
+			position.Position++
+			*cursor = encodeCollectionCursor(position)
+			return found, nil
+		}
+		matchCount++
+	}
+	*cursor = ""
+	return found, nil
+}
+
+// newSearchResultGif returns a GIF found by a search for the keywords
+func newSearchResultGif(gif *provider.GifResult, keywords string) *savedGif {
+	return &savedGif{URL: gif.URL, ID: gif.ID, Keywords: keywords, Description: getGifDescription(gif), Provider: gif.Provider, Attribution: gif.Attribution, ShareReportURL: gif.ShareReportURL}
+}
+
+// getPreviewGif returns the next GIF of a preview post, with its keywords and description: from the GIF provider
+// for a search, skipping the GIFs already seen in the preview session, or from the user's and team's collections.
+// The cursor is moved to the next GIF.
+func (p *Plugin) getPreviewGif(ctx context.Context, snapshot *pluginSnapshot, source string, query provider.Query, userID, teamID, channelID string, seen []string, cursor *string) (*savedGif, *model.AppError) {
+	var gifs []*savedGif
+	var appErr *model.AppError
+	switch source {
+	case sourceSearch:
+		gif, appErr := p.searchNewGif(ctx, snapshot, query, cursor, channelID, seen, true)
+		if appErr != nil || gif == nil {
+			return nil, appErr
+		}
+		return newSearchResultGif(gif, query.Keywords), nil
+	case sourceFavorites:
+		gifs, appErr = p.getFavorites(userID)
+	case sourceRecent:
+		gifs, appErr = p.getRecentGifs(userID)
+	case sourceAlias:
+		if !p.API.HasPermissionToTeam(userID, teamID, model.PermissionViewTeam) {
+			return nil, p.errorGenerator.FromMessage("Only the team members can use the team's aliases")
+		}
+		gifs, appErr = p.getAliasGifs(teamID, strings.TrimPrefix(query.Keywords, aliasTokenPrefix))
+	default:
+		return nil, p.errorGenerator.FromMessage("Unknown GIF source: " + source)
+	}
+	if appErr != nil {
+		return nil, appErr
+	}
+	gif, err := nextCollectionGif(gifs, cursor)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Could not read the cursor", err)
+	}
+	return gif, nil
+}
+
+// executeCommandCollectionPreview returns an ephemeral post with the first GIF of a collection matching the filter
+func (p *Plugin) executeCommandCollectionPreview(snapshot *pluginSnapshot, source, keywords, filter, caption, emptyMessage string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	cursor := encodeCollectionCursor(collectionCursor{Filter: filter})
+	// The collections are stored in the KV store, the GIF provider is not called
+	gif, appErr := p.getPreviewGif(context.Background(), snapshot, source, provider.Query{Keywords: keywords}, args.UserId, args.TeamId, args.ChannelId, nil, &cursor)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if gif == nil {
+		return ephemeralResponse(emptyMessage), nil
+	}
+	p.sendPreviewPost(snapshot, gif, caption, cursor, "", source, nil, args)
+	return &model.CommandResponse{}, nil
+}
+
+// getKVIndex returns the entries of an index stored in the KV store, the description naming them in the errors
+func (p *Plugin) getKVIndex(key, description string) ([]string, *model.AppError) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	var entries []string
+	if data != nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, p.errorGenerator.FromError("Could not read the "+description, err)
+		}
+	}
+	return entries, nil
+}
+
+// updateKVIndex atomically applies an update to an index stored in the KV store, retrying if it is concurrently modified
+func (p *Plugin) updateKVIndex(key, description string, update func(entries []string) []string) *model.AppError {
+	for attempt := 0; attempt < kvUpdateAttempts; attempt++ {
+		oldData, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return appErr
+		}
+		var entries []string
+		if oldData != nil {
+			if err := json.Unmarshal(oldData, &entries); err != nil {
+				return p.errorGenerator.FromError("Could not read the "+description, err)
+			}
+		}
+		newData, err := json.Marshal(update(entries))
+		if err != nil {
+			return p.errorGenerator.FromError("Could not serialize the "+description, err)
+		}
+		updated, appErr := p.API.KVCompareAndSet(key, oldData, newData)
+		if appErr != nil || updated {
+			return appErr
+		}
+	}
+	return p.errorGenerator.FromMessage("Could not save the " + description + " because of concurrent updates, please try again")
+}
+
+func removeIndexEntry(entries []string, entry string) []string {
+	result := []string{}
+	for _, existing := range entries {
+		if existing != entry {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
+
+func (p *Plugin) getSavedGifs(key string) ([]*savedGif, *model.AppError) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil || data == nil {
+		return []*savedGif{}, appErr
+	}
+	var gifs []*savedGif
+	if err := json.Unmarshal(data, &gifs); err != nil {
+		return nil, p.errorGenerator.FromError("Could not read the saved GIFs", err)
+	}
+	return gifs, nil
+}
+
+// updateSavedGifs atomically applies an update to a list of saved GIFs, retrying if the list is concurrently modified
+func (p *Plugin) updateSavedGifs(key string, update func(gifs []*savedGif) []*savedGif) *model.AppError {
+	for attempt := 0; attempt < kvUpdateAttempts; attempt++ {
+		oldData, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return appErr
+		}
+		var gifs []*savedGif
+		if oldData != nil {
+			if err := json.Unmarshal(oldData, &gifs); err != nil {
+				return p.errorGenerator.FromError("Could not read the saved GIFs", err)
+			}
+		}
+		newData, err := json.Marshal(update(gifs))
+		if err != nil {
+			return p.errorGenerator.FromError("Could not serialize the saved GIFs", err)
+		}
+		updated, appErr := p.API.KVCompareAndSet(key, oldData, newData)
+		if appErr != nil {
+			return appErr
+		}
+		if updated {
+			return nil
+		}
+	}
+	return p.errorGenerator.FromMessage("Could not save the GIFs because of concurrent updates, please try again")
+}
+
+// prependSavedGif adds the GIF at the start of the list, removing any previous occurrence, and keeps at most maxLength GIFs
+func prependSavedGif(gifs []*savedGif, gif *savedGif, maxLength int) []*savedGif {
+	result := []*savedGif{gif}
+	for _, existing := range gifs {
+		if existing.URL != gif.URL && len(result) < maxLength {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+var testSavedGifs = []*savedGif{
+	{URL: "https://gif.fr/1", Keywords: "happy kitty"},
+	{URL: "https://gif.fr/2", Keywords: "sad dog"},
+	{URL: "https://gif.fr/3", Keywords: "Happy dog"},
+}
+
+func TestNextCollectionGifShouldBrowseMatchingGifs(t *testing.T) {
+	cursor := encodeCollectionCursor(collectionCursor{Filter: "happy"})
+
+	gif, err := nextCollectionGif(testSavedGifs, &cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, testSavedGifs[0], gif)
+	assert.NotEmpty(t, cursor)
+
+	gif, err = nextCollectionGif(testSavedGifs, &cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, testSavedGifs[2], gif)
+	assert.Empty(t, cursor)
+}
+
+func TestNextCollectionGifShouldReturnNilWhenNothingMatches(t *testing.T) {
+	cursor := encodeCollectionCursor(collectionCursor{Filter: "parrot"})
+
+	gif, err := nextCollectionGif(testSavedGifs, &cursor)
+	assert.Nil(t, err)
+	assert.Nil(t, gif)
+	assert.Empty(t, cursor)
+}
+
+func TestNextCollectionGifShouldFailWithInvalidCursor(t *testing.T) {
+	cursor := "not a cursor"
+
+	gif, err := nextCollectionGif(testSavedGifs, &cursor)
+	assert.NotNil(t, err)
+	assert.Nil(t, gif)
+}
+
+func TestPrependSavedGifShouldRemoveDuplicatesAndApplyMaxLength(t *testing.T) {
+	gifs := prependSavedGif(testSavedGifs, &savedGif{URL: "https://gif.fr/2", Keywords: "sad dog again"}, 2)
+
+	assert.Len(t, gifs, 2)
+	assert.Equal(t, "sad dog again", gifs[0].Keywords)
+	assert.Equal(t, "https://gif.fr/1", gifs[1].URL)
+}
+
+func TestUpdateSavedGifsShouldRetryWhenConcurrentlyModified(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", "key").Return(nil, nil)
+	api.On("KVCompareAndSet", "key", mock.Anything, mock.Anything).Return(false, nil).Once()
+	api.On("KVCompareAndSet", "key", mock.Anything, mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil && len(gifs) == 1
+	})).Return(true, nil).Once()
+
+	err := p.updateSavedGifs("key", func(gifs []*savedGif) []*savedGif {
+		return prependSavedGif(gifs, testSavedGifs[0], 10)
+	})
+
+	assert.Nil(t, err)
+	api.AssertNumberOfCalls(t, "KVCompareAndSet", 2)
+}
+
+func TestUpdateSavedGifsShouldFailAfterTooManyConcurrentModifications(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", "key").Return(nil, nil)
+	api.On("KVCompareAndSet", "key", mock.Anything, mock.Anything).Return(false, nil)
+
+	err := p.updateSavedGifs("key", func(gifs []*savedGif) []*savedGif { return gifs })
+
+	assert.NotNil(t, err)
+	api.AssertNumberOfCalls(t, "KVCompareAndSet", kvUpdateAttempts)
+}
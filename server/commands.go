@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
 
 	manifest "github.com/moussetc/mattermost-plugin-giphy"
 	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
 
 	"github.com/mattermost/mattermost-server/v6/model"
 
@@ -15,52 +17,125 @@ import (
 
 // Contains all that's related to the basic Post command
 
-// Triggers used to define slash commands
+// Default triggers of the slash commands
 const (
 	triggerGif  = "gif"
 	triggerGifs = "gifs"
 )
 
 func (p *Plugin) RegisterCommands() error {
-	unregisterErr := p.API.UnregisterCommand("", triggerGif)
-	if unregisterErr != nil {
-		p.API.LogWarn("Unable to unregister the " + triggerGif + " command" + unregisterErr.Error())
-	}
-	unregisterErr = p.API.UnregisterCommand("", triggerGifs)
-	if unregisterErr != nil {
-		p.API.LogWarn("Unable to unregister the " + triggerGifs + " command" + unregisterErr.Error())
+	// Unregister the previous commands, whose triggers may have been changed or removed from the configuration
+	previousTriggers := p.registeredTriggers
+	p.registeredTriggers = nil
+	for _, trigger := range previousTriggers {
+		unregisterErr := p.API.UnregisterCommand("", trigger)
+		if unregisterErr != nil {
+			p.API.LogWarn("Unable to unregister the " + trigger + " command" + unregisterErr.Error())
+		}
 	}
 
-	config := p.getConfiguration()
+	// Called under the configuration lock, once the snapshot of the configuration is published
+	config := p.getSnapshot().configuration
 	if config.CommandTriggerGif != "" {
-		err := p.API.RegisterCommand(&model.Command{
+		err := p.registerCommand(&model.Command{
 			Trigger:          config.CommandTriggerGif,
 			Description:      "Post a GIF matching your search",
 			DisplayName:      "Giphy Search",
 			AutoComplete:     true,
-			AutoCompleteDesc: "Post a GIF matching your search",
+			AutoCompleteDesc: "Post a GIF matching your search" + quotedKeywordsHelp,
 			AutoCompleteHint: getHintMessage(config.CommandTriggerGif),
 		})
 		if err != nil {
-			return errors.Wrap(err, "Unable to define the following command: "+config.CommandTriggerGif)
+			return err
 		}
 	}
 	if config.CommandTriggerGifWithPreview != "" {
-		err := p.API.RegisterCommand(&model.Command{
+		err := p.registerCommand(&model.Command{
 			Trigger:          config.CommandTriggerGifWithPreview,
 			Description:      "Preview a GIF",
 			DisplayName:      "Giphy Shuffle",
 			AutoComplete:     true,
-			AutoCompleteDesc: "Let you preview and shuffle a GIF before posting for real",
+			AutoCompleteDesc: "Let you preview and shuffle a GIF before posting for real" + quotedKeywordsHelp,
 			AutoCompleteHint: getHintMessage(config.CommandTriggerGifWithPreview),
 		})
 		if err != nil {
-			return errors.Wrap(err, "Unable to define the following command: "+config.CommandTriggerGifWithPreview)
+			return err
+		}
+	}
+	for _, alias := range config.ParsedCommandTriggerAliases {
+		err := p.registerCommand(&model.Command{
+			Trigger:          alias,
+			Description:      "Preview a GIF",
+			DisplayName:      "Giphy Shuffle (/" + alias + ")",
+			AutoComplete:     true,
+			AutoCompleteDesc: "Let you preview and shuffle a GIF before posting for real, use #caption to also use your keywords as caption" + quotedKeywordsHelp,
+			AutoCompleteHint: getHintMessage(alias),
+		})
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// registerCommand registers a slash command and remembers its trigger to unregister it when the configuration changes
+func (p *Plugin) registerCommand(command *model.Command) error {
+	if err := p.API.RegisterCommand(command); err != nil {
+		return errors.Wrap(err, "Unable to define the following command: "+command.Trigger)
+	}
+	p.registeredTriggers = append(p.registeredTriggers, command.Trigger)
+	return nil
+}
+
+// getTriggerMode returns whether the trigger is one of the plugin's commands, and whether it previews the GIF before posting it
+func getTriggerMode(config *pluginConf.Configuration, trigger string) (supported, withPreview bool) {
+	switch {
+	case trigger == "":
+		return false, false
+	case trigger == config.CommandTriggerGifWithPreview:
+		return true, true
+	case trigger == config.CommandTriggerGif:
+		return true, false
+	}
+	for _, alias := range config.ParsedCommandTriggerAliases {
+		if trigger == alias {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// subcommands lists the commands that can follow a trigger instead of keywords, ex: /gif schedule list
+var subcommands = map[string]func(p *Plugin, snapshot *pluginSnapshot, parameters []string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError){
+	subcommandSchedule:  (*Plugin).executeCommandSchedule,
+	subcommandFavorites: (*Plugin).executeCommandFavorites,
+	subcommandAlias:     (*Plugin).executeCommandAlias,
+	subcommandRecent:    (*Plugin).executeCommandRecent,
+	subcommandAdmin:     (*Plugin).executeCommandAdmin,
+}
+
+// getCommandTrigger returns the trigger of a command line, without the leading slash
+func getCommandTrigger(commandLine string) string {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(fields[0], "/")
+}
+
+// parseSubcommand returns the subcommand called by the command line and its parameters, or an empty subcommand if the command line is a GIF search.
+// Quoted keywords are always a GIF search, so that searches like "recent news" can be made.
+func parseSubcommand(commandLine, trigger string) (string, []string) {
+	fields := strings.Fields(strings.Replace(commandLine, "/"+trigger, "", 1))
+	if len(fields) == 0 || strings.HasPrefix(fields[0], "\"") {
+		return "", nil
+	}
+	if _, ok := subcommands[fields[0]]; !ok {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
 func parseCommandLine(commandLine, trigger string) (keywords, caption string, err error) {
 	reg := regexp.MustCompile("^\\s*(?P<keywords>(\"([^\\s\"]+\\s*)+\")+|([^\\s\"]+\\s*)+)(?P<caption>\\s+\"(\\s*[^\\s\"]+\\s*)+\")?\\s*$")
 	matchIndexes := reg.FindStringSubmatch(strings.Replace(commandLine, "/"+trigger, "", 1))
@@ -74,95 +149,203 @@ func parseCommandLine(commandLine, trigger string) (keywords, caption string, er
 	return strings.Trim(strings.TrimSpace(results["keywords"]), "\""), strings.Trim(strings.TrimSpace(results["caption"]), "\""), nil
 }
 
+// parseLanguageOption extracts the optional "lang:<locale>" option that can follow the trigger,
+// and returns the command line without the option
+func parseLanguageOption(commandLine, trigger string) (string, string) {
+	reg := regexp.MustCompile("^(\\s*/" + regexp.QuoteMeta(trigger) + ")\\s+lang:([a-zA-Z]{2,3}(?:[-_][a-zA-Z]{2})?)(\\s|$)")
+	matches := reg.FindStringSubmatch(commandLine)
+	if matches == nil {
+		return commandLine, ""
+	}
+	return matches[1] + " " + commandLine[len(matches[0]):], matches[2]
+}
+
+// parseCaptionOption extracts the Slack-style "#caption" option that can follow the trigger (and language option),
+// meaning the keywords are also the caption of the GIF, and returns the command line without the option
+func parseCaptionOption(commandLine, trigger string) (string, bool) {
+	reg := regexp.MustCompile("^(\\s*/" + regexp.QuoteMeta(trigger) + ")\\s+#caption(\\s|$)")
+	matches := reg.FindStringSubmatch(commandLine)
+	if matches == nil {
+		return commandLine, false
+	}
+	return matches[1] + " " + commandLine[len(matches[0]):], true
+}
+
+// getUserLanguage returns the locale used to search GIFs for a user: the language chosen for this command
+// if any, or else the user's Mattermost locale if the plugin is configured to use it.
+// An empty locale means the provider will use the language configured for the plugin.
+func (p *Plugin) getUserLanguage(snapshot *pluginSnapshot, userID, language string) string {
+	if language != "" {
+		return language
+	}
+	if !snapshot.configuration.UseUserLanguage {
+		return ""
+	}
+	user, err := p.API.GetUser(userID)
+	if err != nil {
+		p.API.LogWarn("Unable to get the user's locale, using the default language instead: " + err.Error())
+		return ""
+	}
+	return user.Locale
+}
+
 // executeCommandGif returns a public post containing a matching GIF
-func (p *Plugin) executeCommandGif(keywords, caption string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+func (p *Plugin) executeCommandGif(ctx context.Context, snapshot *pluginSnapshot, query provider.Query, caption string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
 	cursor := ""
-	gifURL, errGif := p.gifProvider.GetGifURL(keywords, &cursor)
+	gif, keywords, errGif := p.searchRelaxedGif(ctx, snapshot, query, &cursor, args.ChannelId, nil, false)
 	if errGif != nil {
 		p.API.LogWarn("Error while trying to get GIF URL: " + errGif.Error())
 		return nil, errGif
 	}
-	if gifURL == "" {
-		return p.handleNoGifFound(keywords, args)
+	if gif == nil {
+		return p.handleNoGifFound(ctx, snapshot, query, caption, false, args)
 	}
 
-	text := generateGifCaption(p.getConfiguration().DisplayMode, keywords, caption, gifURL, p.gifProvider.GetAttributionMessage())
-	return &model.CommandResponse{ResponseType: model.CommandResponseTypeInChannel, Text: text}, nil
+	sentGif := newSearchResultGif(gif, keywords)
+	p.recordRecentGif(snapshot, args.UserId, sentGif)
+	p.recordChannelGif(snapshot, args.ChannelId, sentGif)
+	query.Keywords = keywords
+	p.reportShare(snapshot, false, provider.Share{Provider: gif.Provider, GifID: gif.ID, Query: query, ReportURL: gif.ShareReportURL})
+	return generateGifCommandResponse(snapshot, sentGif, caption), nil
+}
+
+// generateGifCommandResponse returns the response that posts the GIF in the channel,
+// with the attribution of the GIF or else of the configured provider
+func generateGifCommandResponse(snapshot *pluginSnapshot, gif *savedGif, caption string) *model.CommandResponse {
+	config := snapshot.configuration
+	text := generateGifCaption(config.DisplayMode, config.AltTextMode, config.CaptionPolicy, gif.Keywords, caption, gif.URL, gif.Description, snapshot.getAttributionMessage(gif.Provider, gif.Attribution))
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeInChannel,
+		Text:         text,
+		Attachments:  generateGifPostAttachments(gif),
+	}
 }
 
 // executeCommandGifWithPreview returns an ephemeral post with one GIF that can either be posted, shuffled or canceled
-func (p *Plugin) executeCommandGifWithPreview(keywords, caption string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+func (p *Plugin) executeCommandGifWithPreview(ctx context.Context, snapshot *pluginSnapshot, query provider.Query, caption string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
 	cursor := ""
-	gifURL, errGif := p.gifProvider.GetGifURL(keywords, &cursor)
+	gif, keywords, errGif := p.searchRelaxedGif(ctx, snapshot, query, &cursor, args.ChannelId, nil, true)
 	if errGif != nil {
 		p.API.LogWarn("Error while trying to get GIF URL: " + errGif.Error())
 		return nil, errGif
 	}
-	if gifURL == "" {
-		return p.handleNoGifFound(keywords, args)
+	if gif == nil {
+		return p.handleNoGifFound(ctx, snapshot, query, caption, true, args)
 	}
 
-	post := p.generateGifPost(p.botID, keywords, caption, gifURL, args.ChannelId, args.RootId, p.gifProvider.GetAttributionMessage())
+	// The shuffles continue the search of the keywords that found the GIF
+	query.Keywords = keywords
+	previewGif := newSearchResultGif(gif, keywords)
+	seen := []string{gifKey(previewGif.ID, previewGif.URL)}
+	postID := p.sendPreviewPost(snapshot, previewGif, caption, cursor, query.Locale, sourceSearch, seen, args)
+	p.prefetchPreviewGif(snapshot, postID, query, cursor, args.ChannelId, seen)
+	return &model.CommandResponse{}, nil
+}
+
+// sendPreviewPost sends the ephemeral post that lets the user shuffle, send or save a GIF.
+// The seen GIFs are the ones already shown in the preview session, that will not be shown again by a shuffle.
+// Returns the ID of the preview post.
+func (p *Plugin) sendPreviewPost(snapshot *pluginSnapshot, gif *savedGif, caption, cursor, language, source string, seen []string, args *model.CommandArgs) string {
+	attribution := snapshot.getAttributionMessage(gif.Provider, gif.Attribution)
+	post := generateGifPost(snapshot.configuration, snapshot.botID, gif.Keywords, caption, gif.URL, gif.Description, args.ChannelId, args.RootId, attribution)
 	// Only embedded display mode works inside an ephemeral post
-	post.Message = generateGifCaption(pluginConf.DisplayModeEmbedded, keywords, caption, gifURL, p.gifProvider.GetAttributionMessage())
+	post.Message = generateGifCaption(pluginConf.DisplayModeEmbedded, snapshot.configuration.AltTextMode, snapshot.configuration.CaptionPolicy, gif.Keywords, caption, gif.URL, gif.Description, attribution)
 	post.SetProps(map[string]interface{}{
-		"attachments": generateShufflePostAttachments(keywords, caption, gifURL, cursor, args.RootId),
+		"attachments": generateShufflePostAttachments(gif, caption, cursor, args.RootId, language, source, seen),
 	})
+	// The ID is set here so that the GIF prefetched for the next shuffle can be found from the post
+	post.Id = model.NewId()
 	p.API.SendEphemeralPost(args.UserId, post)
-
-	return &model.CommandResponse{}, nil
+	return post.Id
 }
 
-func (p *Plugin) handleNoGifFound(keywords string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+// handleNoGifFound tells the user that no GIF matches the keywords, with buttons to search the terms suggested by the GIF provider
+func (p *Plugin) handleNoGifFound(ctx context.Context, snapshot *pluginSnapshot, query provider.Query, caption string, preview bool, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
 	// Create ephemeral post directly rather than with CommandResponse, so the bot can be the author
 	post := &model.Post{
-		Message:   "No GIFs found for '" + keywords + "'",
-		UserId:    p.botID,
+		Message:   "No GIFs found for '" + escapeMarkdown(query.Keywords) + "'",
+		UserId:    snapshot.botID,
 		ChannelId: args.ChannelId,
 		RootId:    args.RootId,
 	}
+	if suggestions := p.getSuggestions(ctx, snapshot, query, preview); len(suggestions) > 0 {
+		post.Message += ", try one of these searches:"
+		post.SetProps(map[string]interface{}{
+			"attachments": generateSuggestionPostAttachments(suggestions, caption, args.RootId, query.Locale),
+		})
+	}
 
 	p.API.SendEphemeralPost(args.UserId, post)
 
 	return &model.CommandResponse{}, nil
 }
 
+// ephemeralResponse returns a command response only visible by the user
+func ephemeralResponse(text string) *model.CommandResponse {
+	return &model.CommandResponse{ResponseType: model.CommandResponseTypeEphemeral, Text: text}
+}
+
+// quotedKeywordsHelp tells how to search keywords that start like a subcommand
+const quotedKeywordsHelp = " (quote the keywords starting with fav, alias, recent, schedule or admin to search them)"
+
 func getHintMessage(trigger string) string {
-	return "[happy kitty] or /" + trigger + " \"[happy kitty]\" \"[This is a custom caption]\""
+	return "[happy kitty] or /" + trigger + " \"[happy kitty]\" \"[This is a custom caption]\" or /" + trigger + " lang:fr [chat heureux]"
 }
 
-func generateGifCaption(displayMode, keywords, caption, gifURL, attributionMessage string) string {
-	captionOrKeywords := caption
+// generateGifCaption returns the Markdown of a GIF post. The keywords are displayed as plain text,
+// and the caption as allowed by the caption policy. An empty attribution message is left out.
+func generateGifCaption(displayMode, altTextMode, captionPolicy, keywords, caption, gifURL, description, attributionMessage string) string {
+	gifURL = escapeMarkdownURL(gifURL)
+	captionOrKeywords := sanitizeCaption(captionPolicy, caption)
 	if caption == "" {
-		captionOrKeywords = fmt.Sprintf("**/gif [%s](%s)**", keywords, gifURL)
+		captionOrKeywords = fmt.Sprintf("**/gif [%s](%s)**", escapeMarkdown(keywords), gifURL)
+	}
+	if altTextMode == pluginConf.AltTextModeProviderCaption && description != "" {
+		captionOrKeywords += " \n> " + escapeMarkdownText(description)
 	}
 	if displayMode == pluginConf.DisplayModeFullURL {
+		if attributionMessage == "" {
+			return fmt.Sprintf("%s \n*%s*", captionOrKeywords, gifURL)
+		}
 		return fmt.Sprintf("%s \n*%s*\n%s", captionOrKeywords, gifURL, attributionMessage)
 	}
-	return fmt.Sprintf("%s \n*%s* \n![GIF for '%s'](%s)", captionOrKeywords, attributionMessage, keywords, gifURL)
+	if attributionMessage == "" {
+		return fmt.Sprintf("%s \n![%s](%s)", captionOrKeywords, generateGifAltText(altTextMode, keywords, description), gifURL)
+	}
+	return fmt.Sprintf("%s \n*%s* \n![%s](%s)", captionOrKeywords, attributionMessage, generateGifAltText(altTextMode, keywords, description), gifURL)
 }
 
-func (p *Plugin) generateGifPost(userID, keywords, caption, gifURL, channelID, rootID, attributionMessage string) *model.Post {
+func generateGifPost(config *pluginConf.Configuration, userID, keywords, caption, gifURL, description, channelID, rootID, attributionMessage string) *model.Post {
 	return &model.Post{
-		Message:   generateGifCaption(p.getConfiguration().DisplayMode, keywords, caption, gifURL, attributionMessage),
+		Message:   generateGifCaption(config.DisplayMode, config.AltTextMode, config.CaptionPolicy, keywords, caption, gifURL, description, attributionMessage),
 		UserId:    userID,
 		ChannelId: channelID,
 		RootId:    rootID,
 	}
 }
 
-func generateShufflePostAttachments(keywords, caption, gifURL, cursor, rootID string) []*model.SlackAttachment {
+func generateShufflePostAttachments(gif *savedGif, caption, cursor, rootID, language, source string, seen []string) []*model.SlackAttachment {
 	actionContext := map[string]interface{}{
-		contextKeywords: keywords,
-		contextCaption:  caption,
-		contextGifURL:   gifURL,
-		contextCursor:   cursor,
-		contextRootID:   rootID,
+		contextKeywords:    gif.Keywords,
+		contextCaption:     caption,
+		contextGifURL:      gif.URL,
+		contextGifID:       gif.ID,
+		contextDescription: gif.Description,
+		contextProvider:    gif.Provider,
+		contextAttribution: gif.Attribution,
+		contextCursor:      cursor,
+		// Only set for the GIFs found by a search
+		contextShareReportURL: gif.ShareReportURL,
+		contextRootID:         rootID,
+		contextLanguage:       language,
+		contextSource:         source,
+		contextSeen:           seen,
 	}
 
 	actions := []*model.PostAction{}
 	actions = append(actions, generateButton("Cancel", URLCancel, "default", actionContext))
 	actions = append(actions, generateButton("Shuffle", URLShuffle, "primary", actionContext))
+	actions = append(actions, generateButton("Save", URLSave, "default", actionContext))
 	actions = append(actions, generateButton("Send", URLSend, "good", actionContext))
 
 	attachments := []*model.SlackAttachment{}
@@ -173,6 +356,44 @@ func generateShufflePostAttachments(keywords, caption, gifURL, cursor, rootID st
 	return attachments
 }
 
+// generateSuggestionPostAttachments returns the buttons that preview the GIFs of the suggested search terms
+func generateSuggestionPostAttachments(suggestions []string, caption, rootID, language string) []*model.SlackAttachment {
+	actions := []*model.PostAction{}
+	for _, suggestion := range suggestions {
+		actions = append(actions, generateButton(suggestion, URLSuggestion, "default", map[string]interface{}{
+			contextKeywords: suggestion,
+			contextCaption:  caption,
+			contextRootID:   rootID,
+			contextLanguage: language,
+		}))
+	}
+	return []*model.SlackAttachment{{Actions: actions}}
+}
+
+// generateGifPostAttachments returns the buttons displayed under a posted GIF
+func generateGifPostAttachments(gif *savedGif) []*model.SlackAttachment {
+	actionContext := map[string]interface{}{
+		contextKeywords:    gif.Keywords,
+		contextGifURL:      gif.URL,
+		contextGifID:       gif.ID,
+		contextDescription: gif.Description,
+		contextProvider:    gif.Provider,
+		contextAttribution: gif.Attribution,
+	}
+
+	return []*model.SlackAttachment{{
+		Actions: []*model.PostAction{
+			generateButton("Save", URLSave, "default", actionContext),
+			generateButton("Respond with a GIF", URLRespond, "default", actionContext),
+		},
+	}}
+}
+
+// getPluginURL returns the URL of a plugin HTTP handler, relative to the server
+func getPluginURL(path string) string {
+	return fmt.Sprintf("/plugins/%s%s", manifest.Manifest.Id, path)
+}
+
 // Generate an attachment for an action Button that will point to a plugin HTTP handler
 func generateButton(name string, urlAction string, style string, context map[string]interface{}) *model.PostAction {
 	return &model.PostAction{
@@ -180,7 +401,7 @@ func generateButton(name string, urlAction string, style string, context map[str
 		Type:  model.PostActionTypeButton,
 		Style: style,
 		Integration: &model.PostActionIntegration{
-			URL:     fmt.Sprintf("/plugins/%s%s", manifest.Manifest.Id, urlAction),
+			URL:     getPluginURL(urlAction),
 			Context: context,
 		},
 	}
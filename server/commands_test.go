@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
 
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
 	"github.com/stretchr/testify/assert"
 
 	"github.com/mattermost/mattermost-server/v6/model"
@@ -26,7 +29,7 @@ func TestRegisterCommandsShouldFailWhenRegisterGifCommandFails(t *testing.T) {
 	api.On("RegisterCommand", mock.MatchedBy(func(command *model.Command) bool { return command.Trigger == config.CommandTriggerGifWithPreview })).Return(nil)
 	api.On("UnregisterCommand", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 	p := Plugin{}
-	p.configuration = &config
+	setMockConfiguration(&p, &config)
 	p.SetAPI(api)
 
 	assert.NotNil(t, p.RegisterCommands())
@@ -40,17 +43,18 @@ func TestRegisterCommandsShouldFailWhenRegisterGifPreviewCommandFails(t *testing
 	api.On("RegisterCommand", mock.MatchedBy(func(command *model.Command) bool { return command.Trigger == config.CommandTriggerGifWithPreview })).Return(errors.New("fail mock register command"))
 	api.On("UnregisterCommand", mock.Anything, mock.Anything).Return(nil)
 	p := Plugin{}
-	p.configuration = &config
+	setMockConfiguration(&p, &config)
 	p.SetAPI(api)
 
 	assert.NotNil(t, p.RegisterCommands())
 }
 
 func TestExecuteCommandGifShouldReturnInChannelResponseWhenSearchSucceeds(t *testing.T) {
-	_, p := initMockAPI()
-	p.gifProvider = newMockGifProvider()
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	mockRecentGifs(api, nil)
 
-	response, err := p.executeCommandGif(testKeywords, testCaption, testArgs)
+	response, err := p.executeCommandGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, testCaption, testArgs)
 
 	assert.Nil(t, err)
 	assert.NotNil(t, response)
@@ -61,10 +65,10 @@ func TestExecuteCommandGifShouldReturnInChannelResponseWhenSearchSucceeds(t *tes
 
 func TestExecuteCommandGifShouldSendEphemeralPostWhenSearchReturnsNoResult(t *testing.T) {
 	api, p := initMockAPI()
-	p.gifProvider = &mockGifProvider{""}
+	setMockGifProvider(p, &mockGifProvider{""})
 	api.On("SendEphemeralPost", mock.Anything, mock.Anything).Return(nil)
 
-	response, err := p.executeCommandGif(testKeywords, testCaption, testArgs)
+	response, err := p.executeCommandGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, testCaption, testArgs)
 
 	assert.Nil(t, err)
 	assert.NotNil(t, response)
@@ -76,7 +80,7 @@ func TestExecuteCommandGifShouldSendEphemeralPostWhenSearchReturnsNoResult(t *te
 		mock.MatchedBy(func(post *model.Post) bool {
 			return post != nil &&
 				post.ChannelId == testArgs.ChannelId &&
-				post.UserId == p.botID &&
+				post.UserId == p.getSnapshot().botID &&
 				strings.Contains(post.Message, "found") &&
 				strings.Contains(post.Message, testKeywords)
 		}))
@@ -85,10 +89,10 @@ func TestExecuteCommandGifShouldSendEphemeralPostWhenSearchReturnsNoResult(t *te
 func TestExecuteCommandGifShouldLogAndFailWhenSearchFails(t *testing.T) {
 	api, p := initMockAPI()
 	errorMessage := "ARGHHHH"
-	p.gifProvider = &mockGifProviderFail{errorMessage}
+	setMockGifProvider(p, &mockGifProviderFail{errorMessage})
 	api.On("LogWarn", mock.AnythingOfType("string")).Return(nil)
 
-	response, err := p.executeCommandGif("mayhem", "guy", testArgs)
+	response, err := p.executeCommandGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: "mayhem"}, "guy", testArgs)
 	assert.NotNil(t, err)
 	assert.Empty(t, response)
 	assert.Contains(t, err.DetailedError, errorMessage)
@@ -97,14 +101,14 @@ func TestExecuteCommandGifShouldLogAndFailWhenSearchFails(t *testing.T) {
 
 func TestExecuteCommandGifWithPreviewShouldPostAnEphemeralGifPostWhenSearchSucceeds(t *testing.T) {
 	api, p := initMockAPI()
-	p.gifProvider = newMockGifProvider()
+	setMockGifProvider(p, newMockGifProvider())
 
 	var recordCreationPost *model.Post
 	api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Return(nil, nil).Run(func(args mock.Arguments) {
 		recordCreationPost = args.Get(1).(*model.Post)
 	})
 
-	response, err := p.executeCommandGifWithPreview(testKeywords, testCaption, testArgs)
+	response, err := p.executeCommandGifWithPreview(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, testCaption, testArgs)
 
 	assert.Nil(t, err)
 	assert.NotNil(t, response)
@@ -116,12 +120,44 @@ func TestExecuteCommandGifWithPreviewShouldPostAnEphemeralGifPostWhenSearchSucce
 	assert.Equal(t, recordCreationPost.ChannelId, testArgs.ChannelId)
 }
 
+func TestExecuteCommandGifWithPreviewShouldUseTheFederatedSearch(t *testing.T) {
+	api, p := initMockAPI()
+	snapshot := *p.getSnapshot()
+	snapshot.gifProvider = &mockGifProvider{"https://gif.fr/main"}
+	snapshot.previewGifProvider = &mockGifProviderSequence{gifs: []*provider.GifResult{
+		{URL: testGifURL, Provider: "tenor", Attribution: "Via Tenor"},
+		{URL: "https://gif.fr/next", Provider: "gfycat", Attribution: "Powered by Gfycat"},
+	}}
+	p.setSnapshot(&snapshot)
+	var preview *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		preview = args.Get(1).(*model.Post)
+	})
+
+	_, err := p.executeCommandGifWithPreview(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, "", testArgs)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, preview) {
+		assert.Contains(t, preview.Message, testGifURL)
+		assert.Contains(t, preview.Message, "Via Tenor")
+		assert.NotContains(t, preview.Message, "https://gif.fr/main")
+		context := preview.Attachments()[0].Actions[0].Integration.Context
+		assert.Equal(t, "Via Tenor", context[contextAttribution])
+		assert.Equal(t, "1", context[contextCursor])
+	}
+
+	mockRecentGifs(api, nil)
+	response, err := p.executeCommandGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, "", testArgs)
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "https://gif.fr/main")
+}
+
 func TestExecuteCommandGifWithPreviewShouldReturnEphemeralResponseWhenSearchReturnsNoResult(t *testing.T) {
 	api, p := initMockAPI()
-	p.gifProvider = &mockGifProvider{""}
+	setMockGifProvider(p, &mockGifProvider{""})
 	api.On("SendEphemeralPost", mock.Anything, mock.Anything).Return(nil)
 
-	response, err := p.executeCommandGifWithPreview(testKeywords, testCaption, testArgs)
+	response, err := p.executeCommandGifWithPreview(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, testCaption, testArgs)
 
 	assert.Nil(t, err)
 	assert.NotNil(t, response)
@@ -131,7 +167,7 @@ func TestExecuteCommandGifWithPreviewShouldReturnEphemeralResponseWhenSearchRetu
 		mock.MatchedBy(func(post *model.Post) bool {
 			return post != nil &&
 				post.ChannelId == testArgs.ChannelId &&
-				post.UserId == p.botID &&
+				post.UserId == p.getSnapshot().botID &&
 				strings.Contains(post.Message, "found") &&
 				strings.Contains(post.Message, testKeywords)
 		}))
@@ -139,10 +175,10 @@ func TestExecuteCommandGifWithPreviewShouldReturnEphemeralResponseWhenSearchRetu
 
 func TestExecuteCommandGifWithPreviewShouldLogAndFailWhenSearchFails(t *testing.T) {
 	api, p := initMockAPI()
-	p.gifProvider = &mockGifProviderFail{"mockError"}
+	setMockGifProvider(p, &mockGifProviderFail{"mockError"})
 	api.On("LogWarn", mock.AnythingOfType("string")).Return(nil)
 
-	response, err := p.executeCommandGifWithPreview("hello", "", nil)
+	response, err := p.executeCommandGifWithPreview(context.Background(), p.getSnapshot(), provider.Query{Keywords: "hello"}, "", testArgs)
 
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "mockError")
@@ -151,7 +187,7 @@ func TestExecuteCommandGifWithPreviewShouldLogAndFailWhenSearchFails(t *testing.
 }
 
 func TestGenerateShufflePostAttachments(t *testing.T) {
-	attachments := generateShufflePostAttachments(testKeywords, testCaption, testGifURL, testCursor, testRootID)
+	attachments := generateShufflePostAttachments(&savedGif{URL: testGifURL, ID: testGifID, Keywords: testKeywords, Description: testDescription, Provider: "tenor", Attribution: "Via Tenor"}, testCaption, testCursor, testRootID, testLanguage, sourceFavorites, []string{testGifID})
 
 	assert.NotNil(t, attachments)
 	assert.Len(t, attachments, 1)
@@ -159,15 +195,39 @@ func TestGenerateShufflePostAttachments(t *testing.T) {
 	assert.NotNil(t, attachment)
 	actions := attachment.Actions
 	assert.NotNil(t, actions)
-	assert.Len(t, actions, 3)
-	for i := 0; i < 3; i++ {
+	assert.Len(t, actions, 4)
+	for i := 0; i < 4; i++ {
 		assert.NotNil(t, actions[i].Integration)
 		context := actions[i].Integration.Context
 		assert.NotNil(t, context)
 		assert.Equal(t, context[contextKeywords], testKeywords)
 		assert.Equal(t, context[contextGifURL], testGifURL)
+		assert.Equal(t, context[contextDescription], testDescription)
 		assert.Equal(t, context[contextCursor], testCursor)
 		assert.Equal(t, context[contextRootID], testRootID)
+		assert.Equal(t, context[contextLanguage], testLanguage)
+		assert.Equal(t, context[contextSource], sourceFavorites)
+		assert.Equal(t, context[contextGifID], testGifID)
+		assert.Equal(t, context[contextSeen], []string{testGifID})
+		assert.Equal(t, context[contextProvider], "tenor")
+		assert.Equal(t, context[contextAttribution], "Via Tenor")
+	}
+}
+
+func TestGenerateGifPostAttachments(t *testing.T) {
+	attachments := generateGifPostAttachments(&savedGif{URL: testGifURL, ID: testGifID, Keywords: testKeywords, Description: testDescription, Provider: "tenor", Attribution: "Via Tenor"})
+
+	assert.Len(t, attachments, 1)
+	assert.Len(t, attachments[0].Actions, 2)
+	assert.Contains(t, attachments[0].Actions[0].Integration.URL, URLSave)
+	assert.Contains(t, attachments[0].Actions[1].Integration.URL, URLRespond)
+	for _, action := range attachments[0].Actions {
+		assert.Equal(t, action.Integration.Context[contextKeywords], testKeywords)
+		assert.Equal(t, action.Integration.Context[contextGifURL], testGifURL)
+		assert.Equal(t, action.Integration.Context[contextDescription], testDescription)
+		assert.Equal(t, action.Integration.Context[contextGifID], testGifID)
+		assert.Equal(t, action.Integration.Context[contextProvider], "tenor")
+		assert.Equal(t, action.Integration.Context[contextAttribution], "Via Tenor")
 	}
 }
 
@@ -205,3 +265,150 @@ func TestParseCommandeLine(t *testing.T) {
 		assert.Equal(t, testCase.expectedCaption, caption, "Testing: "+testCase.command)
 	}
 }
+
+func TestParseLanguageOption(t *testing.T) {
+	testCases := []struct {
+		command             string
+		expectedCommandLine string
+		expectedLanguage    string
+	}{
+		{command: "/gif happy kitty", expectedCommandLine: "/gif happy kitty", expectedLanguage: ""},
+		{command: "/gif lang:fr chat heureux", expectedCommandLine: "/gif chat heureux", expectedLanguage: "fr"},
+		{command: "/gif lang:pt-BR \"gato feliz\" \"Olá\"", expectedCommandLine: "/gif \"gato feliz\" \"Olá\"", expectedLanguage: "pt-BR"},
+		{command: "/gif happy lang:fr", expectedCommandLine: "/gif happy lang:fr", expectedLanguage: ""},
+		{command: "/gif lang:french", expectedCommandLine: "/gif lang:french", expectedLanguage: ""},
+	}
+	for _, testCase := range testCases {
+		commandLine, language := parseLanguageOption(testCase.command, triggerGif)
+		assert.Equal(t, testCase.expectedCommandLine, commandLine, "Testing: "+testCase.command)
+		assert.Equal(t, testCase.expectedLanguage, language, "Testing: "+testCase.command)
+	}
+}
+
+func TestGetUserLanguageShouldPreferCommandLanguage(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.UseUserLanguage = true
+
+	assert.Equal(t, "fr", p.getUserLanguage(p.getSnapshot(), testUserID, "fr"))
+	api.AssertNumberOfCalls(t, "GetUser", 0)
+}
+
+func TestGetUserLanguageShouldUseUserLocaleWhenEnabled(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.UseUserLanguage = true
+	api.On("GetUser", testUserID).Return(&model.User{Id: testUserID, Locale: "pt-BR"}, nil)
+
+	assert.Equal(t, "pt-BR", p.getUserLanguage(p.getSnapshot(), testUserID, ""))
+}
+
+func TestGetUserLanguageShouldIgnoreUserLocaleWhenDisabled(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.UseUserLanguage = false
+
+	assert.Equal(t, "", p.getUserLanguage(p.getSnapshot(), testUserID, ""))
+	api.AssertNumberOfCalls(t, "GetUser", 0)
+}
+
+func TestGetUserLanguageShouldFallbackWhenUserCannotBeFound(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.UseUserLanguage = true
+	api.On("GetUser", testUserID).Return(nil, model.NewAppError("test", "not found", nil, "", 404))
+	api.On("LogWarn", mock.AnythingOfType("string")).Return(nil)
+
+	assert.Equal(t, "", p.getUserLanguage(p.getSnapshot(), testUserID, ""))
+}
+
+func TestParseSubcommand(t *testing.T) {
+	subcommand, parameters := parseSubcommand("/gif schedule list", triggerGif)
+	assert.Equal(t, subcommandSchedule, subcommand)
+	assert.Equal(t, []string{"list"}, parameters)
+
+	subcommand, parameters = parseSubcommand("/gif happy kitty", triggerGif)
+	assert.Empty(t, subcommand)
+	assert.Nil(t, parameters)
+
+	subcommand, _ = parseSubcommand("/gif", triggerGif)
+	assert.Empty(t, subcommand)
+
+	subcommand, parameters = parseSubcommand("/gif \"recent news\"", triggerGif)
+	assert.Empty(t, subcommand)
+	assert.Nil(t, parameters)
+
+	subcommand, _ = parseSubcommand("/gif \"fav\" \"My favorite\"", triggerGif)
+	assert.Empty(t, subcommand)
+}
+
+func TestParseCaptionOption(t *testing.T) {
+	testCases := []struct {
+		command             string
+		expectedCommandLine string
+		expectedCaption     bool
+	}{
+		{command: "/giphy happy kitty", expectedCommandLine: "/giphy happy kitty", expectedCaption: false},
+		{command: "/giphy #caption happy kitty", expectedCommandLine: "/giphy happy kitty", expectedCaption: true},
+		{command: "/giphy happy #caption", expectedCommandLine: "/giphy happy #caption", expectedCaption: false},
+		{command: "/giphy #captions", expectedCommandLine: "/giphy #captions", expectedCaption: false},
+	}
+	for _, testCase := range testCases {
+		commandLine, keywordsAsCaption := parseCaptionOption(testCase.command, "giphy")
+		assert.Equal(t, testCase.expectedCommandLine, commandLine, "Testing: "+testCase.command)
+		assert.Equal(t, testCase.expectedCaption, keywordsAsCaption, "Testing: "+testCase.command)
+	}
+}
+
+func TestGetTriggerMode(t *testing.T) {
+	config := generateMockPluginConfig()
+	config.ParsedCommandTriggerAliases = []string{"giphy"}
+	testCases := []struct {
+		trigger             string
+		expectedSupported   bool
+		expectedWithPreview bool
+	}{
+		{trigger: triggerGif, expectedSupported: true, expectedWithPreview: false},
+		{trigger: triggerGifs, expectedSupported: true, expectedWithPreview: true},
+		{trigger: "giphy", expectedSupported: true, expectedWithPreview: true},
+		{trigger: "worm", expectedSupported: false, expectedWithPreview: false},
+		{trigger: "", expectedSupported: false, expectedWithPreview: false},
+	}
+	for _, testCase := range testCases {
+		supported, withPreview := getTriggerMode(&config, testCase.trigger)
+		assert.Equal(t, testCase.expectedSupported, supported, "Testing: "+testCase.trigger)
+		assert.Equal(t, testCase.expectedWithPreview, withPreview, "Testing: "+testCase.trigger)
+	}
+}
+
+func TestRegisterCommandsShouldUnregisterRemovedAliases(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("RegisterCommand", mock.Anything).Return(nil)
+	api.On("UnregisterCommand", "", mock.AnythingOfType("string")).Return(nil)
+	p.getSnapshot().configuration.ParsedCommandTriggerAliases = []string{"giphy"}
+
+	assert.Nil(t, p.RegisterCommands())
+	api.AssertCalled(t, "RegisterCommand", mock.MatchedBy(func(command *model.Command) bool { return command.Trigger == "giphy" }))
+	api.AssertNotCalled(t, "UnregisterCommand", "", "giphy")
+
+	config := generateMockPluginConfig()
+	setMockConfiguration(p, &config)
+	assert.Nil(t, p.RegisterCommands())
+	api.AssertCalled(t, "UnregisterCommand", "", "giphy")
+	assert.Equal(t, []string{triggerGif, triggerGifs}, p.registeredTriggers)
+}
+
+func TestRegisterCommandsShouldUnregisterRenamedTriggers(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("RegisterCommand", mock.Anything).Return(nil)
+	api.On("UnregisterCommand", "", mock.AnythingOfType("string")).Return(nil)
+
+	assert.Nil(t, p.RegisterCommands())
+	api.AssertNotCalled(t, "UnregisterCommand", mock.Anything, mock.Anything)
+
+	config := generateMockPluginConfig()
+	config.CommandTriggerGif = "img"
+	config.CommandTriggerGifWithPreview = "imgs"
+	setMockConfiguration(p, &config)
+	assert.Nil(t, p.RegisterCommands())
+	api.AssertCalled(t, "UnregisterCommand", "", triggerGif)
+	api.AssertCalled(t, "UnregisterCommand", "", triggerGifs)
+	api.AssertCalled(t, "RegisterCommand", mock.MatchedBy(func(command *model.Command) bool { return command.Trigger == "imgs" }))
+	assert.Equal(t, []string{"img", "imgs"}, p.registeredTriggers)
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"path/filepath"
+	"strings"
+	"time"
 
 	manifest "github.com/moussetc/mattermost-plugin-giphy"
 	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
@@ -14,68 +17,183 @@ import (
 	pluginapi "github.com/mattermost/mattermost-plugin-api"
 )
 
-// getConfiguration retrieves the active configuration under lock, making it safe to use
-// concurrently. The active configuration may change underneath the client of this method, but
-// the struct returned by this API call is considered immutable.
-func (p *Plugin) getConfiguration() *pluginConf.Configuration {
-	p.configurationLock.RLock()
-	defer p.configurationLock.RUnlock()
+// Maximum duration of the test search of a new GIF provider
+const warmUpTimeout = 10 * time.Second
+
+// pluginSnapshot is the state of the plugin built from a configuration. It is never modified once published:
+// a configuration change publishes a new snapshot, and the requests in progress finish with the previous one.
+type pluginSnapshot struct {
+	configuration *pluginConf.Configuration
+	gifProvider   provider.GifProvider
+	// Provider of the preview searches, that can search several providers: nil to use gifProvider
+	previewGifProvider provider.GifProvider
+	botID              string
+}
 
-	if p.configuration == nil {
-		return &pluginConf.Configuration{}
+// getSearchProvider returns the provider of a search. The previews use the federated search, if it is configured.
+func (s *pluginSnapshot) getSearchProvider(preview bool) provider.GifProvider {
+	if preview && s.previewGifProvider != nil {
+		return s.previewGifProvider
 	}
-
-	return p.configuration
+	return s.gifProvider
 }
 
-// setConfiguration replaces the active configuration under lock.
-func (p *Plugin) setConfiguration(configuration *pluginConf.Configuration) {
-	p.configurationLock.Lock()
-	defer p.configurationLock.Unlock()
+// getAttributionMessage returns the attribution of a GIF, or the attribution of the main provider if the GIF has none.
+// The alias GIFs were not found by a provider and have no attribution.
+func (s *pluginSnapshot) getAttributionMessage(gifProvider, attribution string) string {
+	if gifProvider == aliasGifProvider {
+		return ""
+	}
+	if attribution != "" {
+		return attribution
+	}
+	return s.gifProvider.GetAttributionMessage()
+}
 
-	if configuration != nil && p.configuration == configuration {
-		panic("setConfiguration called with the existing configuration")
+// getSnapshot returns the current snapshot, which is safe to use concurrently. It is read once by each hook,
+// command and HTTP request, and passed down so that the whole request uses a configuration, a GIF provider
+// and a bot that belong together, even if the configuration changes in the meantime.
+func (p *Plugin) getSnapshot() *pluginSnapshot {
+	if snapshot, ok := p.snapshot.Load().(*pluginSnapshot); ok && snapshot.configuration != nil {
+		return snapshot
 	}
+	return &pluginSnapshot{configuration: &pluginConf.Configuration{}}
+}
 
-	p.configuration = configuration
+// setSnapshot publishes a new snapshot for the next requests
+func (p *Plugin) setSnapshot(snapshot *pluginSnapshot) {
+	p.snapshot.Store(snapshot)
 }
 
 // OnConfigurationChange is invoked when configuration changes may have been made.
+// The new configuration and its GIF provider are only published once they are valid
+// and the provider answered a search, otherwise the previous snapshot stays in use.
+// Without a previous snapshot, a provider that fails to answer is only logged, so that the plugin
+// still activates and /gif admin test-config can be used to diagnose the problem.
 func (p *Plugin) OnConfigurationChange() error {
+	p.configurationLock.Lock()
+	defer p.configurationLock.Unlock()
+
 	var configuration = new(pluginConf.Configuration)
 	// Load the public configuration fields from the Mattermost server configuration.
 	if err := p.API.LoadPluginConfiguration(configuration); err != nil {
 		return errors.Wrap(err, "Failed to load plugin configuration")
 	}
-	p.setConfiguration(configuration)
+	autoReplyRules, err := pluginConf.ParseAutoReplyRules(configuration.AutoReplyRules)
+	if err != nil {
+		return err
+	}
+	configuration.ParsedAutoReplyRules = autoReplyRules
+	if err = computeCommandTriggers(configuration); err != nil {
+		return err
+	}
 
-	if configuration.DisplayMode == "" {
-		return errors.New("the Display Mode must be configured")
+	issues := validateConfiguration(configuration)
+	for _, issue := range issues {
+		if !issue.Blocking {
+			p.API.LogWarn("Check the plugin configuration", "setting", issue.Setting, "warning", issue.Message)
+		}
+	}
+	if pluginConf.HasBlockingIssue(issues) {
+		return errors.New(formatBlockingIssues(issues))
 	}
 
-	gifProvider, err := provider.GifProviderGenerator(*configuration, p.errorGenerator, p.rootURL)
+	gifProvider, appErr := provider.GifProviderGenerator(*configuration, p.errorGenerator, p.rootURL)
+	if appErr != nil {
+		return appErr
+	}
+	previewGifProvider, appErr := provider.FederatedGifProviderGenerator(*configuration, gifProvider, p.errorGenerator, p.rootURL)
+	if appErr != nil {
+		return appErr
+	}
+	if appErr = warmUpGifProvider(gifProvider, configuration); appErr != nil {
+		if _, published := p.snapshot.Load().(*pluginSnapshot); published {
+			return errors.Wrap(appErr, "the GIF provider failed to answer a test search")
+		}
+		p.API.LogWarn("The GIF provider failed to answer a test search, use /"+configuration.CommandTriggerGifWithPreview+" admin test-config to check the configuration", "error", appErr.Error())
+	}
+	botID, err := ensureBot(p)
 	if err != nil {
 		return err
 	}
-	p.gifProvider = gifProvider
+	p.setSnapshot(&pluginSnapshot{configuration: configuration, gifProvider: gifProvider, previewGifProvider: previewGifProvider, botID: botID})
+
+	return p.RegisterCommands()
+}
+
+// warmUpGifProvider searches a single GIF with a new provider before it is published,
+// so that the first commands don't pay for its connections and a provider that can't search is never used
+func warmUpGifProvider(gifProvider provider.GifProvider, configuration *pluginConf.Configuration) *model.AppError {
+	ctx, cancel := context.WithTimeout(context.Background(), warmUpTimeout)
+	defer cancel()
+	cursor := ""
+	_, appErr := gifProvider.GetGif(ctx, provider.Query{Keywords: testConfigKeywords, Budget: getRenditionBudget(configuration, "")}, &cursor)
+	return appErr
+}
+
+// validateConfiguration checks the whole configuration, including the settings that depend on the GIF provider
+func validateConfiguration(configuration *pluginConf.Configuration) []pluginConf.ValidationIssue {
+	return append(configuration.Validate(), provider.ValidateRenditions(*configuration)...)
+}
+
+// formatBlockingIssues describes the issues that prevent the plugin from working, for the System Console logs
+func formatBlockingIssues(issues []pluginConf.ValidationIssue) string {
+	messages := []string{}
+	for _, issue := range issues {
+		if issue.Blocking {
+			messages = append(messages, issue.String())
+		}
+	}
+	return "invalid plugin configuration, please fix the following settings in the System Console: " + strings.Join(messages, "; ")
+}
+
+// computeCommandTriggers sets the triggers of the commands from the configured names. By default, /gif previews
+// the GIFs if posting without preview is disabled, else /gif posts them directly and /gifs previews them.
+func computeCommandTriggers(configuration *pluginConf.Configuration) error {
+	instantTrigger := pluginConf.NormalizeCommandTrigger(configuration.InstantCommandTrigger)
+	previewTrigger := pluginConf.NormalizeCommandTrigger(configuration.PreviewCommandTrigger)
 	if configuration.DisablePostingWithoutPreview {
 		// Force preview
-		configuration.CommandTriggerGif = ""
-		configuration.CommandTriggerGifWithPreview = triggerGif
+		instantTrigger = ""
+		if previewTrigger == "" {
+			previewTrigger = triggerGif
+		}
 	} else {
 		// Slack-like syntax
-		configuration.CommandTriggerGif = triggerGif
-		configuration.CommandTriggerGifWithPreview = triggerGifs
+		if instantTrigger == "" {
+			instantTrigger = triggerGif
+		}
+		if previewTrigger == "" {
+			previewTrigger = triggerGifs
+		}
+		if err := pluginConf.ValidateCommandTrigger(instantTrigger); err != nil {
+			return errors.Wrap(err, "invalid command to post GIFs")
+		}
+		if instantTrigger == previewTrigger {
+			return errors.New("the commands to post and preview GIFs must be different: /" + previewTrigger)
+		}
 	}
-	errRegister := p.RegisterCommands()
-	if errRegister != nil {
-		return errRegister
+	if err := pluginConf.ValidateCommandTrigger(previewTrigger); err != nil {
+		return errors.Wrap(err, "invalid command to preview GIFs")
 	}
-
-	return p.defineBot(configuration.Provider)
+	reserved := []string{previewTrigger}
+	if instantTrigger != "" {
+		reserved = append(reserved, instantTrigger)
+	}
+	triggerAliases, err := pluginConf.ParseCommandTriggerAliases(configuration.CommandTriggerAliases, reserved...)
+	if err != nil {
+		return err
+	}
+	configuration.CommandTriggerGif = instantTrigger
+	configuration.CommandTriggerGifWithPreview = previewTrigger
+	configuration.ParsedCommandTriggerAliases = triggerAliases
+	return nil
 }
 
-func (p *Plugin) defineBot(provider string) error {
+// ensureBot creates the plugin's bot if needed, and returns its ID
+var ensureBot = defaultEnsureBot
+
+func defaultEnsureBot(p *Plugin) (string, error) {
 	client := pluginapi.NewClient(p.API, p.Driver)
 	bot := model.Bot{
 		Username:    "gifcommandsplugin",
@@ -84,10 +202,7 @@ func (p *Plugin) defineBot(provider string) error {
 	}
 	botID, ensureBotError := client.Bot.EnsureBot(&bot, pluginapi.ProfileImagePath(filepath.Join("assets", "icon.png")))
 	if ensureBotError != nil {
-		return errors.Wrap(ensureBotError, "failed to ensure GIF bot.")
+		return "", errors.Wrap(ensureBotError, "failed to ensure GIF bot.")
 	}
-
-	p.botID = botID
-
-	return nil
+	return botID, nil
 }
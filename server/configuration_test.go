@@ -2,11 +2,18 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	pluginError "github.com/moussetc/mattermost-plugin-giphy/server/internal/error"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
 	"github.com/moussetc/mattermost-plugin-giphy/server/internal/test"
 
+	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
 	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
 	"github.com/stretchr/testify/assert"
@@ -54,22 +61,195 @@ func TestOnConfigurationChangeGifProviderError(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
-func TestGetSetConfiguration(t *testing.T) {
-	p := Plugin{}
+func TestOnConfigurationChangeInvalidAutoReplyRules(t *testing.T) {
+	api := &plugintest.API{}
+	pluginConfig := generateMockPluginConfig()
+	pluginConfig.AutoReplyRules = `[{"phrase": "ship it"}]`
+	api.On("LoadPluginConfiguration", mock.AnythingOfType("*configuration.Configuration")).Return(mockLoadConfig(pluginConfig))
+	p := Plugin{errorGenerator: test.MockErrorGenerator()}
+	p.SetAPI(api)
+	err := p.OnConfigurationChange()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "automatic GIF reply")
+}
+
+func TestOnConfigurationChangeInvalidCommandTriggerAliases(t *testing.T) {
+	api := &plugintest.API{}
+	pluginConfig := generateMockPluginConfig()
+	pluginConfig.CommandTriggerAliases = "giphy, gifs"
+	api.On("LoadPluginConfiguration", mock.AnythingOfType("*configuration.Configuration")).Return(mockLoadConfig(pluginConfig))
+	p := Plugin{errorGenerator: test.MockErrorGenerator()}
+	p.SetAPI(api)
+	err := p.OnConfigurationChange()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "gifs")
+}
+
+func TestComputeCommandTriggers(t *testing.T) {
+	testCases := []struct {
+		testLabel              string
+		forcePreview           bool
+		instantTrigger         string
+		previewTrigger         string
+		aliases                string
+		expectedError          bool
+		expectedInstantTrigger string
+		expectedPreviewTrigger string
+		expectedAliases        []string
+	}{
+		{testLabel: "Default with forced preview", forcePreview: true, expectedInstantTrigger: "", expectedPreviewTrigger: triggerGif, expectedAliases: []string{}},
+		{testLabel: "Default without forced preview", expectedInstantTrigger: triggerGif, expectedPreviewTrigger: triggerGifs, expectedAliases: []string{}},
+		{testLabel: "Custom triggers", instantTrigger: "/IMG", previewTrigger: "imgs", aliases: "gif", expectedInstantTrigger: "img", expectedPreviewTrigger: "imgs", expectedAliases: []string{"gif"}},
+		{testLabel: "Custom trigger with forced preview", forcePreview: true, instantTrigger: "img", previewTrigger: "imgs", expectedInstantTrigger: "", expectedPreviewTrigger: "imgs", expectedAliases: []string{}},
+		{testLabel: "Same triggers", instantTrigger: "img", previewTrigger: "img", expectedError: true},
+		{testLabel: "Same as default trigger", instantTrigger: triggerGifs, expectedError: true},
+		{testLabel: "Built-in trigger", previewTrigger: "away", expectedError: true},
+		{testLabel: "Invalid trigger", instantTrigger: "my gif", expectedError: true},
+		{testLabel: "Alias used by a trigger", previewTrigger: "giphy", aliases: "giphy", expectedError: true},
+	}
+	for _, testCase := range testCases {
+		config := generateMockPluginConfig()
+		config.DisablePostingWithoutPreview = testCase.forcePreview
+		config.InstantCommandTrigger = testCase.instantTrigger
+		config.PreviewCommandTrigger = testCase.previewTrigger
+		config.CommandTriggerAliases = testCase.aliases
+		err := computeCommandTriggers(&config)
+		if testCase.expectedError {
+			assert.NotNil(t, err, testCase.testLabel)
+			continue
+		}
+		assert.Nil(t, err, testCase.testLabel)
+		assert.Equal(t, testCase.expectedInstantTrigger, config.CommandTriggerGif, testCase.testLabel)
+		assert.Equal(t, testCase.expectedPreviewTrigger, config.CommandTriggerGifWithPreview, testCase.testLabel)
+		assert.Equal(t, testCase.expectedAliases, config.ParsedCommandTriggerAliases, testCase.testLabel)
+	}
+}
+
+func TestOnConfigurationChangeShouldReportAllBlockingIssues(t *testing.T) {
+	api := &plugintest.API{}
+	pluginConfig := generateMockPluginConfig()
+	pluginConfig.APIKey = ""
+	pluginConfig.Rating = "nc-17"
+	api.On("LoadPluginConfiguration", mock.AnythingOfType("*configuration.Configuration")).Return(mockLoadConfig(pluginConfig))
+	p := Plugin{errorGenerator: test.MockErrorGenerator()}
+	p.SetAPI(api)
 
-	initialConfig := p.getConfiguration()
-	assert.NotNil(t, initialConfig)
+	err := p.OnConfigurationChange()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "API Key: an API key is required for giphy")
+	assert.Contains(t, err.Error(), "Content rating: unknown rating 'nc-17'")
+}
+
+func mockEnsureBot(t *testing.T, botID string) {
+	previous := ensureBot
+	ensureBot = func(p *Plugin) (string, error) {
+		return botID, nil
+	}
+	t.Cleanup(func() { ensureBot = previous })
+}
+
+// generateMocksForConfigurationChanges returns a plugin whose configuration has a new API key each time it is loaded,
+// with a GIF provider that returns an URL containing the API key
+func generateMocksForConfigurationChanges(t *testing.T) (*plugintest.API, *Plugin) {
+	api := &plugintest.API{}
+	var version int32
+	api.On("LoadPluginConfiguration", mock.AnythingOfType("*configuration.Configuration")).Return(func(dest interface{}) error {
+		config := generateMockPluginConfig()
+		config.APIKey = fmt.Sprintf("key%d", atomic.AddInt32(&version, 1))
+		*dest.(*pluginConf.Configuration) = config
+		return nil
+	})
+	api.On("RegisterCommand", mock.Anything).Return(nil)
+	api.On("UnregisterCommand", mock.Anything, mock.Anything).Return(nil)
+	mockEnsureBot(t, "botId42")
+	defaultGenerator := provider.GifProviderGenerator
+	provider.GifProviderGenerator = func(configuration pluginConf.Configuration, errorGenerator pluginError.PluginError, rootURL string) (provider.GifProvider, *model.AppError) {
+		return &mockGifProvider{"https://gif.fr/" + configuration.APIKey}, nil
+	}
+	t.Cleanup(func() { provider.GifProviderGenerator = defaultGenerator })
+	p := &Plugin{errorGenerator: test.MockErrorGenerator()}
+	p.SetAPI(api)
+	return api, p
+}
+
+func TestOnConfigurationChangeShouldPublishANewSnapshot(t *testing.T) {
+	_, p := generateMocksForConfigurationChanges(t)
+
+	assert.Nil(t, p.OnConfigurationChange())
+
+	snapshot := p.getSnapshot()
+	assert.Equal(t, "key1", snapshot.configuration.APIKey)
+	assert.Equal(t, "https://gif.fr/key1", snapshot.gifProvider.(*mockGifProvider).mockURL)
+	assert.Equal(t, "botId42", snapshot.botID)
+	assert.Equal(t, triggerGif, snapshot.configuration.CommandTriggerGif)
+}
 
-	initialConfig.APIKey = "COUCOU"
-	p.setConfiguration(initialConfig)
+func TestOnConfigurationChangeShouldKeepThePreviousSnapshotWhenInvalid(t *testing.T) {
+	p := generateMocksForConfigurationTesting("")
+	previous := &pluginSnapshot{configuration: &pluginConf.Configuration{DisplayMode: pluginConf.DisplayModeEmbedded}, gifProvider: newMockGifProvider(), botID: "botId42"}
+	p.setSnapshot(previous)
+
+	assert.NotNil(t, p.OnConfigurationChange())
+
+	assert.Same(t, previous, p.getSnapshot())
+}
+
+func TestOnConfigurationChangeShouldKeepThePreviousSnapshotWhenTheWarmUpFails(t *testing.T) {
+	_, p := generateMocksForConfigurationChanges(t)
+	provider.GifProviderGenerator = func(configuration pluginConf.Configuration, errorGenerator pluginError.PluginError, rootURL string) (provider.GifProvider, *model.AppError) {
+		return &mockGifProviderFail{"invalid API key"}, nil
+	}
+	previous := &pluginSnapshot{configuration: &pluginConf.Configuration{DisplayMode: pluginConf.DisplayModeEmbedded}, gifProvider: newMockGifProvider(), botID: "botId42"}
+	p.setSnapshot(previous)
+
+	err := p.OnConfigurationChange()
 
-	modifiedConfig := p.getConfiguration()
-	assert.Equal(t, initialConfig.APIKey, modifiedConfig.APIKey)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "invalid API key")
+	}
+	assert.Same(t, previous, p.getSnapshot())
+}
+
+func TestOnConfigurationChangeShouldPublishTheFirstSnapshotWhenTheWarmUpFails(t *testing.T) {
+	api, p := generateMocksForConfigurationChanges(t)
+	provider.GifProviderGenerator = func(configuration pluginConf.Configuration, errorGenerator pluginError.PluginError, rootURL string) (provider.GifProvider, *model.AppError) {
+		return &mockGifProviderFail{"no network"}, nil
+	}
+	api.On("LogWarn", mock.Anything, "error", mock.Anything).Return(nil)
 
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("The code did not panic")
+	assert.Nil(t, p.OnConfigurationChange())
+
+	assert.Equal(t, "key1", p.getSnapshot().configuration.APIKey)
+	api.AssertCalled(t, "LogWarn", mock.Anything, "error", mock.MatchedBy(func(message string) bool { return strings.Contains(message, "no network") }))
+}
+
+// Run with -race to check that the configuration changes are safe
+func TestOnConfigurationChangeShouldNotRaceWithCommands(t *testing.T) {
+	api, p := generateMocksForConfigurationChanges(t)
+	mockRecentGifs(api, nil)
+	assert.Nil(t, p.OnConfigurationChange())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			assert.Nil(t, p.OnConfigurationChange())
 		}
 	}()
-	p.setConfiguration(modifiedConfig)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif kitty", UserId: testUserID, ChannelId: testChannelID})
+				assert.Nil(t, err)
+				assert.Contains(t, response.Text, "https://gif.fr/key")
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, "https://gif.fr/key21", p.getSnapshot().gifProvider.(*mockGifProvider).mockURL)
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to avoiding duplicate GIFs, in a preview session and in the recent history of a channel
+
+const (
+	channelGifsKeyPrefix = "channelgifs_"
+	// Maximum number of GIFs in the history of a channel, the oldest ones are removed first
+	maxChannelGifs = 100
+	// Maximum number of GIFs remembered by a preview post, to keep the post small
+	maxSeenGifs = 50
+	// Maximum number of duplicate GIFs skipped in a row before showing a duplicate anyway
+	maxSkippedDuplicateGifs = 10
+)
+
+// gifKey identifies a GIF by its provider ID, or by its URL when the ID is unknown
+func gifKey(id, url string) string {
+	if id != "" {
+		return id
+	}
+	return url
+}
+
+// addSeenGif adds a GIF to the ones seen in a preview session, forgetting the oldest ones when there are too many
+func addSeenGif(seen []string, key string) []string {
+	seen = append(seen, key)
+	if len(seen) > maxSeenGifs {
+		seen = seen[len(seen)-maxSeenGifs:]
+	}
+	return seen
+}
+
+func isSeenGif(seen []string, key string) bool {
+	for _, seenKey := range seen {
+		if seenKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+func containsGif(gifs []*savedGif, key string) bool {
+	for _, gif := range gifs {
+		if gifKey(gif.ID, gif.URL) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// getChannelDuplicatesWindow returns how long the GIFs posted in a channel should not be posted again, 0 if disabled
+func getChannelDuplicatesWindow(config *pluginConf.Configuration) time.Duration {
+	return time.Duration(config.ChannelDuplicatesHours) * time.Hour
+}
+
+// removeOldChannelGifs returns the GIFs posted after the start of the window
+func removeOldChannelGifs(gifs []*savedGif, window time.Duration) []*savedGif {
+	since := model.GetMillisForTime(time.Now().Add(-window))
+	result := []*savedGif{}
+	for _, gif := range gifs {
+		if gif.SavedAt >= since {
+			result = append(result, gif)
+		}
+	}
+	return result
+}
+
+// getChannelGifs returns the GIFs recently posted in the channel, or nothing if duplicates are allowed in channels
+func (p *Plugin) getChannelGifs(snapshot *pluginSnapshot, channelID string) []*savedGif {
+	window := getChannelDuplicatesWindow(snapshot.configuration)
+	if window <= 0 || channelID == "" {
+		return nil
+	}
+	gifs, appErr := p.getSavedGifs(channelGifsKeyPrefix + channelID)
+	if appErr != nil {
+		p.API.LogWarn("Unable to read the recent GIFs of the channel", "channelID", channelID, "error", appErr.Error())
+		return nil
+	}
+	return removeOldChannelGifs(gifs, window)
+}
+
+// recordChannelGif adds a GIF posted in the channel to its history, if duplicates are not allowed in channels.
+// Failures are only logged since they must not prevent the GIF from being sent.
+func (p *Plugin) recordChannelGif(snapshot *pluginSnapshot, channelID string, gif *savedGif) {
+	window := getChannelDuplicatesWindow(snapshot.configuration)
+	if window <= 0 {
+		return
+	}
+	posted := *gif
+	posted.SavedAt = model.GetMillis()
+	appErr := p.updateSavedGifs(channelGifsKeyPrefix+channelID, func(gifs []*savedGif) []*savedGif {
+		return prependSavedGif(removeOldChannelGifs(gifs, window), &posted, maxChannelGifs)
+	})
+	if appErr != nil {
+		p.API.LogWarn("Unable to add the GIF to the recent GIFs of the channel", "channelID", channelID, "error", appErr.Error())
+	}
+}
+
+// searchNewGif returns the next GIF matching the query that was not already seen in the preview session.
+// The GIFs recently posted in the channel are also skipped, unless there is nothing else to show.
+// If too many duplicates are skipped in a row while the provider has more results, the last one is returned
+// rather than no GIF at all, and the cursor is after it so that the next search continues further.
+func (p *Plugin) searchNewGif(ctx context.Context, snapshot *pluginSnapshot, query provider.Query, cursor *string, channelID string, seen []string, preview bool) (*provider.GifResult, *model.AppError) {
+	channelGifs := p.getChannelGifs(snapshot, channelID)
+	var fallback, last *provider.GifResult
+	for skipped := 0; skipped <= maxSkippedDuplicateGifs; skipped++ {
+		gif, appErr := searchGif(ctx, snapshot, query, cursor, preview)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if gif == nil {
+			return fallback, nil
+		}
+		key := gifKey(gif.ID, gif.URL)
+		if !isSeenGif(seen, key) {
+			if !containsGif(channelGifs, key) {
+				return gif, nil
+			}
+			if fallback == nil {
+				fallback = gif
+			}
+		}
+		if *cursor == "" {
+			return fallback, nil
+		}
+		last = gif
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return last, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+var testSearchResults = []*provider.GifResult{
+	{ID: "gif1", URL: "https://gif.fr/1"},
+	{ID: "gif2", URL: "https://gif.fr/2"},
+	{ID: "gif3", URL: "https://gif.fr/3"},
+}
+
+func TestAddSeenGifShouldKeepTheLastGifs(t *testing.T) {
+	seen := []string{}
+	for i := 0; i < maxSeenGifs+5; i++ {
+		seen = addSeenGif(seen, fmt.Sprintf("gif%d", i))
+	}
+
+	assert.Len(t, seen, maxSeenGifs)
+	assert.Equal(t, "gif5", seen[0])
+	assert.Equal(t, fmt.Sprintf("gif%d", maxSeenGifs+4), seen[maxSeenGifs-1])
+}
+
+func TestGifKeyShouldFallbackToURL(t *testing.T) {
+	assert.Equal(t, "gif1", gifKey("gif1", testGifURL))
+	assert.Equal(t, testGifURL, gifKey("", testGifURL))
+}
+
+func TestSearchNewGifShouldSkipSeenGifs(t *testing.T) {
+	_, p := initMockAPI()
+	setMockGifProvider(p, &mockGifProviderSequence{gifs: testSearchResults})
+	cursor := ""
+
+	gif, err := p.searchNewGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, &cursor, testChannelID, []string{"gif1", "gif2"}, true)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, gif)
+	assert.Equal(t, "gif3", gif.ID)
+}
+
+func TestSearchNewGifShouldReturnNoGifWhenAllWereSeen(t *testing.T) {
+	_, p := initMockAPI()
+	setMockGifProvider(p, &mockGifProviderSequence{gifs: testSearchResults})
+	cursor := ""
+
+	gif, err := p.searchNewGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, &cursor, testChannelID, []string{"gif1", "gif2", "gif3"}, true)
+
+	assert.Nil(t, err)
+	assert.Nil(t, gif)
+}
+
+func TestSearchNewGifShouldReturnTheLastGifWhenTooManyInARowWereSeen(t *testing.T) {
+	_, p := initMockAPI()
+	gifs := []*provider.GifResult{}
+	seen := []string{}
+	for i := 0; i < maxSkippedDuplicateGifs+5; i++ {
+		id := fmt.Sprintf("gif%d", i)
+		gifs = append(gifs, &provider.GifResult{ID: id, URL: "https://gif.fr/" + id})
+		seen = append(seen, id)
+	}
+	setMockGifProvider(p, &mockGifProviderSequence{gifs: gifs})
+	cursor := ""
+
+	gif, err := p.searchNewGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, &cursor, testChannelID, seen, true)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, gif) {
+		assert.Equal(t, fmt.Sprintf("gif%d", maxSkippedDuplicateGifs), gif.ID)
+	}
+	assert.Equal(t, fmt.Sprintf("%d", maxSkippedDuplicateGifs+1), cursor)
+}
+
+func TestSearchNewGifShouldSkipGifsRecentlyPostedInTheChannel(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.ChannelDuplicatesHours = 24
+	setMockGifProvider(p, &mockGifProviderSequence{gifs: testSearchResults})
+	api.On("KVGet", channelGifsKeyPrefix+testChannelID).Return(mockStoredGifs([]*savedGif{
+		{ID: "gif1", URL: "https://gif.fr/1", SavedAt: model.GetMillis()},
+		// Posted before the window, can be posted again
+		{ID: "gif2", URL: "https://gif.fr/2", SavedAt: model.GetMillisForTime(time.Now().Add(-48 * time.Hour))},
+	}), nil)
+	cursor := ""
+
+	gif, err := p.searchNewGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, &cursor, testChannelID, nil, true)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, gif)
+	assert.Equal(t, "gif2", gif.ID)
+}
+
+func TestSearchNewGifShouldPostChannelDuplicateWhenThereIsNothingElse(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.ChannelDuplicatesHours = 24
+	setMockGifProvider(p, &mockGifProviderSequence{gifs: testSearchResults[:1]})
+	api.On("KVGet", channelGifsKeyPrefix+testChannelID).Return(mockStoredGifs([]*savedGif{{ID: "gif1", SavedAt: model.GetMillis()}}), nil)
+	cursor := ""
+
+	gif, err := p.searchNewGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, &cursor, testChannelID, nil, true)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, gif)
+	assert.Equal(t, "gif1", gif.ID)
+}
+
+func TestRecordChannelGifShouldDoNothingWhenDisabled(t *testing.T) {
+	api, p := initMockAPI()
+
+	p.recordChannelGif(p.getSnapshot(), testChannelID, &savedGif{ID: "gif1", URL: testGifURL})
+
+	api.AssertNotCalled(t, "KVGet", mock.Anything)
+}
+
+func TestRecordChannelGifShouldRemoveOldGifs(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.ChannelDuplicatesHours = 1
+	api.On("KVGet", channelGifsKeyPrefix+testChannelID).Return(mockStoredGifs([]*savedGif{
+		{ID: "gif2", URL: "https://gif.fr/2", SavedAt: model.GetMillis()},
+		{ID: "gif3", URL: "https://gif.fr/3", SavedAt: model.GetMillisForTime(time.Now().Add(-2 * time.Hour))},
+	}), nil)
+	api.On("KVCompareAndSet", channelGifsKeyPrefix+testChannelID, mock.Anything, mock.Anything).Return(true, nil)
+
+	p.recordChannelGif(p.getSnapshot(), testChannelID, &savedGif{ID: "gif1", URL: "https://gif.fr/1"})
+
+	api.AssertCalled(t, "KVCompareAndSet", channelGifsKeyPrefix+testChannelID, mock.Anything, mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil &&
+			len(gifs) == 2 &&
+			gifs[0].ID == "gif1" && gifs[0].SavedAt > 0 &&
+			gifs[1].ID == "gif2"
+	}))
+}
+
+func TestHandleShuffleShouldSkipGifsSeenInThePreview(t *testing.T) {
+	api, p := initMockAPI()
+	// The provider gives the second GIF again with the next cursor
+	setMockGifProvider(p, &mockGifProviderSequence{gifs: []*provider.GifResult{testSearchResults[0], testSearchResults[1], testSearchResults[1], testSearchResults[2]}})
+	api.On("UpdateEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil)
+	request := generateTestIntegrationRequest()
+	request.Cursor = "2"
+	request.Seen = []string{"gif1", "gif2"}
+	h := &defaultHTTPHandler{}
+	w := httptest.NewRecorder()
+
+	h.handleShuffle(p, p.getSnapshot(), w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertCalled(t, "UpdateEphemeralPost", testUserID, mock.MatchedBy(func(post *model.Post) bool {
+		context := post.Attachments()[0].Actions[0].Integration.Context
+		return context[contextGifID] == "gif3" &&
+			assert.ObjectsAreEqual([]string{"gif1", "gif2", "gif3"}, context[contextSeen])
+	}))
+}
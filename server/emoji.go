@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/emoji"
+)
+
+// Contains what's related to searching the emoji of the keywords
+
+// normalizeKeywords replaces the emoji and emoji shortcodes of the keywords by words the GIF providers can search,
+// including the names of the server's custom emoji
+func (p *Plugin) normalizeKeywords(keywords string) string {
+	return emoji.Normalize(keywords, p.isCustomEmoji)
+}
+
+func (p *Plugin) isCustomEmoji(name string) bool {
+	customEmoji, appErr := p.API.GetEmojiByName(name)
+	return appErr == nil && customEmoji != nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteGifCommandShouldSearchTheWordsOfTheEmoji(t *testing.T) {
+	api, p := initMockAPI()
+	mockRecentGifs(api, nil)
+	gifProvider := &mockGifProviderKeywords{urls: map[string]string{"party party parrot :nope:": testGifURL}}
+	setMockGifProvider(p, gifProvider)
+	api.On("GetEmojiByName", "party_parrot").Return(&model.Emoji{Name: "party_parrot"}, nil)
+	api.On("GetEmojiByName", "nope").Return(nil, model.NewAppError("GetEmojiByName", "not found", nil, "", http.StatusNotFound))
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/gif 🎉 :party_parrot: :nope:", UserId: testUserID})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"party party parrot :nope:"}, gifProvider.searches)
+	if assert.NotNil(t, response) {
+		assert.Contains(t, response.Text, "**/gif [party party parrot :nope:]("+testGifURL+")**")
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to the GIFs saved by each user in their favorites
+
+const (
+	subcommandFavorites = "fav"
+
+	favoritesKeyPrefix = "favorites_"
+	// Maximum number of favorites per user, the oldest ones are removed first
+	maxFavorites = 200
+)
+
+func (p *Plugin) getFavorites(userID string) ([]*savedGif, *model.AppError) {
+	return p.getSavedGifs(favoritesKeyPrefix + userID)
+}
+
+func (p *Plugin) saveFavorite(userID string, gif *savedGif) *model.AppError {
+	return p.updateSavedGifs(favoritesKeyPrefix+userID, func(gifs []*savedGif) []*savedGif {
+		return prependSavedGif(gifs, gif, maxFavorites)
+	})
+}
+
+// executeCommandFavorites returns an ephemeral post to browse the user's favorite GIFs matching the optional filter
+func (p *Plugin) executeCommandFavorites(snapshot *pluginSnapshot, parameters []string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	filter := strings.Trim(strings.Join(parameters, " "), "\"")
+	emptyMessage := "You have no favorite GIFs yet, use the Save button under a GIF to add one."
+	if filter != "" {
+		emptyMessage = "None of your favorite GIFs matches '" + escapeMarkdown(filter) + "'."
+	}
+	return p.executeCommandCollectionPreview(snapshot, sourceFavorites, "", filter, "", emptyMessage, args)
+}
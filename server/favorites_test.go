@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+func mockStoredGifs(gifs []*savedGif) []byte {
+	data, _ := json.Marshal(gifs)
+	return data
+}
+
+func TestExecuteCommandFavoritesShouldSendPreviewOfFirstMatchingFavorite(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	api.On("KVGet", favoritesKeyPrefix+testUserID).Return(mockStoredGifs(testSavedGifs), nil)
+	var preview *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		preview = args.Get(1).(*model.Post)
+	})
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gifs fav dog", UserId: testUserID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	assert.NotNil(t, preview)
+	assert.Contains(t, preview.Message, "https://gif.fr/2")
+	assert.Contains(t, preview.Message, "sad dog")
+	attachments := preview.Attachments()
+	assert.Len(t, attachments, 1)
+	assert.Equal(t, sourceFavorites, attachments[0].Actions[0].Integration.Context[contextSource])
+}
+
+func TestExecuteCommandFavoritesShouldExplainWhenThereAreNoFavorites(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", favoritesKeyPrefix+testUserID).Return(nil, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gifs fav", UserId: testUserID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+	assert.Contains(t, response.Text, "no favorite")
+}
+
+func TestHandleSaveShouldAddGifToFavorites(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", favoritesKeyPrefix+testUserID).Return(nil, nil)
+	api.On("KVCompareAndSet", favoritesKeyPrefix+testUserID, mock.Anything, mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil &&
+			len(gifs) == 1 &&
+			gifs[0].URL == testGifURL &&
+			gifs[0].Keywords == testKeywords &&
+			gifs[0].Provider == "giphy"
+	})).Return(true, nil)
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil)
+	h := &defaultHTTPHandler{}
+	w := httptest.NewRecorder()
+
+	h.handleSave(p, p.getSnapshot(), w, generateTestIntegrationRequest())
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertCalled(t, "SendEphemeralPost", testUserID, mock.MatchedBy(func(post *model.Post) bool {
+		return strings.Contains(post.Message, "saved")
+	}))
+}
+
+func TestHandleShuffleShouldBrowseFavorites(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	api.On("KVGet", favoritesKeyPrefix+testUserID).Return(mockStoredGifs(testSavedGifs), nil)
+	api.On("UpdateEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil)
+	request := generateTestIntegrationRequest()
+	request.Source = sourceFavorites
+	request.Cursor = encodeCollectionCursor(collectionCursor{Filter: "dog", Position: 1})
+	h := &defaultHTTPHandler{}
+	w := httptest.NewRecorder()
+
+	h.handleShuffle(p, p.getSnapshot(), w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertCalled(t, "UpdateEphemeralPost", testUserID, mock.MatchedBy(func(post *model.Post) bool {
+		return strings.Contains(post.Message, "https://gif.fr/3") && strings.Contains(post.Message, "Happy dog")
+	}))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to the GIFs posted in reply to a GIF post, a GIF equivalent of the emoji reactions
+
+const (
+	dialogElementKeywords = "keywords"
+	dialogElementCaption  = "caption"
+)
+
+// generateRespondDialog returns the dialog asking for the GIF to post in the thread of a post
+func generateRespondDialog(triggerID, postRootID, postID string) model.OpenDialogRequest {
+	rootID := postRootID
+	if rootID == "" {
+		rootID = postID
+	}
+	return model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       getPluginURL(URLRespondDialog),
+		Dialog: model.Dialog{
+			CallbackId:  "respond",
+			Title:       "Respond with a GIF",
+			SubmitLabel: "Preview",
+			// The thread where the GIF will be posted
+			State: rootID,
+			Elements: []model.DialogElement{
+				{
+					DisplayName: "Keywords",
+					Name:        dialogElementKeywords,
+					Type:        "text",
+					Placeholder: "happy kitty",
+					MaxLength:   100,
+				},
+				{
+					DisplayName: "Caption",
+					Name:        dialogElementCaption,
+					Type:        "text",
+					Optional:    true,
+					MaxLength:   300,
+				},
+			},
+		},
+	}
+}
+
+// isGifPost returns true if the post is a GIF posted by the plugin
+func isGifPost(post *model.Post) bool {
+	for _, attachment := range post.Attachments() {
+		if isGifPostAttachment(attachment) {
+			return true
+		}
+	}
+	return false
+}
+
+func isGifPostAttachment(attachment *model.SlackAttachment) bool {
+	for _, action := range attachment.Actions {
+		if action.Integration != nil && action.Integration.URL == getPluginURL(URLRespond) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateGifReplyCount shows the number of GIF replies of a thread on its root post, if it's a GIF post.
+// The replies are counted again each time so that concurrent GIF replies can't lead to a wrong count.
+func (p *Plugin) updateGifReplyCount(rootID string) {
+	thread, appErr := p.API.GetPostThread(rootID)
+	if appErr != nil {
+		p.API.LogWarn("Unable to read the thread to count its GIF replies", "rootID", rootID, "error", appErr.Error())
+		return
+	}
+	root := thread.Posts[rootID]
+	if root == nil || !isGifPost(root) {
+		return
+	}
+	count := 0
+	for id, post := range thread.Posts {
+		if id != rootID && isGifPost(post) {
+			count++
+		}
+	}
+	attachments := root.Attachments()
+	for _, attachment := range attachments {
+		if isGifPostAttachment(attachment) {
+			attachment.Footer = formatGifReplyCount(count)
+		}
+	}
+	root.AddProp("attachments", attachments)
+	if _, appErr = p.API.UpdatePost(root); appErr != nil {
+		p.API.LogWarn("Unable to show the number of GIF replies", "rootID", rootID, "error", appErr.Error())
+	}
+}
+
+func formatGifReplyCount(count int) string {
+	if count == 1 {
+		return "1 GIF reply"
+	}
+	return fmt.Sprintf("%d GIF replies", count)
+}
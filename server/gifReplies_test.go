@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+func generateTestGifPost(id, rootID string) *model.Post {
+	post := &model.Post{Id: id, RootId: rootID, ChannelId: testChannelID}
+	post.AddProp("attachments", generateGifPostAttachments(&savedGif{URL: testGifURL, Keywords: testKeywords}))
+	return post
+}
+
+func TestGenerateRespondDialogShouldTargetTheThreadOfThePost(t *testing.T) {
+	assert.Equal(t, testPostID, generateRespondDialog("trigger", "", testPostID).Dialog.State)
+	assert.Equal(t, testRootID, generateRespondDialog("trigger", testRootID, testPostID).Dialog.State)
+	request := generateRespondDialog("trigger", "", testPostID)
+	assert.Equal(t, "trigger", request.TriggerId)
+	assert.Contains(t, request.URL, URLRespondDialog)
+}
+
+func TestHandleRespondShouldOpenDialog(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("GetPost", testPostID).Return(generateTestGifPost(testPostID, ""), nil)
+	api.On("OpenInteractiveDialog", mock.AnythingOfType("model.OpenDialogRequest")).Return(nil)
+	request := generateTestIntegrationRequest()
+	request.TriggerId = "trigger42"
+	h := &defaultHTTPHandler{}
+	w := httptest.NewRecorder()
+
+	h.handleRespond(p, p.getSnapshot(), w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertCalled(t, "OpenInteractiveDialog", mock.MatchedBy(func(dialog model.OpenDialogRequest) bool {
+		return dialog.TriggerId == "trigger42" && dialog.Dialog.State == testPostID
+	}))
+}
+
+func TestHandleRespondDialogShouldSendPreviewInThread(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil)
+	h := &defaultHTTPHandler{}
+	w := httptest.NewRecorder()
+
+	h.handleRespondDialog(p, p.getSnapshot(), w, &model.SubmitDialogRequest{
+		UserId:     testUserID,
+		ChannelId:  testChannelID,
+		State:      testRootID,
+		Submission: map[string]interface{}{dialogElementKeywords: "high five"},
+	})
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertCalled(t, "SendEphemeralPost", testUserID, mock.MatchedBy(func(post *model.Post) bool {
+		attachments := post.Attachments()
+		return post.RootId == testRootID &&
+			len(attachments) == 1 &&
+			attachments[0].Actions[0].Integration.Context[contextRootID] == testRootID &&
+			attachments[0].Actions[0].Integration.Context[contextKeywords] == "high five"
+	}))
+}
+
+func TestHandleRespondDialogShouldRequireKeywords(t *testing.T) {
+	_, p := initMockAPI()
+	h := &defaultHTTPHandler{}
+	w := httptest.NewRecorder()
+
+	h.handleRespondDialog(p, p.getSnapshot(), w, &model.SubmitDialogRequest{UserId: testUserID, ChannelId: testChannelID, State: testRootID, Submission: map[string]interface{}{}})
+
+	var response model.SubmitDialogResponse
+	assert.Nil(t, json.NewDecoder(w.Result().Body).Decode(&response))
+	assert.NotEmpty(t, response.Errors[dialogElementKeywords])
+}
+
+func TestHandleHTTPRequestShouldRejectDialogSubmissionOfAnotherUser(t *testing.T) {
+	p := setupMockPluginWithAuthent()
+	body, _ := json.Marshal(&model.SubmitDialogRequest{UserId: "someone-else", ChannelId: testChannelID})
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", URLRespondDialog, bytes.NewBuffer(body))
+	r.Header.Add("Mattermost-User-Id", testUserID)
+
+	p.handleHTTPRequest(p.getSnapshot(), w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestUpdateGifReplyCountShouldCountGifRepliesOnRootPost(t *testing.T) {
+	api, p := initMockAPI()
+	thread := model.NewPostList()
+	thread.AddPost(generateTestGifPost(testRootID, ""))
+	thread.AddPost(generateTestGifPost("reply1", testRootID))
+	thread.AddPost(generateTestGifPost("reply2", testRootID))
+	thread.AddPost(&model.Post{Id: "reply3", RootId: testRootID, Message: "lol"})
+	api.On("GetPostThread", testRootID).Return(thread, nil)
+	api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+
+	p.updateGifReplyCount(testRootID)
+
+	api.AssertCalled(t, "UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Id == testRootID && post.Attachments()[0].Footer == "2 GIF replies"
+	}))
+}
+
+func TestUpdateGifReplyCountShouldIgnoreRootPostsThatAreNotGifs(t *testing.T) {
+	api, p := initMockAPI()
+	thread := model.NewPostList()
+	thread.AddPost(&model.Post{Id: testRootID, Message: "Who wants cake?"})
+	thread.AddPost(generateTestGifPost("reply1", testRootID))
+	api.On("GetPostThread", testRootID).Return(thread, nil)
+
+	p.updateGifReplyCount(testRootID)
+
+	api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
 
 	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin"
@@ -19,6 +22,12 @@ const (
 	URLShuffle = "/shuffle"
 	URLCancel  = "/cancel"
 	URLSend    = "/send"
+	URLSave    = "/save"
+	URLRespond = "/respond"
+	// Preview of a search term suggested when no GIF was found
+	URLSuggestion = "/suggestion"
+	// Submission of the dialog opened by the Respond button
+	URLRespondDialog = "/respond/dialog"
 )
 
 type integrationRequest struct {
@@ -27,21 +36,40 @@ type integrationRequest struct {
 	GifURL   string `mapstructure:"gifURL"`
 	Cursor   string `mapstructure:"cursor"`
 	RootID   string `mapstructure:"rootID"`
+	Language string `mapstructure:"language"`
+	Source   string `mapstructure:"source"`
+	// Description of the GIF from the provider
+	Description string `mapstructure:"description"`
+	GifID       string `mapstructure:"gifId"`
+	// Provider that found the GIF and its attribution message, empty for the configured provider
+	Provider    string `mapstructure:"provider"`
+	Attribution string `mapstructure:"attribution"`
+	// URL given by the provider to report that the GIF was posted
+	ShareReportURL string `mapstructure:"shareReportUrl"`
+	// Keys of the GIFs already shown in the preview session
+	Seen []string `mapstructure:"seen"`
+	// User agent of the client that sent the request
+	UserAgent string `mapstructure:"-"`
 	model.PostActionIntegrationRequest
 }
 
 type (
 	pluginHTTPHandler interface {
-		handleCancel(p *Plugin, w http.ResponseWriter, request *integrationRequest)
-		handleShuffle(p *Plugin, w http.ResponseWriter, request *integrationRequest)
-		handleSend(p *Plugin, w http.ResponseWriter, request *integrationRequest)
+		handleCancel(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest)
+		handleShuffle(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest)
+		handleSend(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest)
+		handleSave(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest)
+		handleRespond(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest)
+		handleRespondDialog(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *model.SubmitDialogRequest)
+		handleSuggestion(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest)
 	}
 	defaultHTTPHandler struct{}
 )
 
 var notifyUserOfError = defaultNotifyUserOfError
 
-func (p *Plugin) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
+// handleHTTPRequest dispatches a request to its handler, the whole request uses the snapshot
+func (p *Plugin) handleHTTPRequest(snapshot *pluginSnapshot, w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
@@ -54,6 +82,12 @@ func (p *Plugin) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Dialog submissions are not post actions and have their own format
+	if r.URL.Path == URLRespondDialog {
+		p.handleDialogHTTPRequest(snapshot, w, r, userID)
+		return
+	}
+
 	request, err := parseRequest(r)
 	if err != nil {
 		p.API.LogWarn("Could not parse PostActionIntegrationRequest: "+err.Error(), nil)
@@ -65,27 +99,53 @@ func (p *Plugin) handleHTTPRequest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "The user of the request should match the authenticated user", http.StatusBadRequest)
 		return
 	}
+	request.UserAgent = r.UserAgent()
 	if !p.API.HasPermissionToChannel(request.UserId, request.ChannelId, model.PermissionReadChannel) {
 		http.Error(w, "The user is not allowed to read this channel", http.StatusForbidden)
 		return
 	}
-	if !p.API.HasPermissionToChannel(request.UserId, request.ChannelId, model.PermissionCreatePost) {
+	// Saving a GIF to the user's favorites does not post anything
+	if r.URL.Path != URLSave && !p.API.HasPermissionToChannel(request.UserId, request.ChannelId, model.PermissionCreatePost) {
 		http.Error(w, "The user is not allowed to post in this channel", http.StatusForbidden)
 		return
 	}
 
 	switch r.URL.Path {
 	case URLShuffle:
-		p.httpHandler.handleShuffle(p, w, request)
+		p.httpHandler.handleShuffle(p, snapshot, w, request)
 	case URLSend:
-		p.httpHandler.handleSend(p, w, request)
+		p.httpHandler.handleSend(p, snapshot, w, request)
 	case URLCancel:
-		p.httpHandler.handleCancel(p, w, request)
+		p.httpHandler.handleCancel(p, snapshot, w, request)
+	case URLSave:
+		p.httpHandler.handleSave(p, snapshot, w, request)
+	case URLRespond:
+		p.httpHandler.handleRespond(p, snapshot, w, request)
+	case URLSuggestion:
+		p.httpHandler.handleSuggestion(p, snapshot, w, request)
 	default:
 		http.NotFound(w, r)
 	}
 }
 
+func (p *Plugin) handleDialogHTTPRequest(snapshot *pluginSnapshot, w http.ResponseWriter, r *http.Request, userID string) {
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogWarn("Could not parse SubmitDialogRequest: "+err.Error(), nil)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if userID != request.UserId {
+		http.Error(w, "The user of the request should match the authenticated user", http.StatusBadRequest)
+		return
+	}
+	if !p.API.HasPermissionToChannel(request.UserId, request.ChannelId, model.PermissionCreatePost) {
+		http.Error(w, "The user is not allowed to post in this channel", http.StatusForbidden)
+		return
+	}
+	p.httpHandler.handleRespondDialog(p, snapshot, w, &request)
+}
+
 func parseRequest(r *http.Request) (*integrationRequest, error) {
 	// Read data added by default for a button action
 	body, readErr := ioutil.ReadAll(r.Body)
@@ -104,12 +164,33 @@ func parseRequest(r *http.Request) (*integrationRequest, error) {
 	if context.Keywords == "" {
 		return nil, errors.New("missing " + contextKeywords + " from action request context")
 	}
-	if context.GifURL == "" {
+	// The suggestions are search terms, not GIFs
+	if context.GifURL == "" && r.URL.Path != URLSuggestion {
 		return nil, errors.New("missing " + contextGifURL + " from action request context")
 	}
 	return &context, err
 }
 
+// getProvider returns the provider that found the GIF of the request
+func (r *integrationRequest) getProvider(config *pluginConf.Configuration) string {
+	if r.Provider != "" {
+		return r.Provider
+	}
+	return config.Provider
+}
+
+// getGif returns the GIF of the request, with the configured provider if the request doesn't name one
+func (r *integrationRequest) getGif(config *pluginConf.Configuration) *savedGif {
+	return &savedGif{
+		URL:         r.GifURL,
+		ID:          r.GifID,
+		Keywords:    r.Keywords,
+		Description: r.Description,
+		Provider:    r.getProvider(config),
+		Attribution: r.Attribution,
+	}
+}
+
 func writeResponse(httpStatus int, w http.ResponseWriter) {
 	w.WriteHeader(httpStatus)
 	if httpStatus == http.StatusOK {
@@ -123,68 +204,222 @@ func writeResponse(httpStatus int, w http.ResponseWriter) {
 	}
 }
 
-// Delete the ephemeral shuffle post
-func (h *defaultHTTPHandler) handleCancel(p *Plugin, w http.ResponseWriter, request *integrationRequest) {
+// Delete the ephemeral shuffle post, aborting its shuffle in progress
+func (h *defaultHTTPHandler) handleCancel(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	p.previewSearches.cancel(request.PostId)
+	p.prefetchedGifs.discard(request.PostId)
 	p.API.DeleteEphemeralPost(request.UserId, request.PostId)
 	writeResponse(http.StatusOK, w)
 }
 
 // Replace the GIF in the ephemeral shuffle post by a new one
-func (h *defaultHTTPHandler) handleShuffle(p *Plugin, w http.ResponseWriter, request *integrationRequest) {
+func (h *defaultHTTPHandler) handleShuffle(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
 	if request.Cursor == "" {
-		notifyUserOfError(p.API, p.botID, "No more GIFs found for '"+request.Keywords+"'", nil, &request.PostActionIntegrationRequest)
+		notifyUserOfError(p.API, snapshot.botID, "No more GIFs found for '"+escapeMarkdown(request.Keywords)+"'", nil, &request.PostActionIntegrationRequest)
+		return
+	}
+	ctx, done := p.previewSearches.start(request.PostId)
+	defer done()
+	query := p.newQuery(snapshot, request.Keywords, request.Language, request.UserAgent)
+	shuffledGif, err := p.getShuffledGif(ctx, snapshot, request, query)
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// The preview was canceled, sent or shuffled again in the meantime
+		writeResponse(http.StatusOK, w)
 		return
 	}
-	shuffledGifURL, err := p.gifProvider.GetGifURL(request.Keywords, &request.Cursor)
 	if err != nil {
-		notifyUserOfError(p.API, p.botID, "Unable to fetch a new Gif for shuffling", err, &request.PostActionIntegrationRequest)
+		notifyUserOfError(p.API, snapshot.botID, "Unable to fetch a new Gif for shuffling", err, &request.PostActionIntegrationRequest)
 		writeResponse(http.StatusServiceUnavailable, w)
 		return
 	}
-	if shuffledGifURL == "" {
-		notifyUserOfError(p.API, p.botID, "No GIFs found for '"+request.Keywords+"'", nil, &request.PostActionIntegrationRequest)
+	if shuffledGif == nil {
+		notifyUserOfError(p.API, snapshot.botID, "No GIFs found for '"+escapeMarkdown(request.Keywords)+"'", nil, &request.PostActionIntegrationRequest)
 		return
 	}
 	time := model.GetMillis()
 	post := &model.Post{
 		Id:        request.PostId,
 		ChannelId: request.ChannelId,
-		UserId:    p.botID,
+		UserId:    snapshot.botID,
 		RootId:    request.RootID,
 		// Only embedded display mode works inside an ephemeral post
-		Message:  generateGifCaption(pluginConf.DisplayModeEmbedded, request.Keywords, request.Caption, shuffledGifURL, p.gifProvider.GetAttributionMessage()),
+		Message:  generateGifCaption(pluginConf.DisplayModeEmbedded, snapshot.configuration.AltTextMode, snapshot.configuration.CaptionPolicy, shuffledGif.Keywords, request.Caption, shuffledGif.URL, shuffledGif.Description, snapshot.getAttributionMessage(shuffledGif.Provider, shuffledGif.Attribution)),
 		CreateAt: time,
 		UpdateAt: time,
 	}
+	seen := request.Seen
+	if request.Source == sourceSearch {
+		seen = addSeenGif(seen, gifKey(shuffledGif.ID, shuffledGif.URL))
+	}
 	post.SetProps(map[string]interface{}{
-		"attachments": generateShufflePostAttachments(request.Keywords, request.Caption, shuffledGifURL, request.Cursor, request.RootID),
+		"attachments": generateShufflePostAttachments(shuffledGif, request.Caption, request.Cursor, request.RootID, request.Language, request.Source, seen),
 	})
 	p.API.UpdateEphemeralPost(request.UserId, post)
+	if request.Source == sourceSearch {
+		p.prefetchPreviewGif(snapshot, request.PostId, query, request.Cursor, request.ChannelId, seen)
+	}
 	writeResponse(http.StatusOK, w)
 }
 
+// getShuffledGif returns the next GIF of the preview, the prefetched one if it is available, and updates the cursor
+func (p *Plugin) getShuffledGif(ctx context.Context, snapshot *pluginSnapshot, request *integrationRequest, query provider.Query) (*savedGif, *model.AppError) {
+	if request.Source == sourceSearch {
+		if gif, cursor, ok := p.prefetchedGifs.take(ctx, snapshot, request.PostId, request.Cursor); ok {
+			request.Cursor = cursor
+			return gif, nil
+		}
+	}
+	return p.getPreviewGif(ctx, snapshot, request.Source, query, request.UserId, request.TeamId, request.ChannelId, request.Seen, &request.Cursor)
+}
+
 // Post the actual GIF and delete the obsolete ephemeral post
-func (h *defaultHTTPHandler) handleSend(p *Plugin, w http.ResponseWriter, request *integrationRequest) {
+func (h *defaultHTTPHandler) handleSend(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	p.previewSearches.cancel(request.PostId)
+	p.prefetchedGifs.discard(request.PostId)
 	p.API.DeleteEphemeralPost(request.UserId, request.PostId)
+	config := snapshot.configuration
 	time := model.GetMillis()
 	post := &model.Post{
-		Message:   generateGifCaption(p.getConfiguration().DisplayMode, request.Keywords, request.Caption, request.GifURL, p.gifProvider.GetAttributionMessage()),
+		Message:   generateGifCaption(config.DisplayMode, config.AltTextMode, config.CaptionPolicy, request.Keywords, request.Caption, request.GifURL, request.Description, snapshot.getAttributionMessage(request.Provider, request.Attribution)),
 		UserId:    request.UserId,
 		ChannelId: request.ChannelId,
 		RootId:    request.RootID,
 		CreateAt:  time,
 		UpdateAt:  time,
 	}
+	gif := request.getGif(config)
+	post.AddProp("attachments", generateGifPostAttachments(gif))
 	_, err := p.API.CreatePost(post)
 	if err != nil {
-		notifyUserOfError(p.API, p.botID, "Unable to create post : ", err, &request.PostActionIntegrationRequest)
+		notifyUserOfError(p.API, snapshot.botID, "Unable to create post : ", err, &request.PostActionIntegrationRequest)
+		writeResponse(http.StatusInternalServerError, w)
+		return
+	}
+	p.recordRecentGif(snapshot, request.UserId, gif)
+	p.recordChannelGif(snapshot, request.ChannelId, gif)
+	if request.RootID != "" && config.ShowGifReplyCount {
+		p.updateGifReplyCount(request.RootID)
+	}
+	if request.Source == sourceSearch {
+		p.reportShare(snapshot, true, provider.Share{
+			Provider:  request.getProvider(config),
+			GifID:     request.GifID,
+			Query:     provider.Query{Keywords: request.Keywords, Locale: request.Language},
+			ReportURL: request.ShareReportURL,
+		})
+	}
+
+	writeResponse(http.StatusOK, w)
+}
+
+// Add the GIF to the user's favorites
+func (h *defaultHTTPHandler) handleSave(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	gif := request.getGif(snapshot.configuration)
+	gif.SavedAt = model.GetMillis()
+	err := p.saveFavorite(request.UserId, gif)
+	if err != nil {
+		notifyUserOfError(p.API, snapshot.botID, "Unable to save the GIF to your favorites", err, &request.PostActionIntegrationRequest)
+		writeResponse(http.StatusInternalServerError, w)
+		return
+	}
+	p.API.SendEphemeralPost(request.UserId, &model.Post{
+		Message:   "*GIF for '" + escapeMarkdown(request.Keywords) + "' saved to your favorites, use `/" + snapshot.configuration.CommandTriggerGifWithPreview + " " + subcommandFavorites + "` to find it.*",
+		ChannelId: request.ChannelId,
+		UserId:    snapshot.botID,
+		RootId:    request.RootID,
+	})
+	writeResponse(http.StatusOK, w)
+}
+
+// Open a dialog asking for the keywords of a GIF to post in the thread of the GIF post
+func (h *defaultHTTPHandler) handleRespond(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	post, err := p.API.GetPost(request.PostId)
+	if err != nil {
+		notifyUserOfError(p.API, snapshot.botID, "Unable to find the post to respond to", err, &request.PostActionIntegrationRequest)
 		writeResponse(http.StatusInternalServerError, w)
 		return
 	}
+	if err = p.API.OpenInteractiveDialog(generateRespondDialog(request.TriggerId, post.RootId, post.Id)); err != nil {
+		notifyUserOfError(p.API, snapshot.botID, "Unable to open the GIF dialog", err, &request.PostActionIntegrationRequest)
+		writeResponse(http.StatusInternalServerError, w)
+		return
+	}
+	writeResponse(http.StatusOK, w)
+}
+
+// Send the GIF preview requested in the Respond dialog, in the thread of the original post
+func (h *defaultHTTPHandler) handleRespondDialog(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *model.SubmitDialogRequest) {
+	if request.Cancelled {
+		writeDialogResponse(nil, w)
+		return
+	}
+	keywords, _ := request.Submission[dialogElementKeywords].(string)
+	caption, _ := request.Submission[dialogElementCaption].(string)
+	if strings.TrimSpace(keywords) == "" {
+		writeDialogResponse(map[string]string{dialogElementKeywords: "Please enter some keywords"}, w)
+		return
+	}
+	args := &model.CommandArgs{
+		UserId:    request.UserId,
+		ChannelId: request.ChannelId,
+		TeamId:    request.TeamId,
+		RootId:    request.State,
+	}
+	ctx, cancel := newSearchContext()
+	defer cancel()
+	var response *model.CommandResponse
+	var err *model.AppError
+	if alias, isAlias := parseAliasToken(strings.TrimSpace(keywords)); isAlias {
+		response, err = p.executeCommandGifFromAlias(snapshot, alias, caption, true, args)
+	} else {
+		response, err = p.executeCommandGifWithPreview(ctx, snapshot, p.newQuery(snapshot, strings.TrimSpace(keywords), p.getUserLanguage(snapshot, request.UserId, ""), ""), caption, args)
+	}
+	if err != nil {
+		writeDialogResponse(map[string]string{dialogElementKeywords: err.Message}, w)
+		return
+	}
+	if response != nil && response.Text != "" {
+		p.API.SendEphemeralPost(request.UserId, &model.Post{
+			Message:   response.Text,
+			ChannelId: request.ChannelId,
+			UserId:    snapshot.botID,
+			RootId:    request.State,
+		})
+	}
+	writeDialogResponse(nil, w)
+}
 
+// Replace the "No GIFs found" post by the preview of the suggested search term
+func (h *defaultHTTPHandler) handleSuggestion(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	p.API.DeleteEphemeralPost(request.UserId, request.PostId)
+	args := &model.CommandArgs{
+		UserId:    request.UserId,
+		ChannelId: request.ChannelId,
+		TeamId:    request.TeamId,
+		RootId:    request.RootID,
+	}
+	ctx, cancel := newSearchContext()
+	defer cancel()
+	query := p.newQuery(snapshot, request.Keywords, request.Language, request.UserAgent)
+	if _, err := p.executeCommandGifWithPreview(ctx, snapshot, query, request.Caption, args); err != nil {
+		notifyUserOfError(p.API, snapshot.botID, "Unable to fetch a GIF for '"+escapeMarkdown(request.Keywords)+"'", err, &request.PostActionIntegrationRequest)
+		writeResponse(http.StatusServiceUnavailable, w)
+		return
+	}
 	writeResponse(http.StatusOK, w)
 }
 
+func writeDialogResponse(elementErrors map[string]string, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if elementErrors != nil {
+		json, jsonErr := json.Marshal(&model.SubmitDialogResponse{Errors: elementErrors})
+		if jsonErr == nil {
+			_, _ = w.Write(json)
+		}
+	}
+}
+
 // Informs the user of an error (domain error with message, or technical error with err) that occurred in a button handler, and logs it if it's technical
 func defaultNotifyUserOfError(api plugin.API, botID string, message string, err *model.AppError, request *model.PostActionIntegrationRequest) {
 	fullMessage := message
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -10,7 +11,9 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
 	"github.com/moussetc/mattermost-plugin-giphy/server/internal/test"
 
 	"github.com/stretchr/testify/assert"
@@ -22,14 +25,17 @@ import (
 )
 
 const (
-	testChannelID = "gifs-channel"
-	testCaption   = "Message prêt à tout"
-	testUserID    = "gif-user"
-	testPostID    = "skfqsldjhfkljhf"
-	testKeywords  = "kitty"
-	testGifURL    = "https://gif.fr/gif/42"
-	testCursor    = "43abc"
-	testRootID    = "4242abc"
+	testChannelID   = "gifs-channel"
+	testCaption     = "Message prêt à tout"
+	testUserID      = "gif-user"
+	testPostID      = "skfqsldjhfkljhf"
+	testKeywords    = "kitty"
+	testGifURL      = "https://gif.fr/gif/42"
+	testCursor      = "43abc"
+	testRootID      = "4242abc"
+	testLanguage    = "pt-BR"
+	testDescription = "A kitty jumps into a box"
+	testGifID       = "kitty42"
 )
 
 var testPostActionIntegrationRequest = model.PostActionIntegrationRequest{
@@ -42,6 +48,9 @@ var testPostActionIntegrationRequest = model.PostActionIntegrationRequest{
 		contextKeywords: testKeywords,
 		contextCursor:   testCursor,
 		contextRootID:   testRootID,
+		contextLanguage: testLanguage,
+		contextGifID:    testGifID,
+		contextSeen:     []string{testGifID},
 	},
 }
 
@@ -52,7 +61,13 @@ func generatePostActionIntegrationRequestBody() io.Reader {
 
 func generateTestIntegrationRequest() *integrationRequest {
 	return &integrationRequest{
-		testGifURL, testKeywords, testCaption, testCursor, testRootID, testPostActionIntegrationRequest,
+		Keywords:                     testKeywords,
+		Caption:                      testCaption,
+		GifURL:                       testGifURL,
+		Cursor:                       testCursor,
+		RootID:                       testRootID,
+		Language:                     testLanguage,
+		PostActionIntegrationRequest: testPostActionIntegrationRequest,
 	}
 }
 
@@ -69,13 +84,13 @@ func setupMockPluginWithAuthent() *Plugin {
 func TestHandleHTTPRequestShouldReturnOKStatusForAllSupportedRoutes(t *testing.T) {
 	p := setupMockPluginWithAuthent()
 
-	goodURLs := [3]string{URLCancel, URLShuffle, URLSend}
+	goodURLs := [6]string{URLCancel, URLShuffle, URLSend, URLSave, URLRespond, URLSuggestion}
 	for _, URL := range goodURLs {
 		w := httptest.NewRecorder()
 		r := httptest.NewRequest("POST", URL, generatePostActionIntegrationRequestBody())
 		r.Header.Add("Mattermost-User-Id", testUserID)
 
-		p.handleHTTPRequest(w, r)
+		p.handleHTTPRequest(p.getSnapshot(), w, r)
 
 		result := w.Result()
 		assert.NotNil(t, result)
@@ -89,7 +104,7 @@ func TestHandleHTTPRequestShouldFailWhenBadMethodIsUsed(t *testing.T) {
 	r := httptest.NewRequest("GET", URLSend, nil)
 	r.Header.Add("Mattermost-User-Id", testUserID)
 
-	p.handleHTTPRequest(w, r)
+	p.handleHTTPRequest(p.getSnapshot(), w, r)
 
 	result := w.Result()
 	assert.NotNil(t, result)
@@ -102,7 +117,7 @@ func TestHandleHTTPRequestShouldFailWhenUnsupportedURLIsUsed(t *testing.T) {
 	r := httptest.NewRequest("POST", "/unexistingURL", generatePostActionIntegrationRequestBody())
 	r.Header.Add("Mattermost-User-Id", testUserID)
 
-	p.handleHTTPRequest(w, r)
+	p.handleHTTPRequest(p.getSnapshot(), w, r)
 
 	result := w.Result()
 	assert.NotNil(t, result)
@@ -114,7 +129,7 @@ func TestHandleHTTPRequestShouldFailWhenMissingAuthHeader(t *testing.T) {
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("POST", URLSend, nil)
 
-	p.handleHTTPRequest(w, r)
+	p.handleHTTPRequest(p.getSnapshot(), w, r)
 
 	result := w.Result()
 	assert.NotNil(t, result)
@@ -127,7 +142,7 @@ func TestHandleHTTPRequestShouldFailWhenAuthHeaderDoesntMatchRequestUser(t *test
 	r := httptest.NewRequest("POST", URLSend, generatePostActionIntegrationRequestBody())
 	r.Header.Add("Mattermost-User-Id", "differentUserId")
 
-	p.handleHTTPRequest(w, r)
+	p.handleHTTPRequest(p.getSnapshot(), w, r)
 
 	result := w.Result()
 	assert.NotNil(t, result)
@@ -149,7 +164,7 @@ func TestHandleHTTPRequestShouldFailWhenUserDontHavePostingPermissionToChannel(t
 	r := httptest.NewRequest("POST", URLSend, generatePostActionIntegrationRequestBody())
 	r.Header.Add("Mattermost-User-Id", testUserID)
 
-	p.handleHTTPRequest(w, r)
+	p.handleHTTPRequest(p.getSnapshot(), w, r)
 
 	result := w.Result()
 	assert.NotNil(t, result)
@@ -169,6 +184,9 @@ func TestParseRequestShouldParseAllValuesFromCorrectRequest(t *testing.T) {
 	assert.Equal(t, request.Keywords, testKeywords)
 	assert.Equal(t, request.Cursor, testCursor)
 	assert.Equal(t, request.RootID, testRootID)
+	assert.Equal(t, request.Language, testLanguage)
+	assert.Equal(t, request.GifID, testGifID)
+	assert.Equal(t, request.Seen, []string{testGifID})
 }
 
 func TestParseRequestShouldFailIfRequestIfBodyCantBeRead(t *testing.T) {
@@ -246,7 +264,7 @@ func TestHandleCancelShouldDeleteEphemeralPost(t *testing.T) {
 	p.SetAPI(api)
 	h := &defaultHTTPHandler{}
 	w := httptest.NewRecorder()
-	h.handleCancel(&p, w, generateTestIntegrationRequest())
+	h.handleCancel(&p, p.getSnapshot(), w, generateTestIntegrationRequest())
 	assert.Equal(t, w.Result().StatusCode, http.StatusOK)
 	api.AssertCalled(t,
 		"DeleteEphemeralPost",
@@ -254,22 +272,65 @@ func TestHandleCancelShouldDeleteEphemeralPost(t *testing.T) {
 		mock.MatchedBy(func(postId string) bool { return postId == testPostID }))
 }
 
+// mockGifProviderBlocking waits until the search is canceled
+type mockGifProviderBlocking struct {
+	started chan struct{}
+}
+
+func (m *mockGifProviderBlocking) GetGif(ctx context.Context, query provider.Query, cursor *string) (*provider.GifResult, *model.AppError) {
+	close(m.started)
+	<-ctx.Done()
+	return nil, (test.MockErrorGenerator()).FromError("Error calling the API", ctx.Err())
+}
+
+func (m *mockGifProviderBlocking) GetAttributionMessage() string {
+	return "test"
+}
+
+func TestHandleCancelShouldAbortTheShuffleInProgress(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("DeleteEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	p := Plugin{}
+	p.SetAPI(api)
+	gifProvider := &mockGifProviderBlocking{started: make(chan struct{})}
+	setMockGifProvider(&p, gifProvider)
+	h := &defaultHTTPHandler{}
+
+	shuffleRecorder := httptest.NewRecorder()
+	shuffled := make(chan struct{})
+	go func() {
+		h.handleShuffle(&p, p.getSnapshot(), shuffleRecorder, generateTestIntegrationRequest())
+		close(shuffled)
+	}()
+	<-gifProvider.started
+	h.handleCancel(&p, p.getSnapshot(), httptest.NewRecorder(), generateTestIntegrationRequest())
+
+	select {
+	case <-shuffled:
+	case <-time.After(time.Second):
+		t.Fatal("the shuffle was not aborted")
+	}
+	assert.Equal(t, http.StatusOK, shuffleRecorder.Result().StatusCode)
+	api.AssertNumberOfCalls(t, "UpdateEphemeralPost", 0)
+	assert.Empty(t, p.previewSearches.searches)
+}
+
 func TestHandleShuffleShouldUpdateEphemeralPostWhenSearchSucceeds(t *testing.T) {
 	api := &plugintest.API{}
 	api.On("UpdateEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Return(nil)
 	p := Plugin{}
 	p.SetAPI(api)
-	p.gifProvider = newMockGifProvider()
+	setMockGifProvider(&p, newMockGifProvider())
 	h := &defaultHTTPHandler{}
 	w := httptest.NewRecorder()
-	h.handleShuffle(&p, w, generateTestIntegrationRequest())
+	h.handleShuffle(&p, p.getSnapshot(), w, generateTestIntegrationRequest())
 	assert.Equal(t, w.Result().StatusCode, http.StatusOK)
 	api.AssertCalled(t, "UpdateEphemeralPost",
 		mock.MatchedBy(func(s string) bool { return s == testUserID }),
 		mock.MatchedBy(func(post *model.Post) bool {
 			return post.Id == testPostID &&
 				strings.Contains(post.Message, "fakeURL") &&
-				post.UserId == p.botID &&
+				post.UserId == p.getSnapshot().botID &&
 				post.ChannelId == testChannelID &&
 				post.RootId == testRootID
 		}))
@@ -284,11 +345,11 @@ func TestHandleShuffleShouldNotifyUserWhenSearchReturnsNoResult(t *testing.T) {
 	}
 	p := Plugin{}
 	p.SetAPI(api)
-	p.gifProvider = &mockGifProvider{""}
-	p.botID = "bot"
+	setMockGifProvider(&p, &mockGifProvider{""})
+	setMockBotID(&p, "bot")
 	h := &defaultHTTPHandler{}
 	w := httptest.NewRecorder()
-	h.handleShuffle(&p, w, generateTestIntegrationRequest())
+	h.handleShuffle(&p, p.getSnapshot(), w, generateTestIntegrationRequest())
 	assert.Equal(t, w.Result().StatusCode, http.StatusOK)
 	assert.True(t, notifyUserWasCalled)
 }
@@ -297,7 +358,7 @@ func TestHandleShuffleShouldFailWhenSearchFails(t *testing.T) {
 	api := &plugintest.API{}
 	p := Plugin{}
 	p.SetAPI(api)
-	p.gifProvider = &mockGifProviderFail{"fakeURL"}
+	setMockGifProvider(&p, &mockGifProviderFail{"fakeURL"})
 	h := &defaultHTTPHandler{}
 
 	notifyUserOfError = func(api plugin.API, botId string, message string, err *model.AppError, request *model.PostActionIntegrationRequest) {
@@ -305,7 +366,7 @@ func TestHandleShuffleShouldFailWhenSearchFails(t *testing.T) {
 	}
 
 	w := httptest.NewRecorder()
-	h.handleShuffle(&p, w, generateTestIntegrationRequest())
+	h.handleShuffle(&p, p.getSnapshot(), w, generateTestIntegrationRequest())
 	assert.Equal(t, w.Result().StatusCode, http.StatusServiceUnavailable)
 	api.AssertNumberOfCalls(t, "UpdateEphemeralPost", 0)
 }
@@ -314,12 +375,13 @@ func TestHandleSendSHouldDeleteTheEphemeralPostAndCreateANewPostWhenSearchSuccee
 	api := &plugintest.API{}
 	api.On("DeleteEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
 	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	mockRecentGifs(api, nil)
 	p := Plugin{}
 	p.SetAPI(api)
-	p.gifProvider = newMockGifProvider()
+	setMockGifProvider(&p, newMockGifProvider())
 	h := &defaultHTTPHandler{}
 	w := httptest.NewRecorder()
-	h.handleSend(&p, w, generateTestIntegrationRequest())
+	h.handleSend(&p, p.getSnapshot(), w, generateTestIntegrationRequest())
 	assert.Equal(t, w.Result().StatusCode, http.StatusOK)
 	api.AssertCalled(t,
 		"DeleteEphemeralPost",
@@ -336,6 +398,25 @@ func TestHandleSendSHouldDeleteTheEphemeralPostAndCreateANewPostWhenSearchSuccee
 	)
 }
 
+func TestHandleSendShouldUseTheAttributionOfTheGif(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("DeleteEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	mockRecentGifs(api, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+	setMockGifProvider(&p, newMockGifProvider())
+	request := generateTestIntegrationRequest()
+	request.Provider = "tenor"
+	request.Attribution = "Via Tenor"
+
+	(&defaultHTTPHandler{}).handleSend(&p, p.getSnapshot(), httptest.NewRecorder(), request)
+
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return strings.Contains(post.Message, "Via Tenor")
+	}))
+}
+
 func TestHandleSendShouldFailWhenCreatePostFails(t *testing.T) {
 	api := &plugintest.API{}
 	api.On("DeleteEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
@@ -346,10 +427,10 @@ func TestHandleSendShouldFailWhenCreatePostFails(t *testing.T) {
 
 	p := Plugin{}
 	p.SetAPI(api)
-	p.gifProvider = newMockGifProvider()
+	setMockGifProvider(&p, newMockGifProvider())
 	h := &defaultHTTPHandler{}
 	w := httptest.NewRecorder()
-	h.handleSend(&p, w, generateTestIntegrationRequest())
+	h.handleSend(&p, p.getSnapshot(), w, generateTestIntegrationRequest())
 	assert.Equal(t, w.Result().StatusCode, http.StatusInternalServerError)
 }
 
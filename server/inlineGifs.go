@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+)
+
+// Contains what's related to the GIF markers inside regular messages, ex: "Hello gif!(happy kitty)"
+
+const (
+	inlineGifMarkerStart = "gif!("
+	// Number of markers replaced by GIFs in a message when the limit is not configured
+	defaultInlineGifsLimit = 3
+)
+
+var inlineGifMarker = regexp.MustCompile(`gif!\(([^()\n]+)\)`)
+
+// MessageWillBePosted replaces the GIF markers of a message by the matching GIFs
+func (p *Plugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*model.Post, string) {
+	snapshot := p.getSnapshot()
+	config := snapshot.configuration
+	if !config.EnableInlineGifs || post.UserId == snapshot.botID || !strings.Contains(post.Message, inlineGifMarkerStart) {
+		return nil, ""
+	}
+	limit := config.InlineGifsLimit
+	if limit <= 0 {
+		limit = defaultInlineGifsLimit
+	}
+
+	// The post is held until all the markers are replaced, so they share a short deadline
+	ctx, cancel := newSearchContextWithTimeout(inlineGifsTimeout)
+	defer cancel()
+	codeSpans := findCodeSpans(post.Message)
+	count := 0
+	language := ""
+	var message strings.Builder
+	end := 0
+	for _, marker := range inlineGifMarker.FindAllStringSubmatchIndex(post.Message, -1) {
+		if isInCodeSpan(codeSpans, marker[0]) {
+			continue
+		}
+		count++
+		if count > limit {
+			break
+		}
+		if count == 1 {
+			language = p.getUserLanguage(snapshot, post.UserId, "")
+		}
+		keywords := strings.TrimSpace(post.Message[marker[2]:marker[3]])
+		message.WriteString(post.Message[end:marker[0]])
+		message.WriteString("\n" + p.generateInlineGif(ctx, snapshot, keywords, language) + "\n")
+		end = marker[1]
+	}
+	if count == 0 {
+		return nil, ""
+	}
+	if count > limit {
+		p.API.LogDebug("Too many GIF markers in message, only the first ones were replaced", "limit", limit)
+	}
+	message.WriteString(post.Message[end:])
+
+	post.Message = strings.TrimSpace(message.String())
+	return post, ""
+}
+
+// findCodeSpans returns the start and end offsets of the fenced code blocks and inline code spans of the message,
+// where the GIF markers are displayed as they are written
+func findCodeSpans(message string) [][2]int {
+	spans := [][2]int{}
+	fence := ""
+	fenceStart := 0
+	for start := 0; start < len(message); {
+		end := strings.IndexByte(message[start:], '\n')
+		if end < 0 {
+			end = len(message)
+		} else {
+			end += start + 1
+		}
+		line := strings.TrimLeft(message[start:end], " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(line, fence) {
+				spans = append(spans, [2]int{fenceStart, end})
+				fence = ""
+			}
+		case strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~"):
+			fence = line[:3]
+			fenceStart = start
+		default:
+			spans = append(spans, findInlineCodeSpans(message[start:end], start)...)
+		}
+		start = end
+	}
+	if fence != "" {
+		// An unclosed fence lasts until the end of the message
+		spans = append(spans, [2]int{fenceStart, len(message)})
+	}
+	return spans
+}
+
+// findInlineCodeSpans returns the spans of a line between two runs of the same number of backticks
+func findInlineCodeSpans(line string, offset int) [][2]int {
+	spans := [][2]int{}
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		run := i
+		for run < len(line) && line[run] == '`' {
+			run++
+		}
+		ticks := line[i:run]
+		closing := run
+		for {
+			next := strings.Index(line[closing:], ticks)
+			if next < 0 {
+				closing = -1
+				break
+			}
+			closing += next
+			if closing+len(ticks) == len(line) || line[closing+len(ticks)] != '`' {
+				break
+			}
+			// Longer run of backticks: not the closing one
+			for closing < len(line) && line[closing] == '`' {
+				closing++
+			}
+		}
+		if closing < 0 {
+			i = run
+			continue
+		}
+		spans = append(spans, [2]int{offset + i, offset + closing + len(ticks)})
+		i = closing + len(ticks)
+	}
+	return spans
+}
+
+func isInCodeSpan(spans [][2]int, offset int) bool {
+	for _, span := range spans {
+		if offset >= span[0] && offset < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// generateInlineGif returns the Markdown of a GIF matching the keywords, or a message explaining why no GIF is available
+func (p *Plugin) generateInlineGif(ctx context.Context, snapshot *pluginSnapshot, keywords, language string) string {
+	cursor := ""
+	gif, appErr := searchGif(ctx, snapshot, p.newQuery(snapshot, keywords, language, ""), &cursor, false)
+	if appErr != nil {
+		p.API.LogWarn("Unable to get a GIF for an inline marker", "error", appErr.Error())
+		return "*(Unable to get a GIF for '" + escapeMarkdown(keywords) + "')*"
+	}
+	if gif == nil {
+		return "*(No GIFs found for '" + escapeMarkdown(keywords) + "')*"
+	}
+	config := snapshot.configuration
+	return generateGifCaption(config.DisplayMode, config.AltTextMode, config.CaptionPolicy, keywords, "", gif.URL, getGifDescription(gif), snapshot.gifProvider.GetAttributionMessage())
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+func TestMessageWillBePostedShouldIgnoreMessagesWhenDisabled(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.EnableInlineGifs = false
+	setMockGifProvider(p, newMockGifProvider())
+
+	post, rejection := p.MessageWillBePosted(nil, &model.Post{UserId: testUserID, Message: "Hello gif!(happy kitty)"})
+
+	assert.Nil(t, post)
+	assert.Empty(t, rejection)
+}
+
+func TestMessageWillBePostedShouldIgnoreMessagesWithoutMarker(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.EnableInlineGifs = true
+	setMockGifProvider(p, newMockGifProvider())
+
+	post, rejection := p.MessageWillBePosted(nil, &model.Post{UserId: testUserID, Message: "Hello gif! (not a marker)"})
+
+	assert.Nil(t, post)
+	assert.Empty(t, rejection)
+}
+
+func TestMessageWillBePostedShouldReplaceMarkersByGifs(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.EnableInlineGifs = true
+	setMockGifProvider(p, &mockGifProvider{testGifURL})
+
+	post, rejection := p.MessageWillBePosted(nil, &model.Post{UserId: testUserID, Message: "Hello gif!(happy kitty) and gif!( sad dog )"})
+
+	assert.Empty(t, rejection)
+	assert.NotNil(t, post)
+	assert.True(t, strings.HasPrefix(post.Message, "Hello"))
+	assert.NotContains(t, post.Message, inlineGifMarkerStart)
+	assert.Contains(t, post.Message, "![GIF for 'happy kitty']("+testGifURL+")")
+	assert.Contains(t, post.Message, "![GIF for 'sad dog']("+testGifURL+")")
+}
+
+func TestMessageWillBePostedShouldApplyLimit(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.EnableInlineGifs = true
+	p.getSnapshot().configuration.InlineGifsLimit = 1
+	setMockGifProvider(p, &mockGifProvider{testGifURL})
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Return()
+
+	post, _ := p.MessageWillBePosted(nil, &model.Post{UserId: testUserID, Message: "gif!(happy kitty) gif!(sad dog)"})
+
+	assert.Contains(t, post.Message, "happy kitty']")
+	assert.Contains(t, post.Message, "gif!(sad dog)")
+}
+
+func TestMessageWillBePostedShouldUseFallbackWhenNoGifFound(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.EnableInlineGifs = true
+	setMockGifProvider(p, &mockGifProvider{""})
+
+	post, _ := p.MessageWillBePosted(nil, &model.Post{UserId: testUserID, Message: "Hello gif!(happy kitty)"})
+
+	assert.Contains(t, post.Message, "No GIFs found for 'happy kitty'")
+}
+
+func TestFindCodeSpans(t *testing.T) {
+	testCases := []struct {
+		message  string
+		expected []string
+	}{
+		{message: "no code", expected: []string{}},
+		{message: "a `b` c ``d`e`` f", expected: []string{"`b`", "``d`e``"}},
+		{message: "unclosed `b and ``c", expected: []string{}},
+		{message: "`b ``c`", expected: []string{"`b ``c`"}},
+		{message: "```go\ngif!(a)\n```\nafter `x`", expected: []string{"```go\ngif!(a)\n```\n", "`x`"}},
+		{message: "before\n  ~~~\nunclosed `fence`", expected: []string{"  ~~~\nunclosed `fence`"}},
+	}
+	for _, testCase := range testCases {
+		spans := []string{}
+		for _, span := range findCodeSpans(testCase.message) {
+			spans = append(spans, testCase.message[span[0]:span[1]])
+		}
+		assert.Equal(t, testCase.expected, spans, testCase.message)
+	}
+}
+
+func TestMessageWillBePostedShouldIgnoreMarkersInCode(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.EnableInlineGifs = true
+	setMockGifProvider(p, &mockGifProvider{testGifURL})
+
+	post, rejection := p.MessageWillBePosted(nil, &model.Post{UserId: testUserID, Message: "Use `gif!(happy kitty)` like this:\n```\ngif!(sad dog)\n```\ngif!(party parrot)"})
+
+	assert.Empty(t, rejection)
+	assert.NotNil(t, post)
+	assert.Contains(t, post.Message, "`gif!(happy kitty)`")
+	assert.Contains(t, post.Message, "```\ngif!(sad dog)\n```")
+	assert.Contains(t, post.Message, "![GIF for 'party parrot']("+testGifURL+")")
+}
+
+func TestMessageWillBePostedShouldIgnoreMessagesWithMarkersOnlyInCode(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.EnableInlineGifs = true
+	setMockGifProvider(p, &mockGifProvider{testGifURL})
+
+	post, rejection := p.MessageWillBePosted(nil, &model.Post{UserId: testUserID, Message: "Type `gif!(happy kitty)` in a message"})
+
+	assert.Nil(t, post)
+	assert.Empty(t, rejection)
+}
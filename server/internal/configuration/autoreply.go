@@ -0,0 +1,73 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AutoReplyRule defines a message that the plugin bot automatically replies to with a GIF
+type AutoReplyRule struct {
+	// Phrase that triggers the reply, matched as whole words ignoring case, or a regular expression if Regex is set
+	Phrase string `json:"phrase"`
+	Regex  bool   `json:"regex"`
+	// Keywords used to search the reply GIF
+	Keywords string `json:"keywords"`
+	// Optional scope of the rule: a channel or a team. The rule applies everywhere if both are empty.
+	ChannelID string `json:"channel_id"`
+	TeamID    string `json:"team_id"`
+	// Minimum delay between two replies of this rule in the same channel
+	CooldownSeconds int `json:"cooldown_seconds"`
+	// Probability to reply when the phrase matches, between 0 (excluded) and 1. Defaults to 1.
+	Probability float64 `json:"probability"`
+
+	matcher *regexp.Regexp
+}
+
+// ParseAutoReplyRules reads the JSON list of rules configured in the System Console
+func ParseAutoReplyRules(rulesJSON string) ([]*AutoReplyRule, error) {
+	if strings.TrimSpace(rulesJSON) == "" {
+		return nil, nil
+	}
+	var rules []*AutoReplyRule
+	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+		return nil, errors.Wrap(err, "the automatic GIF replies must be a JSON list of rules")
+	}
+	for i, rule := range rules {
+		if rule == nil || rule.Phrase == "" || rule.Keywords == "" {
+			return nil, fmt.Errorf("the automatic GIF reply rule #%d must have a phrase and keywords", i+1)
+		}
+		if rule.Probability < 0 || rule.Probability > 1 {
+			return nil, fmt.Errorf("the probability of the automatic GIF reply rule #%d must be between 0 and 1", i+1)
+		}
+		if rule.Probability == 0 {
+			rule.Probability = 1
+		}
+		if rule.CooldownSeconds < 0 {
+			return nil, fmt.Errorf("the cooldown of the automatic GIF reply rule #%d cannot be negative", i+1)
+		}
+		pattern := `(?i)(^|\W)` + regexp.QuoteMeta(rule.Phrase) + `($|\W)`
+		if rule.Regex {
+			pattern = rule.Phrase
+		}
+		matcher, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "the phrase of the automatic GIF reply rule #%d is not a valid regular expression", i+1)
+		}
+		rule.matcher = matcher
+	}
+	return rules, nil
+}
+
+// Matches returns true if the message contains the phrase of the rule
+func (r *AutoReplyRule) Matches(message string) bool {
+	return r.matcher != nil && r.matcher.MatchString(message)
+}
+
+// AppliesTo returns true if the rule applies to the given channel of the given team
+func (r *AutoReplyRule) AppliesTo(channelID, teamID string) bool {
+	return (r.ChannelID == "" || r.ChannelID == channelID) && (r.TeamID == "" || r.TeamID == teamID)
+}
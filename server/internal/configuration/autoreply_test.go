@@ -0,0 +1,59 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAutoReplyRules(t *testing.T) {
+	testCases := []struct {
+		testLabel     string
+		rules         string
+		expectedError bool
+		expectedCount int
+	}{
+		{testLabel: "No rules", rules: "", expectedError: false, expectedCount: 0},
+		{testLabel: "Invalid JSON", rules: "{ not json", expectedError: true},
+		{testLabel: "Missing keywords", rules: `[{"phrase": "ship it"}]`, expectedError: true},
+		{testLabel: "Missing phrase", rules: `[{"keywords": "ship"}]`, expectedError: true},
+		{testLabel: "Invalid probability", rules: `[{"phrase": "ship it", "keywords": "ship", "probability": 2}]`, expectedError: true},
+		{testLabel: "Negative cooldown", rules: `[{"phrase": "ship it", "keywords": "ship", "cooldown_seconds": -1}]`, expectedError: true},
+		{testLabel: "Invalid regex", rules: `[{"phrase": "ship (it", "regex": true, "keywords": "ship"}]`, expectedError: true},
+		{testLabel: "OK", rules: `[{"phrase": "ship it", "keywords": "ship"}, {"phrase": "^coffee\\?$", "regex": true, "keywords": "coffee"}]`, expectedError: false, expectedCount: 2},
+	}
+	for _, testCase := range testCases {
+		rules, err := ParseAutoReplyRules(testCase.rules)
+		if testCase.expectedError {
+			assert.NotNil(t, err, testCase.testLabel)
+		} else {
+			assert.Nil(t, err, testCase.testLabel)
+			assert.Len(t, rules, testCase.expectedCount, testCase.testLabel)
+		}
+	}
+}
+
+func TestAutoReplyRuleDefaultProbability(t *testing.T) {
+	rules, err := ParseAutoReplyRules(`[{"phrase": "ship it", "keywords": "ship"}]`)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.0, rules[0].Probability)
+}
+
+func TestAutoReplyRuleMatches(t *testing.T) {
+	rules, err := ParseAutoReplyRules(`[{"phrase": "ship it", "keywords": "ship"}, {"phrase": "^coffee\\?$", "regex": true, "keywords": "coffee"}]`)
+	assert.Nil(t, err)
+
+	assert.True(t, rules[0].Matches("ship it"))
+	assert.True(t, rules[0].Matches("OK, Ship It!"))
+	assert.False(t, rules[0].Matches("relationship items"))
+	assert.True(t, rules[1].Matches("coffee?"))
+	assert.False(t, rules[1].Matches("coffee? now"))
+}
+
+func TestAutoReplyRuleAppliesTo(t *testing.T) {
+	assert.True(t, (&AutoReplyRule{}).AppliesTo("channel", "team"))
+	assert.True(t, (&AutoReplyRule{ChannelID: "channel"}).AppliesTo("channel", "team"))
+	assert.False(t, (&AutoReplyRule{ChannelID: "channel"}).AppliesTo("otherChannel", "team"))
+	assert.True(t, (&AutoReplyRule{TeamID: "team"}).AppliesTo("channel", "team"))
+	assert.False(t, (&AutoReplyRule{TeamID: "team"}).AppliesTo("channel", "otherTeam"))
+}
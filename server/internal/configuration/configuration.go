@@ -11,11 +11,41 @@ type Configuration struct {
 	Rendition                    string
 	RenditionGfycat              string
 	RenditionTenor               string
+	RenditionFallbacks           string
+	MaxGifSizeKB                 int
+	MaxGifDimension              int
+	MobileMaxGifSizeKB           int
+	MobileMaxGifDimension        int
 	APIKey                       string
+	UseUserLanguage              bool
 	DisablePostingWithoutPreview bool
+	AutoReplyRules               string
+	EnableInlineGifs             bool
+	InlineGifsLimit              int
+	ShowGifReplyCount            bool
+	AltTextMode                  string
+	CaptionPolicy                string
+	RequireGifDescription        bool
+	ChannelDuplicatesHours       int
+	PrefetchPreviewGifs          bool
+	InstantCommandTrigger        string
+	PreviewCommandTrigger        string
+	CommandTriggerAliases        string
+	HTTPConnectTimeoutSeconds    int
+	HTTPRequestTimeoutSeconds    int
+	HTTPProxyURL                 string
+	HTTPCACertificates           string
+	HTTPMaxRetries               int
+	FederatedProviders           string
+	FederatedTimeoutSeconds      int
+	GiphyAPIKey                  string
+	TenorAPIKey                  string
 	// Computed fields:
 	CommandTriggerGif            string
 	CommandTriggerGifWithPreview string
+	ParsedAutoReplyRules         []*AutoReplyRule
+	// Additional triggers that preview a GIF, like the Slack /giphy command
+	ParsedCommandTriggerAliases []string
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -31,3 +61,21 @@ const (
 	// DisplayModeFullURL displays GIFs as raw URLs using image preview
 	DisplayModeFullURL = "full_url"
 )
+
+const (
+	// AltTextModeKeywords uses the search keywords as the GIF alternative text
+	AltTextModeKeywords = "keywords"
+	// AltTextModeProvider uses the GIF description from the provider as the alternative text
+	AltTextModeProvider = "provider"
+	// AltTextModeProviderCaption also displays the GIF description from the provider under the GIF
+	AltTextModeProviderCaption = "provider_caption"
+)
+
+const (
+	// CaptionPolicyAllow keeps the Markdown and the mentions of the captions
+	CaptionPolicyAllow = "allow"
+	// CaptionPolicyStrip displays the Markdown of the captions as plain text, and neutralizes their mentions
+	CaptionPolicyStrip = "strip"
+	// CaptionPolicyNoMentions keeps the Markdown of the captions, but neutralizes their mentions
+	CaptionPolicyNoMentions = "no_mentions"
+)
@@ -0,0 +1,36 @@
+package configuration
+
+import "strings"
+
+// GetFederatedProviders returns the providers searched along with the main GIF provider for the previews,
+// from the comma-separated list configured in the System Console, ex: "tenor, gfycat".
+// The main provider comes first, and the list is empty if the federated search is not configured.
+func (c *Configuration) GetFederatedProviders() []string {
+	providers := []string{}
+	for _, name := range strings.Split(c.FederatedProviders, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && name != c.Provider && !containsValue(providers, name) {
+			providers = append(providers, name)
+		}
+	}
+	if len(providers) == 0 {
+		return providers
+	}
+	return append([]string{c.Provider}, providers...)
+}
+
+// GetProviderAPIKey returns the API key of a provider: the main API key for the main GIF provider,
+// else the key of the provider used for the federated search
+func (c *Configuration) GetProviderAPIKey(provider string) string {
+	if provider == c.Provider {
+		return c.APIKey
+	}
+	switch provider {
+	case "giphy":
+		return c.GiphyAPIKey
+	case "tenor":
+		return c.TenorAPIKey
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,26 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFederatedProviders(t *testing.T) {
+	config := Configuration{Provider: "giphy"}
+	assert.Empty(t, config.GetFederatedProviders())
+
+	config.FederatedProviders = " Giphy "
+	assert.Empty(t, config.GetFederatedProviders())
+
+	config.FederatedProviders = "tenor, GIPHY,gfycat,, tenor"
+	assert.Equal(t, []string{"giphy", "tenor", "gfycat"}, config.GetFederatedProviders())
+}
+
+func TestGetProviderAPIKey(t *testing.T) {
+	config := Configuration{Provider: "tenor", APIKey: "mainKey", GiphyAPIKey: "giphyKey", TenorAPIKey: "unusedKey"}
+
+	assert.Equal(t, "mainKey", config.GetProviderAPIKey("tenor"))
+	assert.Equal(t, "giphyKey", config.GetProviderAPIKey("giphy"))
+	assert.Equal(t, "", config.GetProviderAPIKey("gfycat"))
+}
@@ -0,0 +1,54 @@
+package configuration
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var triggerRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,127}$`)
+
+// builtInCommandTriggers are the commands of the Mattermost server, that plugins cannot replace
+var builtInCommandTriggers = []string{
+	"away", "code", "collapse", "dnd", "echo", "expand", "groupmsg", "header", "help", "invite", "invite_people",
+	"join", "kick", "leave", "logout", "me", "msg", "mute", "offline", "online", "open", "purpose", "remove",
+	"rename", "search", "settings", "shortcuts", "shrug", "status",
+}
+
+// NormalizeCommandTrigger returns the trigger configured in the System Console in lower case, without the leading slash
+func NormalizeCommandTrigger(trigger string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(trigger), "/"))
+}
+
+// ValidateCommandTrigger checks that a normalized trigger can be used for a command of the plugin
+func ValidateCommandTrigger(trigger string) error {
+	if !triggerRegexp.MatchString(trigger) {
+		return fmt.Errorf("the command '%s' must only contain letters, digits, '-', '_' or '.'", trigger)
+	}
+	if containsValue(builtInCommandTriggers, trigger) {
+		return fmt.Errorf("the command '%s' is a built-in Mattermost command", trigger)
+	}
+	return nil
+}
+
+// ParseCommandTriggerAliases reads the comma-separated list of additional triggers configured in the System Console,
+// ex: "giphy, img". The reserved triggers, already used by the plugin, cannot be aliases.
+func ParseCommandTriggerAliases(aliases string, reserved ...string) ([]string, error) {
+	result := []string{}
+	for _, alias := range strings.Split(aliases, ",") {
+		alias = NormalizeCommandTrigger(alias)
+		if alias == "" {
+			continue
+		}
+		if err := ValidateCommandTrigger(alias); err != nil {
+			return nil, errors.Wrap(err, "invalid command alias")
+		}
+		if containsValue(reserved, alias) || containsValue(result, alias) {
+			return nil, fmt.Errorf("the command alias '%s' is already used", alias)
+		}
+		result = append(result, alias)
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCommandTriggerAliases(t *testing.T) {
+	testCases := []struct {
+		testLabel       string
+		aliases         string
+		expectedError   bool
+		expectedAliases []string
+	}{
+		{testLabel: "No aliases", aliases: " ", expectedAliases: []string{}},
+		{testLabel: "One alias", aliases: "giphy", expectedAliases: []string{"giphy"}},
+		{testLabel: "Several aliases", aliases: "/Giphy, img ,,meme", expectedAliases: []string{"giphy", "img", "meme"}},
+		{testLabel: "Invalid alias", aliases: "giphy, my gif", expectedError: true},
+		{testLabel: "Reserved alias", aliases: "gif", expectedError: true},
+		{testLabel: "Duplicate alias", aliases: "giphy,GIPHY", expectedError: true},
+		{testLabel: "Built-in command", aliases: "giphy,me", expectedError: true},
+	}
+	for _, testCase := range testCases {
+		aliases, err := ParseCommandTriggerAliases(testCase.aliases, "gif", "gifs")
+		if testCase.expectedError {
+			assert.NotNil(t, err, testCase.testLabel)
+		} else {
+			assert.Nil(t, err, testCase.testLabel)
+			assert.Equal(t, testCase.expectedAliases, aliases, testCase.testLabel)
+		}
+	}
+}
+
+func TestValidateCommandTrigger(t *testing.T) {
+	assert.Nil(t, ValidateCommandTrigger("gif"))
+	assert.Nil(t, ValidateCommandTrigger("my-gif_2.0"))
+	assert.NotNil(t, ValidateCommandTrigger(""))
+	assert.NotNil(t, ValidateCommandTrigger("my gif"))
+	assert.NotNil(t, ValidateCommandTrigger("-gif"))
+	assert.NotNil(t, ValidateCommandTrigger("shrug"))
+}
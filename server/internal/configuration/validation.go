@@ -0,0 +1,151 @@
+package configuration
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ValidationIssue is a problem found in the configuration
+type ValidationIssue struct {
+	// Name of the setting, as displayed in the System Console
+	Setting string
+	Message string
+	// Blocking issues prevent the plugin from working, the other issues are only warnings
+	Blocking bool
+}
+
+func (i ValidationIssue) String() string {
+	return i.Setting + ": " + i.Message
+}
+
+// Names of the providers that need an API key, with the page to get one
+var apiKeyPages = map[string]string{
+	"giphy": "https://developers.giphy.com/dashboard/",
+	"tenor": "https://developers.google.com/tenor/guides/quickstart",
+}
+
+// Names of the settings of the API keys used by the federated search, as displayed in the System Console
+var federatedAPIKeySettings = map[string]string{
+	"giphy": "GIPHY API Key for the federated search",
+	"tenor": "Tenor API Key for the federated search",
+}
+
+var (
+	knownProviders = []string{"giphy", "tenor", "gfycat"}
+	knownRatings   = []string{"", "g", "pg", "pg-13", "r"}
+	// API keys only contain letters, digits, '-' and '_', spaces are usually copy-paste mistakes
+	apiKeyRegexp   = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	languageRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z]{2})?$`)
+)
+
+// Validate checks the values of the configuration that don't depend on the GIF provider implementation
+func (c *Configuration) Validate() []ValidationIssue {
+	issues := []ValidationIssue{}
+	blocking := func(setting, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Setting: setting, Message: fmt.Sprintf(format, args...), Blocking: true})
+	}
+	warning := func(setting, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Setting: setting, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch c.DisplayMode {
+	case "":
+		blocking("Display mode", "the Display Mode must be configured")
+	case DisplayModeEmbedded, DisplayModeFullURL:
+	default:
+		blocking("Display mode", "unknown display mode '%s', use '%s' or '%s'", c.DisplayMode, DisplayModeEmbedded, DisplayModeFullURL)
+	}
+
+	if c.Provider == "" {
+		blocking("GIF Provider", "the GIF provider must be configured")
+	} else if !containsValue(knownProviders, c.Provider) {
+		blocking("GIF Provider", "unknown GIF provider '%s', use one of: %s", c.Provider, strings.Join(knownProviders, ", "))
+	}
+	if page, needsKey := apiKeyPages[c.Provider]; needsKey {
+		if strings.TrimSpace(c.APIKey) == "" {
+			blocking("API Key", "an API key is required for %s, get one at %s", c.Provider, page)
+		} else if !apiKeyRegexp.MatchString(c.APIKey) {
+			warning("API Key", "the API key contains unexpected characters (spaces?), check that it was copied correctly")
+		}
+	}
+
+	// The first federated provider is the main one, already checked
+	federated := c.GetFederatedProviders()
+	for i := 1; i < len(federated); i++ {
+		name := federated[i]
+		if !containsValue(knownProviders, name) {
+			blocking("Federated search providers", "unknown GIF provider '%s', use some of: %s", name, strings.Join(knownProviders, ", "))
+		} else if page, needsKey := apiKeyPages[name]; needsKey && strings.TrimSpace(c.GetProviderAPIKey(name)) == "" {
+			blocking(federatedAPIKeySettings[name], "an API key is required to search %s, get one at %s", name, page)
+		}
+	}
+
+	if !containsValue(knownRatings, c.Rating) {
+		blocking("Content rating", "unknown rating '%s', use one of: g, pg, pg-13, r, or nothing", c.Rating)
+	}
+	if c.Language != "" && !languageRegexp.MatchString(c.Language) {
+		warning("Language", "'%s' does not look like a language code, ex: en, pt-BR", c.Language)
+	}
+	switch c.AltTextMode {
+	case "", AltTextModeKeywords, AltTextModeProvider, AltTextModeProviderCaption:
+	default:
+		blocking("GIF alternative text", "unknown mode '%s', use one of: %s, %s, %s", c.AltTextMode, AltTextModeKeywords, AltTextModeProvider, AltTextModeProviderCaption)
+	}
+	switch c.CaptionPolicy {
+	case "", CaptionPolicyAllow, CaptionPolicyStrip, CaptionPolicyNoMentions:
+	default:
+		blocking("Captions", "unknown policy '%s', use one of: %s, %s, %s", c.CaptionPolicy, CaptionPolicyAllow, CaptionPolicyStrip, CaptionPolicyNoMentions)
+	}
+
+	if proxy := strings.TrimSpace(c.HTTPProxyURL); proxy != "" {
+		if proxyURL, err := url.Parse(proxy); err != nil || proxyURL.Host == "" {
+			blocking("HTTP proxy", "'%s' is not a valid URL, ex: http://proxy.example.com:3128", proxy)
+		}
+	}
+	if c.HTTPCACertificates != "" && !strings.Contains(c.HTTPCACertificates, "-----BEGIN CERTIFICATE-----") {
+		blocking("Additional CA certificates", "the certificates must be in PEM format")
+	}
+
+	numbers := []struct {
+		setting string
+		value   int
+	}{
+		{"Maximum GIF markers per message", c.InlineGifsLimit},
+		{"Maximum GIF size (KB)", c.MaxGifSizeKB},
+		{"Maximum GIF width or height (pixels)", c.MaxGifDimension},
+		{"Maximum GIF size on mobile (KB)", c.MobileMaxGifSizeKB},
+		{"Maximum GIF width or height on mobile (pixels)", c.MobileMaxGifDimension},
+		{"Avoid GIFs already posted in the channel for (hours)", c.ChannelDuplicatesHours},
+		{"Connection timeout (seconds)", c.HTTPConnectTimeoutSeconds},
+		{"Request timeout (seconds)", c.HTTPRequestTimeoutSeconds},
+		{"Retries of failed requests", c.HTTPMaxRetries},
+		{"Federated search timeout (seconds)", c.FederatedTimeoutSeconds},
+	}
+	for _, number := range numbers {
+		if number.value < 0 {
+			blocking(number.setting, "the value cannot be negative")
+		}
+	}
+	return issues
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// HasBlockingIssue returns true if one of the issues prevents the plugin from working
+func HasBlockingIssue(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Blocking {
+			return true
+		}
+	}
+	return false
+}
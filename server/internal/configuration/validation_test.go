@@ -0,0 +1,64 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func generateValidConfiguration() Configuration {
+	return Configuration{
+		Provider:    "giphy",
+		DisplayMode: DisplayModeEmbedded,
+		APIKey:      "aBcD1234eFgH5678",
+		Language:    "en",
+		Rating:      "pg",
+		AltTextMode: AltTextModeKeywords,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		testLabel        string
+		update           func(c *Configuration)
+		expectedSetting  string
+		expectedBlocking bool
+	}{
+		{testLabel: "Empty display mode", update: func(c *Configuration) { c.DisplayMode = "" }, expectedSetting: "Display mode", expectedBlocking: true},
+		{testLabel: "Unknown display mode", update: func(c *Configuration) { c.DisplayMode = "inline" }, expectedSetting: "Display mode", expectedBlocking: true},
+		{testLabel: "Empty provider", update: func(c *Configuration) { c.Provider = "" }, expectedSetting: "GIF Provider", expectedBlocking: true},
+		{testLabel: "Unknown provider", update: func(c *Configuration) { c.Provider = "imgur" }, expectedSetting: "GIF Provider", expectedBlocking: true},
+		{testLabel: "Missing API key", update: func(c *Configuration) { c.APIKey = " " }, expectedSetting: "API Key", expectedBlocking: true},
+		{testLabel: "API key with spaces", update: func(c *Configuration) { c.APIKey = "aBcD1234 eFgH5678" }, expectedSetting: "API Key", expectedBlocking: false},
+		{testLabel: "Unknown rating", update: func(c *Configuration) { c.Rating = "nc-17" }, expectedSetting: "Content rating", expectedBlocking: true},
+		{testLabel: "Invalid language", update: func(c *Configuration) { c.Language = "english" }, expectedSetting: "Language", expectedBlocking: false},
+		{testLabel: "Unknown alternative text mode", update: func(c *Configuration) { c.AltTextMode = "title" }, expectedSetting: "GIF alternative text", expectedBlocking: true},
+		{testLabel: "Unknown caption policy", update: func(c *Configuration) { c.CaptionPolicy = "escape" }, expectedSetting: "Captions", expectedBlocking: true},
+		{testLabel: "Negative number", update: func(c *Configuration) { c.MaxGifSizeKB = -1 }, expectedSetting: "Maximum GIF size (KB)", expectedBlocking: true},
+		{testLabel: "Invalid proxy URL", update: func(c *Configuration) { c.HTTPProxyURL = "proxy" }, expectedSetting: "HTTP proxy", expectedBlocking: true},
+		{testLabel: "Invalid CA certificates", update: func(c *Configuration) { c.HTTPCACertificates = "MIIB..." }, expectedSetting: "Additional CA certificates", expectedBlocking: true},
+		{testLabel: "Negative retries", update: func(c *Configuration) { c.HTTPMaxRetries = -1 }, expectedSetting: "Retries of failed requests", expectedBlocking: true},
+		{testLabel: "Unknown federated provider", update: func(c *Configuration) { c.FederatedProviders = "gfycat, imgur" }, expectedSetting: "Federated search providers", expectedBlocking: true},
+		{testLabel: "Missing federated API key", update: func(c *Configuration) { c.FederatedProviders = "tenor" }, expectedSetting: "Tenor API Key for the federated search", expectedBlocking: true},
+		{testLabel: "Negative federated timeout", update: func(c *Configuration) { c.FederatedTimeoutSeconds = -1 }, expectedSetting: "Federated search timeout (seconds)", expectedBlocking: true},
+	}
+	for _, testCase := range testCases {
+		config := generateValidConfiguration()
+		testCase.update(&config)
+		issues := config.Validate()
+		if assert.Len(t, issues, 1, testCase.testLabel) {
+			assert.Equal(t, testCase.expectedSetting, issues[0].Setting, testCase.testLabel)
+			assert.Equal(t, testCase.expectedBlocking, issues[0].Blocking, testCase.testLabel)
+			assert.Equal(t, testCase.expectedBlocking, HasBlockingIssue(issues), testCase.testLabel)
+		}
+	}
+}
+
+func TestValidateShouldAcceptValidConfiguration(t *testing.T) {
+	config := generateValidConfiguration()
+	assert.Empty(t, config.Validate())
+
+	config.Provider = "gfycat"
+	config.APIKey = ""
+	assert.Empty(t, config.Validate())
+}
@@ -0,0 +1,153 @@
+// Package emoji translates the emoji of the search keywords into words, since the GIF providers only search words
+package emoji
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+const (
+	// Variation selector that asks for the emoji presentation of a character, ignored when matching emoji
+	variationSelector = '\uFE0F'
+	// Range of the skin tone modifiers, ignored when matching emoji
+	firstSkinTone = '\U0001F3FB'
+	lastSkinTone  = '\U0001F3FF'
+)
+
+// searchTerms are the words searched for the emoji whose name makes a poor search, by emoji name
+var searchTerms = map[string]string{
+	"+1":             "thumbs up",
+	"thumbsup":       "thumbs up",
+	"-1":             "thumbs down",
+	"thumbsdown":     "thumbs down",
+	"100":            "hundred points",
+	"tada":           "party",
+	"partying_face":  "party",
+	"rofl":           "rolling on the floor laughing",
+	"joy":            "laughing tears",
+	"sob":            "crying",
+	"heart":          "love",
+	"heart_eyes":     "in love",
+	"pray":           "please",
+	"clap":           "applause",
+	"ok_hand":        "ok",
+	"wave":           "hello",
+	"muscle":         "strong",
+	"sweat_smile":    "nervous laugh",
+	"see_no_evil":    "see no evil monkey",
+	"exploding_head": "mind blown",
+}
+
+// emojiNames are the names of the system emoji by sequence of code points (without variation selectors and skin tones),
+// the first name in alphabetical order being used when an emoji has several names, as Mattermost does
+var emojiNames, maxEmojiRunes = makeEmojiNames()
+
+func makeEmojiNames() (map[string]string, int) {
+	names := map[string][]string{}
+	maxRunes := 0
+	for name, codePoints := range model.SystemEmojis {
+		runes, ok := parseCodePoints(codePoints)
+		// The emoji with a skin tone are searched like the emoji without skin tone
+		if !ok || len(runes) == 0 {
+			continue
+		}
+		key := string(runes)
+		names[key] = append(names[key], name)
+		if len(runes) > maxRunes {
+			maxRunes = len(runes)
+		}
+	}
+	result := map[string]string{}
+	for key, keyNames := range names {
+		sort.Strings(keyNames)
+		result[key] = keyNames[0]
+	}
+	return result, maxRunes
+}
+
+// parseCodePoints reads the code points of a system emoji (ex: "2764-fe0f") without the variation selectors,
+// ok is false if they are invalid or have a skin tone
+func parseCodePoints(codePoints string) (runes []rune, ok bool) {
+	for _, hex := range strings.Split(codePoints, "-") {
+		codePoint, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || isSkinTone(rune(codePoint)) {
+			return nil, false
+		}
+		if rune(codePoint) != variationSelector {
+			runes = append(runes, rune(codePoint))
+		}
+	}
+	return runes, true
+}
+
+func isSkinTone(r rune) bool {
+	return r >= firstSkinTone && r <= lastSkinTone
+}
+
+func isModifier(r rune) bool {
+	return r == variationSelector || isSkinTone(r)
+}
+
+// Normalize replaces the Unicode emoji and the emoji shortcodes (ex: ":tada:") of the keywords by search terms.
+// isCustomEmoji tells if a shortcode that is not a system emoji is a custom emoji of the server, whose name is searched.
+// The unknown shortcodes are kept, and the keywords are returned unchanged if they contain no emoji.
+func Normalize(keywords string, isCustomEmoji func(name string) bool) string {
+	changed := false
+	normalized := model.EmojiPattern.ReplaceAllStringFunc(keywords, func(shortcode string) string {
+		name := strings.ToLower(strings.Trim(shortcode, ":"))
+		if _, ok := model.GetSystemEmojiId(name); !ok && (isCustomEmoji == nil || !isCustomEmoji(name)) {
+			return shortcode
+		}
+		changed = true
+		return " " + toSearchTerm(name) + " "
+	})
+
+	runes := []rune(normalized)
+	var builder strings.Builder
+	for i := 0; i < len(runes); {
+		if name, length := matchEmoji(runes[i:]); length > 0 {
+			builder.WriteString(" " + toSearchTerm(name) + " ")
+			i += length
+			changed = true
+			continue
+		}
+		builder.WriteRune(runes[i])
+		i++
+	}
+	if !changed {
+		return keywords
+	}
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+// matchEmoji returns the name of the longest system emoji at the start of the text, and its length in runes
+// including the modifiers, or a length of 0 if the text does not start with an emoji
+func matchEmoji(runes []rune) (string, int) {
+	name, length := "", 0
+	sequence := []rune{}
+	for i := 0; i < len(runes) && len(sequence) < maxEmojiRunes; i++ {
+		if isModifier(runes[i]) {
+			if length == i && length > 0 {
+				// The modifiers that follow the emoji are part of it
+				length = i + 1
+			}
+			continue
+		}
+		sequence = append(sequence, runes[i])
+		if sequenceName, ok := emojiNames[string(sequence)]; ok {
+			name, length = sequenceName, i+1
+		}
+	}
+	return name, length
+}
+
+// toSearchTerm returns the words searched for an emoji name
+func toSearchTerm(name string) string {
+	if term, ok := searchTerms[name]; ok {
+		return term
+	}
+	return strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }), " ")
+}
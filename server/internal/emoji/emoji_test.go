@@ -0,0 +1,37 @@
+package emoji
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	isCustomEmoji := func(name string) bool { return name == "party_parrot" }
+	testCases := []struct {
+		keywords string
+		expected string
+	}{
+		{keywords: "happy  cat", expected: "happy  cat"},
+		{keywords: "🎉", expected: "party"},
+		{keywords: ":tada:", expected: "party"},
+		{keywords: ":TADA:cake", expected: "party cake"},
+		{keywords: "🎉🎂", expected: "party birthday"},
+		{keywords: "cat 😆", expected: "cat laughing"},
+		{keywords: "👍🏽 great", expected: "thumbs up great"},
+		{keywords: "❤", expected: "love"},
+		{keywords: "❤️", expected: "love"},
+		{keywords: "🏳️‍🌈 pride", expected: "rainbow flag pride"},
+		{keywords: "🇫🇷", expected: "flag fr"},
+		{keywords: ":party_parrot:", expected: "party parrot"},
+		{keywords: ":unknown_emoji: dance", expected: ":unknown_emoji: dance"},
+		{keywords: "12:30: lunch", expected: "12:30: lunch"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, Normalize(testCase.keywords, isCustomEmoji), testCase.keywords)
+	}
+}
+
+func TestNormalizeWithoutCustomEmoji(t *testing.T) {
+	assert.Equal(t, ":party_parrot:", Normalize(":party_parrot:", nil))
+}
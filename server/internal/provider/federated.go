@@ -0,0 +1,239 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	pluginError "github.com/moussetc/mattermost-plugin-giphy/server/internal/error"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Maximum duration of the search of one provider when the federated search timeout is not configured
+const defaultFederatedTimeout = 5 * time.Second
+
+// federated searches several providers concurrently and interleaves their results:
+// the first GIF of each provider, then the second GIF of each provider, etc.
+type federated struct {
+	providers      []GifProvider
+	errorGenerator pluginError.PluginError
+	// Maximum duration of the search of each provider, so that a slow provider does not delay the others
+	timeout time.Duration
+}
+
+// federatedCursor is the position of a federated search in the results of each provider
+type federatedCursor struct {
+	// Cursors of the providers, in the order of the configuration
+	Cursors []string `json:"cursors"`
+	// Providers that have no more results
+	Done []bool `json:"done"`
+	// Results of the last search of the providers that were not returned yet
+	Pending []*GifResult `json:"pending,omitempty"`
+}
+
+// FederatedGifProviderGenerator returns the provider used for the previews: the main provider if the federated search
+// is not configured, else a provider that searches the main provider and the federated providers concurrently.
+var FederatedGifProviderGenerator = defaultFederatedGifProviderGenerator
+
+func defaultFederatedGifProviderGenerator(configuration pluginConf.Configuration, mainProvider GifProvider, errorGenerator pluginError.PluginError, rootURL string) (GifProvider, *model.AppError) {
+	names := configuration.GetFederatedProviders()
+	if len(names) == 0 {
+		return mainProvider, nil
+	}
+	httpClient, httpErr := NewHTTPClient(HTTPClientOptionsFromConfiguration(configuration))
+	if httpErr != nil {
+		return nil, errorGenerator.FromError("Invalid HTTP client settings", httpErr)
+	}
+	providers := []GifProvider{mainProvider}
+	for _, name := range names[1:] {
+		gifProvider, appErr := newGifProvider(name, configuration, httpClient, errorGenerator, rootURL)
+		if appErr != nil {
+			return nil, appErr
+		}
+		providers = append(providers, gifProvider)
+	}
+	timeout := time.Duration(configuration.FederatedTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultFederatedTimeout
+	}
+	return NewFederatedProvider(providers, errorGenerator, timeout), nil
+}
+
+// NewFederatedProvider creates a provider that searches the providers concurrently
+func NewFederatedProvider(providers []GifProvider, errorGenerator pluginError.PluginError, timeout time.Duration) GifProvider {
+	return &federated{providers: providers, errorGenerator: errorGenerator, timeout: timeout}
+}
+
+// GetAttributionMessage returns the attribution of the main provider.
+// Each GIF found by the federated search has the attribution of its own provider.
+func (p *federated) GetAttributionMessage() string {
+	return p.providers[0].GetAttributionMessage()
+}
+
+// GetGif returns the next GIF of the interleaved results. When the results of the last search are all returned,
+// the providers that have more results are searched again. The providers that fail or time out are skipped,
+// unless no provider found a GIF.
+func (p *federated) GetGif(ctx context.Context, query Query, cursor *string) (*GifResult, *model.AppError) {
+	state := federatedCursor{Cursors: make([]string, len(p.providers)), Done: make([]bool, len(p.providers))}
+	if *cursor != "" {
+		if err := json.Unmarshal([]byte(*cursor), &state); err != nil || len(state.Cursors) != len(p.providers) || len(state.Done) != len(p.providers) {
+			return nil, p.errorGenerator.FromError("Could not read the cursor of the federated search", err)
+		}
+	}
+	if len(state.Pending) == 0 {
+		results, appErr := p.searchAll(ctx, query, &state)
+		if appErr != nil {
+			return nil, appErr
+		}
+		state.Pending = results
+	}
+	if len(state.Pending) == 0 {
+		*cursor = ""
+		return nil, nil
+	}
+	gif := state.Pending[0]
+	state.Pending = state.Pending[1:]
+	*cursor = encodeFederatedCursor(state)
+	return gif, nil
+}
+
+// searchAll searches the next GIF of each provider that has more results, and updates their cursors
+func (p *federated) searchAll(ctx context.Context, query Query, state *federatedCursor) ([]*GifResult, *model.AppError) {
+	gifs := make([]*GifResult, len(p.providers))
+	errs := make([]*model.AppError, len(p.providers))
+	cursors := make([]string, len(p.providers))
+	copy(cursors, state.Cursors)
+
+	var wg sync.WaitGroup
+	for i := range p.providers {
+		if state.Done[i] {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			providerCtx, cancel := context.WithTimeout(ctx, p.timeout)
+			defer cancel()
+			gifs[i], errs[i] = p.providers[i].GetGif(providerCtx, query, &cursors[i])
+		}(i)
+	}
+	wg.Wait()
+
+	results := []*GifResult{}
+	var firstErr *model.AppError
+	for i, gif := range gifs {
+		if state.Done[i] {
+			continue
+		}
+		if errs[i] != nil {
+			// The provider will be searched again from the same position
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		state.Cursors[i] = cursors[i]
+		state.Done[i] = gif == nil || cursors[i] == ""
+		if gif != nil {
+			if gif.Attribution == "" {
+				gif.Attribution = p.providers[i].GetAttributionMessage()
+			}
+			results = append(results, gif)
+		}
+	}
+	if len(results) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return results, nil
+}
+
+// GetSuggestions returns the suggestions of the providers that can suggest search terms, in the order of the configuration.
+// The providers that fail or time out are skipped.
+func (p *federated) GetSuggestions(ctx context.Context, query Query, limit int) ([]string, *model.AppError) {
+	suggestions := []string{}
+	var firstErr *model.AppError
+	for _, gifProvider := range p.providers {
+		suggestionProvider, ok := gifProvider.(SuggestionProvider)
+		if !ok || len(suggestions) >= limit {
+			continue
+		}
+		providerCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		terms, appErr := suggestionProvider.GetSuggestions(providerCtx, query, limit)
+		cancel()
+		if appErr != nil {
+			if firstErr == nil {
+				firstErr = appErr
+			}
+			continue
+		}
+		suggestions = appendSuggestions(suggestions, query.Keywords, terms, limit)
+	}
+	if len(suggestions) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return suggestions, nil
+}
+
+// GetGifByPage asks the providers that resolve pages for the GIF of the page, only the provider of the page finds it
+func (p *federated) GetGifByPage(ctx context.Context, page GifPage, query Query) (*GifResult, *model.AppError) {
+	var firstErr *model.AppError
+	for _, gifProvider := range p.providers {
+		resolver, ok := gifProvider.(PageResolver)
+		if !ok {
+			continue
+		}
+		gif, appErr := resolver.GetGifByPage(ctx, page, query)
+		if appErr != nil {
+			if firstErr == nil {
+				firstErr = appErr
+			}
+			continue
+		}
+		if gif != nil {
+			if gif.Attribution == "" {
+				gif.Attribution = gifProvider.GetAttributionMessage()
+			}
+			return gif, nil
+		}
+	}
+	return nil, firstErr
+}
+
+// ReportShare forwards the share to the providers that report shares, only the provider that found the GIF reports it
+func (p *federated) ReportShare(ctx context.Context, share Share) *model.AppError {
+	var firstErr *model.AppError
+	for _, gifProvider := range p.providers {
+		if reporter, ok := gifProvider.(ShareReporter); ok {
+			if appErr := reporter.ReportShare(ctx, share); appErr != nil && firstErr == nil {
+				firstErr = appErr
+			}
+		}
+	}
+	return firstErr
+}
+
+// encodeFederatedCursor returns the cursor of the search, or an empty cursor if there are no more results
+func encodeFederatedCursor(state federatedCursor) string {
+	if len(state.Pending) == 0 {
+		done := true
+		for _, providerDone := range state.Done {
+			done = done && providerDone
+		}
+		if done {
+			return ""
+		}
+	}
+	// The renditions are not needed once the GIF URL is chosen, and would make the cursor too big
+	pending := make([]*GifResult, len(state.Pending))
+	for i, gif := range state.Pending {
+		light := *gif
+		light.Renditions = nil
+		pending[i] = &light
+	}
+	state.Pending = pending
+	data, _ := json.Marshal(state)
+	return string(data)
+}
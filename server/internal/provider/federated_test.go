@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/test"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubProvider returns count GIFs named after the provider, the cursor being the position of the next GIF
+type stubProvider struct {
+	name  string
+	count int
+	// Duration of each search
+	delay time.Duration
+	err   *model.AppError
+}
+
+func (p *stubProvider) GetGif(ctx context.Context, query Query, cursor *string) (*GifResult, *model.AppError) {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, test.MockErrorGenerator().FromError("Search canceled", ctx.Err())
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	position, _ := strconv.Atoi(*cursor)
+	if position >= p.count {
+		*cursor = ""
+		return nil, nil
+	}
+	*cursor = strconv.Itoa(position + 1)
+	if position+1 == p.count {
+		*cursor = ""
+	}
+	id := p.name + strconv.Itoa(position+1)
+	return &GifResult{ID: id, Provider: p.name, URL: "https://gif.fr/" + id, Renditions: map[string]Rendition{"gif": {URL: "https://gif.fr/" + id}}}, nil
+}
+
+func (p *stubProvider) GetAttributionMessage() string {
+	return "Powered by " + p.name
+}
+
+// searchAllFederated returns the IDs of all the GIFs found by the provider
+func searchAllFederated(t *testing.T, gifProvider GifProvider) []string {
+	ids := []string{}
+	cursor := ""
+	for i := 0; i < 10; i++ {
+		gif, err := gifProvider.GetGif(context.Background(), Query{Keywords: "kitty"}, &cursor)
+		assert.Nil(t, err)
+		if gif == nil {
+			break
+		}
+		ids = append(ids, gif.ID)
+		assert.Equal(t, "Powered by "+gif.Provider, gif.Attribution)
+		if cursor == "" {
+			break
+		}
+	}
+	return ids
+}
+
+func TestFederatedProviderShouldInterleaveTheResults(t *testing.T) {
+	gifProvider := NewFederatedProvider([]GifProvider{
+		&stubProvider{name: "giphy", count: 3},
+		&stubProvider{name: "tenor", count: 1},
+		&stubProvider{name: "gfycat", count: 2},
+	}, test.MockErrorGenerator(), time.Second)
+
+	ids := searchAllFederated(t, gifProvider)
+
+	assert.Equal(t, []string{"giphy1", "tenor1", "gfycat1", "giphy2", "gfycat2", "giphy3"}, ids)
+}
+
+func TestFederatedProviderShouldSkipSlowAndFailingProviders(t *testing.T) {
+	gifProvider := NewFederatedProvider([]GifProvider{
+		&stubProvider{name: "giphy", count: 2, delay: time.Minute},
+		&stubProvider{name: "tenor", count: 2},
+		&stubProvider{name: "gfycat", count: 2, err: test.MockErrorGenerator().FromMessage("Gfycat is down")},
+	}, test.MockErrorGenerator(), 20*time.Millisecond)
+
+	start := time.Now()
+	cursor := ""
+	for _, expectedID := range []string{"tenor1", "tenor2"} {
+		gif, err := gifProvider.GetGif(context.Background(), Query{Keywords: "kitty"}, &cursor)
+		assert.Nil(t, err)
+		if assert.NotNil(t, gif) {
+			assert.Equal(t, expectedID, gif.ID)
+		}
+	}
+	assert.NotEmpty(t, cursor)
+
+	// Only the failing providers are left
+	gif, err := gifProvider.GetGif(context.Background(), Query{Keywords: "kitty"}, &cursor)
+	assert.Nil(t, gif)
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestFederatedProviderShouldFailWhenAllProvidersFail(t *testing.T) {
+	gifProvider := NewFederatedProvider([]GifProvider{
+		&stubProvider{name: "giphy", err: test.MockErrorGenerator().FromMessage("Invalid API key")},
+		&stubProvider{name: "tenor", count: 1, delay: time.Minute},
+	}, test.MockErrorGenerator(), 20*time.Millisecond)
+	cursor := ""
+
+	gif, err := gifProvider.GetGif(context.Background(), Query{Keywords: "kitty"}, &cursor)
+
+	assert.Nil(t, gif)
+	assert.NotNil(t, err)
+}
+
+func TestFederatedProviderShouldKeepTheCursorSmall(t *testing.T) {
+	gifProvider := NewFederatedProvider([]GifProvider{
+		&stubProvider{name: "giphy", count: 2},
+		&stubProvider{name: "tenor", count: 2},
+	}, test.MockErrorGenerator(), time.Second)
+	cursor := ""
+
+	gif, err := gifProvider.GetGif(context.Background(), Query{Keywords: "kitty"}, &cursor)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, gif.Renditions)
+	var state federatedCursor
+	assert.Nil(t, json.Unmarshal([]byte(cursor), &state))
+	if assert.Len(t, state.Pending, 1) {
+		assert.Equal(t, "tenor1", state.Pending[0].ID)
+		assert.Equal(t, "https://gif.fr/tenor1", state.Pending[0].URL)
+		assert.Nil(t, state.Pending[0].Renditions)
+	}
+}
+
+func TestFederatedProviderShouldRejectInvalidCursor(t *testing.T) {
+	gifProvider := NewFederatedProvider([]GifProvider{&stubProvider{name: "giphy", count: 1}}, test.MockErrorGenerator(), time.Second)
+	for _, cursor := range []string{"42", `{"cursors":["", ""],"done":[false, false]}`} {
+		gif, err := gifProvider.GetGif(context.Background(), Query{Keywords: "kitty"}, &cursor)
+		assert.Nil(t, gif)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestFederatedGifProviderGenerator(t *testing.T) {
+	mainProvider := &stubProvider{name: "giphy"}
+	config := pluginConf.Configuration{Provider: ProviderGiphy, APIKey: testGiphyAPIKey, RenditionGfycat: testGfycatRendition}
+
+	gifProvider, err := defaultFederatedGifProviderGenerator(config, mainProvider, test.MockErrorGenerator(), "/test")
+	assert.Nil(t, err)
+	assert.Same(t, mainProvider, gifProvider)
+
+	config.FederatedProviders = "gfycat"
+	gifProvider, err = defaultFederatedGifProviderGenerator(config, mainProvider, test.MockErrorGenerator(), "/test")
+	assert.Nil(t, err)
+	if assert.IsType(t, &federated{}, gifProvider) {
+		federatedProvider := gifProvider.(*federated)
+		assert.Len(t, federatedProvider.providers, 2)
+		assert.Same(t, mainProvider, federatedProvider.providers[0])
+		assert.Equal(t, defaultFederatedTimeout, federatedProvider.timeout)
+		assert.Equal(t, mainProvider.GetAttributionMessage(), gifProvider.GetAttributionMessage())
+	}
+
+	config.FederatedProviders = "tenor"
+	_, err = defaultFederatedGifProviderGenerator(config, mainProvider, test.MockErrorGenerator(), "/test")
+	assert.NotNil(t, err)
+}
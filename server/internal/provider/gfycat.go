@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -54,8 +55,9 @@ func (p *gfycat) GetAttributionMessage() string {
 	return "Powered by Gfycat"
 }
 
-// Return the URL of a GIF that matches the query, or an empty string if no GIF matches the query, or an error if the search failed
-func (p *gfycat) GetGifURL(request string, cursor *string) (string, *model.AppError) {
+// Return the GIF that matches the query, or nil if no GIF matches the query, or an error if the search failed.
+// The Gfycat API does not support languages so the locale is ignored.
+func (p *gfycat) GetGif(ctx context.Context, query Query, cursor *string) (*GifResult, *model.AppError) {
 	/**
 	 * Known quirks of the Gfycat API
 	 * - "count" parameter is applied _before_ any filtering (private GIF, etc.) so if you ask
@@ -71,16 +73,16 @@ func (p *gfycat) GetGifURL(request string, cursor *string) (string, *model.AppEr
 	var pageCursor = gfyPageCursor{CursorForPage: "", PositionInPage: 0}
 	if cursor != nil && *cursor != "" {
 		if err := json.Unmarshal([]byte(*cursor), &pageCursor); err != nil {
-			return "", p.errorGenerator.FromError("Could not unserialize Gfycat cursor", err)
+			return nil, p.errorGenerator.FromError("Could not unserialize Gfycat cursor", err)
 		}
 	}
 
-	req, err := http.NewRequest("GET", baseURLGfycat+"/gfycats/search", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURLGfycat+"/gfycats/search", nil)
 	if err != nil {
-		return "", p.errorGenerator.FromError("Could not generate GfyCat search URL", err)
+		return nil, p.errorGenerator.FromError("Could not generate GfyCat search URL", err)
 	}
 	q := req.URL.Query()
-	q.Add("search_text", request)
+	q.Add("search_text", query.Keywords)
 	if pageCursor.CursorForPage != "" {
 		q.Add("cursor", pageCursor.CursorForPage)
 	}
@@ -90,49 +92,35 @@ func (p *gfycat) GetGifURL(request string, cursor *string) (string, *model.AppEr
 
 	r, err := p.httpClient.Do(req)
 	if err != nil {
-		return "", p.errorGenerator.FromError("Error calling the GfyCat search API", err)
+		return nil, p.errorGenerator.FromError("Error calling the GfyCat search API", err)
 	}
 	if r != nil && r.Body != nil {
 		defer r.Body.Close()
 	}
 
 	if r.StatusCode != http.StatusOK {
-		return "", p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the GfyCat search API (HTTP Status: %v)", r.Status))
+		return nil, p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the GfyCat search API (HTTP Status: %v)", r.Status))
 	}
 	var response gfySearchResult
-	decoder := json.NewDecoder(r.Body)
 	if r.Body == nil {
-		return "", p.errorGenerator.FromMessage("GfyCat search response body is empty")
+		return nil, p.errorGenerator.FromMessage("GfyCat search response body is empty")
 	}
+	decoder := json.NewDecoder(r.Body)
 	if err = decoder.Decode(&response); err != nil {
-		return "", p.errorGenerator.FromError("Could not parse Gfycat search response body", err)
+		return nil, p.errorGenerator.FromError("Could not parse Gfycat search response body", err)
 	}
 	if len(response.Gfycats) < 1 {
-		return "", nil
-	}
-	gif := response.Gfycats[pageCursor.PositionInPage]
-	urlNode, ok := gif[p.rendition]
-	if !ok {
-		return "", p.errorGenerator.FromMessage("No URL found for display style \"" + p.rendition + "\" in the response")
-	}
-	var url string
-	if urlNode != nil {
-		if err = json.Unmarshal(*urlNode, &url); err != nil {
-			return "", p.errorGenerator.FromError("Could not read "+p.rendition+"node", err)
-		}
+		return nil, nil
 	}
+	preferences := p.renditionPreferences()
+	result := gfycatToGifResult(response.Gfycats[pageCursor.PositionInPage], preferences)
+	url := selectRendition(result.Renditions, preferences, query.Budget)
 	// Ignore suffix without a Mattermost preview
 	if url == "" || strings.HasSuffix(url, ".webm") || strings.HasSuffix(url, ".mp4") {
-		urlNode, ok = gif["gifUrl"]
-		if !ok {
-			return "", p.errorGenerator.FromMessage("No URL found for the \"gifUrl\" in the response")
-		}
-		if err = json.Unmarshal(*urlNode, &url); err != nil {
-			return "", p.errorGenerator.FromError("Could not read gifUrl node", err)
-		}
+		url = result.Renditions["gifUrl"].URL
 	}
 	if url == "" {
-		return "", p.errorGenerator.FromMessage("An empty URL was returned for display style \"" + p.rendition + "\"")
+		return nil, p.errorGenerator.FromMessage("No URL found for display style \"" + p.rendition + "\" in the response")
 	}
 
 	noMoreResults := false
@@ -153,10 +141,49 @@ func (p *gfycat) GetGifURL(request string, cursor *string) (string, *model.AppEr
 	} else {
 		nextCursor, err := json.Marshal(pageCursor)
 		if err != nil {
-			return "", p.errorGenerator.FromError("Could not serialize Gfycat cursor", err)
+			return nil, p.errorGenerator.FromError("Could not serialize Gfycat cursor", err)
 		}
 
 		*cursor = string(nextCursor)
 	}
-	return url, nil
+	result.URL = url
+	return result, nil
+}
+
+// gfycatToGifResult reads the metadata of a Gfycat GIF, the renditions being the preferred ones and all the fields containing URLs
+func gfycatToGifResult(gif map[string]*json.RawMessage, preferences []string) *GifResult {
+	result := &GifResult{
+		Provider:   ProviderGfycat,
+		Renditions: map[string]Rendition{},
+	}
+	for key, node := range gif {
+		var value string
+		if node == nil || json.Unmarshal(*node, &value) != nil || value == "" {
+			continue
+		}
+		switch key {
+		case "gfyId":
+			result.ID = value
+		case "gfyName":
+			result.PageURL = "https://gfycat.com/" + value
+		case "title":
+			result.Title = value
+		case "description":
+			result.AltText = value
+		default:
+			if strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://") || isPreferredRendition(key, preferences) {
+				result.Renditions[key] = Rendition{URL: value}
+			}
+		}
+	}
+	return result
+}
+
+func isPreferredRendition(name string, preferences []string) bool {
+	for _, preference := range preferences {
+		if name == preference {
+			return true
+		}
+	}
+	return false
 }
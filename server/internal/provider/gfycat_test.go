@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -46,60 +47,82 @@ func TestNewGfycatProvider(t *testing.T) {
 	}
 }
 
-func TestGfycatProviderGetGifURLShouldReturnUrlWhenSearchSucceeds(t *testing.T) {
+func TestGfycatProviderGetGifShouldReturnUrlWhenSearchSucceeds(t *testing.T) {
 	p, _ := NewGfycatProvider(NewMockHTTPClient(newServerResponseOK(defaultGfycatResponseBody)), test.MockErrorGenerator(), testGfycatRendition)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
-	assert.NotEmpty(t, url)
-	assert.Equal(t, "url0", url)
+	assert.NotNil(t, gif)
+	assert.Equal(t, "url0", gif.URL)
 }
 
-func TestGfycatProviderGetGifURLShouldFailIfSearchBodyIsEmpty(t *testing.T) {
+func TestGfycatProviderGetGifShouldReturnMetadataAndRenditions(t *testing.T) {
+	p, _ := NewGfycatProvider(NewMockHTTPClient(newServerResponseOK(`{"gfycats": [{
+		"gfyId": "happycat", "gfyName": "HappyCat", "title": "Happy cat", "description": "A cat jumps",
+		"gif100px": "https://thumbs.gfycat.com/HappyCat-max-1mb.gif", "mp4Url": "https://giant.gfycat.com/HappyCat.mp4", "width": 480
+	}]}`)), test.MockErrorGenerator(), testGfycatRendition)
+	cursor := ""
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, &GifResult{
+		ID:       "happycat",
+		Provider: ProviderGfycat,
+		Title:    "Happy cat",
+		AltText:  "A cat jumps",
+		PageURL:  "https://gfycat.com/HappyCat",
+		URL:      "https://thumbs.gfycat.com/HappyCat-max-1mb.gif",
+		Renditions: map[string]Rendition{
+			"gif100px": {URL: "https://thumbs.gfycat.com/HappyCat-max-1mb.gif"},
+			"mp4Url":   {URL: "https://giant.gfycat.com/HappyCat.mp4"},
+		},
+	}, gif)
+}
+
+func TestGfycatProviderGetGifShouldFailIfSearchBodyIsEmpty(t *testing.T) {
 	p, _ := NewGfycatProvider(NewMockHTTPClient(newServerResponseOK("")), test.MockErrorGenerator(), testGfycatRendition)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "empty")
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGfycatProviderGetGifURLShouldFailWhenParseError(t *testing.T) {
+func TestGfycatProviderGetGifShouldFailWhenParseError(t *testing.T) {
 	p, _ := NewGfycatProvider(NewMockHTTPClient(newServerResponseOK("Hello world")), test.MockErrorGenerator(), testGfycatRendition)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGfycatProviderGetGifURLShouldReturnEmptyUrlWhenSearchReturnNoResult(t *testing.T) {
+func TestGfycatProviderGetGifShouldReturnEmptyUrlWhenSearchReturnNoResult(t *testing.T) {
 	p, _ := NewGfycatProvider(NewMockHTTPClient(newServerResponseOK("{\"data\": [] }")), test.MockErrorGenerator(), testGfycatRendition)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGfycatProviderGetGifURLShouldFailWhenNoURLForRendition(t *testing.T) {
+func TestGfycatProviderGetGifShouldFailWhenNoURLForRendition(t *testing.T) {
 	badRendition := "NotExistingDisplayStyle"
 	p, _ := NewGfycatProvider(NewMockHTTPClient(newServerResponseOK(defaultGfycatResponseBody)), test.MockErrorGenerator(), badRendition)
 
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "No URL found")
 	assert.Contains(t, err.Error(), badRendition)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGfycatProviderGetGifURLShouldFailWhenSearchBadStatus(t *testing.T) {
+func TestGfycatProviderGetGifShouldFailWhenSearchBadStatus(t *testing.T) {
 	serverResponse := newServerResponseKO(400)
 	p, _ := NewGfycatProvider(NewMockHTTPClient(serverResponse), test.MockErrorGenerator(), testGfycatRendition)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), serverResponse.Status)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
 func generateGfycatProviderForURLBuildingTests(respondeBody string) (p GifProvider, client *MockHTTPClient, cursor string) {
@@ -110,7 +133,7 @@ func generateGfycatProviderForURLBuildingTests(respondeBody string) (p GifProvid
 	return p, client, cursor
 }
 
-func TestGfycatProviderGetGifURLWhenStartingSearch(t *testing.T) {
+func TestGfycatProviderGetGifWhenStartingSearch(t *testing.T) {
 	p, client, cursor := generateGfycatProviderForURLBuildingTests(defaultGfycatResponseBody)
 
 	// Check: API cursor should not be passed as initial cursor is unset
@@ -118,14 +141,14 @@ func TestGfycatProviderGetGifURLWhenStartingSearch(t *testing.T) {
 		assert.NotContains(t, req.URL.RawQuery, "cursor")
 		return true
 	}
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
-	assert.Equal(t, "url0", url)
+	assert.Equal(t, "url0", gif.URL)
 	assert.Equal(t, "{\"cursorForPage\":\"\",\"positionInPage\":1}", cursor)
 }
 
-func TestGfycatProviderGetGifURLWhenCursorIsInCurrentPage(t *testing.T) {
+func TestGfycatProviderGetGifWhenCursorIsInCurrentPage(t *testing.T) {
 	p, client, _ := generateGfycatProviderForURLBuildingTests(defaultGfycatResponseBody)
 	cursor := "{\"cursorForPage\":\"currentCursor\",\"positionInPage\":1}"
 
@@ -135,28 +158,28 @@ func TestGfycatProviderGetGifURLWhenCursorIsInCurrentPage(t *testing.T) {
 		return true
 	}
 
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
-	assert.Equal(t, "url1", url)
+	assert.Equal(t, "url1", gif.URL)
 	assert.Equal(t, "{\"cursorForPage\":\"currentCursor\",\"positionInPage\":2}", cursor)
 }
 
-func TestGfycatProviderGetGifURLWhenNextCursorIsDifferentPage(t *testing.T) {
+func TestGfycatProviderGetGifWhenNextCursorIsDifferentPage(t *testing.T) {
 	p, _, _ := generateGfycatProviderForURLBuildingTests(defaultGfycatResponseBody)
 	cursor := "{\"cursorForPage\":\"currentCursor\",\"positionInPage\":2}"
 
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
-	assert.Equal(t, "url2", url)
+	assert.Equal(t, "url2", gif.URL)
 	assert.Equal(t, "{\"cursorForPage\":\"nextCursor\",\"positionInPage\":0}", cursor)
 }
 
-func TestGfycatProviderGetGifURLWhenThisIsTheLastGifResult(t *testing.T) {
+func TestGfycatProviderGetGifWhenThisIsTheLastGifResult(t *testing.T) {
 	p, _, cursor := generateGfycatProviderForURLBuildingTests("{ \"cursor\": \"\", \"gfycats\" : [ { \"gifUrl\": \"\", \"gif100px\": \"url0\"}] }")
 
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
-	assert.Equal(t, "url0", url)
+	assert.Equal(t, "url0", gif.URL)
 	assert.Equal(t, "", cursor)
 }
@@ -1,7 +1,9 @@
 package provider
 
 import (
+	"context"
 	"net/http"
+	"strings"
 
 	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
 	pluginError "github.com/moussetc/mattermost-plugin-giphy/server/internal/error"
@@ -9,10 +11,19 @@ import (
 	"github.com/mattermost/mattermost-server/v6/model"
 )
 
+// Names of the providers, as set in the configuration
+const (
+	ProviderGiphy  = "giphy"
+	ProviderTenor  = "tenor"
+	ProviderGfycat = "gfycat"
+)
+
 // GifProvider exposes methods to get GIF from an API
 type GifProvider interface {
-	// GetGifURL return the URL of a GIF that matches the requested keywords if one is found or else
-	GetGifURL(request string, cursor *string) (string, *model.AppError)
+	// GetGif returns the GIF that matches the query at the position of the cursor if one is found or else nil,
+	// and moves the cursor to the next GIF (an empty cursor means there are no more GIFs).
+	// The search is aborted when the context is canceled or its deadline is exceeded.
+	GetGif(ctx context.Context, query Query, cursor *string) (*GifResult, *model.AppError)
 
 	// GetAttributionMessage returns the text that should be displayed near the GIF, as defined by the providers' Terms of Service
 	GetAttributionMessage() string
@@ -24,9 +35,14 @@ type HTTPClient interface {
 	Get(s string) (*http.Response, error)
 }
 
+// Query describes the GIFs requested to a provider
 type Query struct {
 	Keywords string
-	Cursor   string
+	// Mattermost locale of the user (ex: "pt-BR"): if it is empty or not supported by the provider,
+	// the configured language is used instead.
+	Locale string
+	// Limits of the chosen rendition, that can depend on the user's device
+	Budget RenditionBudget
 }
 
 // nolint: structcheck //linter mistakenly thinks all fields are unused but they are used by composition
@@ -35,22 +51,44 @@ type abstractGifProvider struct {
 	errorGenerator pluginError.PluginError
 	language       string
 	rating         string
-	rendition      string
+	// Comma-separated list of the preferred renditions, in order
+	rendition string
 }
 
-func defaultGifProviderGenerator(configuration pluginConf.Configuration, errorGenerator pluginError.PluginError, rootURL string) (gifProvider GifProvider, err *model.AppError) {
+func defaultGifProviderGenerator(configuration pluginConf.Configuration, errorGenerator pluginError.PluginError, rootURL string) (GifProvider, *model.AppError) {
 	if configuration.Provider == "" {
 		return nil, errorGenerator.FromMessage("The GIF provider must be configured")
 	}
-	switch configuration.Provider {
-	case "giphy":
-		gifProvider, err = NewGiphyProvider(http.DefaultClient, errorGenerator, configuration.APIKey, configuration.Language, configuration.Rating, configuration.Rendition, rootURL)
-	case "tenor":
-		gifProvider, err = NewTenorProvider(http.DefaultClient, errorGenerator, configuration.APIKey, configuration.Language, configuration.Rating, configuration.RenditionTenor)
-	default:
-		gifProvider, err = NewGfycatProvider(http.DefaultClient, errorGenerator, configuration.RenditionGfycat)
+	httpClient, httpErr := NewHTTPClient(HTTPClientOptionsFromConfiguration(configuration))
+	if httpErr != nil {
+		return nil, errorGenerator.FromError("Invalid HTTP client settings", httpErr)
 	}
-	return gifProvider, err
+	return newGifProvider(configuration.Provider, configuration, httpClient, errorGenerator, rootURL)
 }
 
 var GifProviderGenerator = defaultGifProviderGenerator
+
+// newGifProvider creates a provider by name, with its API key and display style from the configuration
+func newGifProvider(name string, configuration pluginConf.Configuration, httpClient HTTPClient, errorGenerator pluginError.PluginError, rootURL string) (GifProvider, *model.AppError) {
+	apiKey := configuration.GetProviderAPIKey(name)
+	switch name {
+	case ProviderGiphy:
+		return NewGiphyProvider(httpClient, errorGenerator, apiKey, configuration.Language, configuration.Rating, withFallbacks(configuration.Rendition, configuration.RenditionFallbacks), rootURL)
+	case ProviderTenor:
+		return NewTenorProvider(httpClient, errorGenerator, apiKey, configuration.Language, configuration.Rating, withFallbacks(configuration.RenditionTenor, configuration.RenditionFallbacks))
+	default:
+		return NewGfycatProvider(httpClient, errorGenerator, withFallbacks(configuration.RenditionGfycat, configuration.RenditionFallbacks))
+	}
+}
+
+// withFallbacks returns the list of the preferred renditions: the configured rendition, followed by the fallback ones
+func withFallbacks(rendition, fallbacks string) string {
+	if strings.TrimSpace(fallbacks) == "" {
+		return rendition
+	}
+	return rendition + "," + fallbacks
+}
+
+func (p *abstractGifProvider) renditionPreferences() []string {
+	return parseRenditionPreferences(p.rendition)
+}
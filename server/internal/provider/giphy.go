@@ -1,10 +1,13 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	pluginError "github.com/moussetc/mattermost-plugin-giphy/server/internal/error"
 
@@ -19,20 +22,51 @@ type giphy struct {
 }
 
 const (
-	baseURLGiphy = "https://api.Giphy.com/v1/gifs"
+	baseURLGiphy     = "https://api.Giphy.com/v1/gifs"
+	baseURLGiphyTags = "https://api.Giphy.com/v1/tags"
 )
 
+// giphyGifResult is the response of the get GIF by ID endpoint
+type giphyGifResult struct {
+	Data giphyGif `json:"data"`
+}
+
 type GiphySearchResult struct {
-	Data []struct {
-		Images map[string]struct {
-			URL string `json:"url"`
-		} `json:"images"`
-	} `json:"data"`
+	Data       []giphyGif `json:"data"`
 	Pagination struct {
 		Offset int `json:"offset"`
 	} `json:"pagination"`
 }
 
+type giphyGif struct {
+	ID      string                `json:"id"`
+	URL     string                `json:"url"`
+	Title   string                `json:"title"`
+	AltText string                `json:"alt_text"`
+	Images  map[string]giphyImage `json:"images"`
+	// Pingback URLs of the GIF, called when a user does something with it
+	Analytics struct {
+		OnSent struct {
+			URL string `json:"url"`
+		} `json:"onsent"`
+	} `json:"analytics"`
+}
+
+// giphyTagsResult is the response of the autocomplete and related terms endpoints
+type giphyTagsResult struct {
+	Data []struct {
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
+// giphyImage is a rendition of a Giphy GIF, the API returns numbers as strings
+type giphyImage struct {
+	URL    string `json:"url"`
+	Width  string `json:"width"`
+	Height string `json:"height"`
+	Size   string `json:"size"`
+}
+
 // NewGiphyProvider creates an instance of a GIF provider that uses the Giphy API
 func NewGiphyProvider(httpClient HTTPClient, errorGenerator pluginError.PluginError, apiKey, language, rating, rendition, rootURL string) (GifProvider, *model.AppError) {
 	if errorGenerator == nil {
@@ -67,17 +101,17 @@ func (p *giphy) GetAttributionMessage() string {
 	return fmt.Sprintf("![GIPHY](%s/public/powered-by-giphy.png)", p.rootURL)
 }
 
-// Return the URL of a GIF that matches the query, or an empty string if no GIF matches the query, or an error if the search failed
-func (p *giphy) GetGifURL(request string, cursor *string) (string, *model.AppError) {
-	req, err := http.NewRequest("GET", baseURLGiphy+"/search", nil)
+// Return the GIF that matches the query, or nil if no GIF matches the query, or an error if the search failed
+func (p *giphy) GetGif(ctx context.Context, query Query, cursor *string) (*GifResult, *model.AppError) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURLGiphy+"/search", nil)
 	if err != nil {
-		return "", p.errorGenerator.FromError("Could not generate URL", err)
+		return nil, p.errorGenerator.FromError("Could not generate URL", err)
 	}
 
 	q := req.URL.Query()
 
 	q.Add("api_key", p.apiKey)
-	q.Add("q", request)
+	q.Add("q", query.Keywords)
 	if counter, err2 := strconv.Atoi(*cursor); err2 == nil {
 		q.Add("offset", fmt.Sprintf("%d", counter))
 	}
@@ -85,15 +119,15 @@ func (p *giphy) GetGifURL(request string, cursor *string) (string, *model.AppErr
 	if len(p.rating) > 0 {
 		q.Add("rating", p.rating)
 	}
-	if len(p.language) > 0 {
-		q.Add("lang", p.language)
+	if language := toGiphyLanguage(query.Locale, p.language); len(language) > 0 {
+		q.Add("lang", language)
 	}
 
 	req.URL.RawQuery = q.Encode()
 
 	r, err := p.httpClient.Do(req)
 	if err != nil {
-		return "", p.errorGenerator.FromError("Error calling the Giphy API", err)
+		return nil, p.errorGenerator.FromError("Error calling the Giphy API", err)
 	}
 	if r != nil && r.Body != nil {
 		defer r.Body.Close()
@@ -104,25 +138,174 @@ func (p *giphy) GetGifURL(request string, cursor *string) (string, *model.AppErr
 		if r.StatusCode == http.StatusTooManyRequests {
 			explanation = ", this can happen if you're using the default Giphy API key"
 		}
-		return "", p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the Giphy API (HTTP Status: %v%s)", r.Status, explanation))
+		return nil, p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the Giphy API (HTTP Status: %v%s)", r.Status, explanation))
 	}
 	var response GiphySearchResult
 	if r.Body == nil {
-		return "", p.errorGenerator.FromMessage("Giphy search response body is empty")
+		return nil, p.errorGenerator.FromMessage("Giphy search response body is empty")
 	}
 	decoder := json.NewDecoder(r.Body)
 	if err = decoder.Decode(&response); err != nil {
-		return "", p.errorGenerator.FromError("Could not parse Giphy search response body", err)
+		return nil, p.errorGenerator.FromError("Could not parse Giphy search response body", err)
 	}
 	if len(response.Data) < 1 {
-		return "", nil
+		return nil, nil
 	}
-	gif := response.Data[0]
-	url := gif.Images[p.rendition].URL
+	gif := response.Data[0].toGifResult()
+	gif.URL = selectRendition(gif.Renditions, p.renditionPreferences(), query.Budget)
 
-	if len(url) < 1 {
-		return "", p.errorGenerator.FromMessage("No URL found for display style \"" + p.rendition + "\" in the response")
+	if len(gif.URL) < 1 {
+		return nil, p.errorGenerator.FromMessage("No URL found for display style \"" + p.rendition + "\" in the response")
 	}
 	*cursor = fmt.Sprintf("%d", response.Pagination.Offset+1)
-	return url, nil
+	return gif, nil
+}
+
+// GetSuggestions returns the search terms that complete the keywords, followed by the terms related to them
+func (p *giphy) GetSuggestions(ctx context.Context, query Query, limit int) ([]string, *model.AppError) {
+	suggestions := []string{}
+	for _, endpoint := range []string{baseURLGiphy + "/search/tags", baseURLGiphyTags + "/related/" + url.PathEscape(query.Keywords)} {
+		if len(suggestions) >= limit {
+			break
+		}
+		terms, appErr := p.getTerms(ctx, endpoint, query, limit)
+		if appErr != nil {
+			return nil, appErr
+		}
+		suggestions = appendSuggestions(suggestions, query.Keywords, terms, limit)
+	}
+	return suggestions, nil
+}
+
+// getTerms returns the names of the tags returned by a Giphy endpoint
+func (p *giphy) getTerms(ctx context.Context, endpoint string, query Query, limit int) ([]string, *model.AppError) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Could not generate URL", err)
+	}
+	q := req.URL.Query()
+	q.Add("api_key", p.apiKey)
+	q.Add("q", query.Keywords)
+	q.Add("limit", strconv.Itoa(limit))
+	req.URL.RawQuery = q.Encode()
+
+	r, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Error calling the Giphy API", err)
+	}
+	if r != nil && r.Body != nil {
+		defer r.Body.Close()
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the Giphy API (HTTP Status: %v)", r.Status))
+	}
+	if r.Body == nil {
+		return nil, p.errorGenerator.FromMessage("Giphy suggestions response body is empty")
+	}
+	var response giphyTagsResult
+	if err = json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, p.errorGenerator.FromError("Could not parse Giphy suggestions response body", err)
+	}
+	terms := []string{}
+	for _, tag := range response.Data {
+		terms = append(terms, tag.Name)
+	}
+	return terms, nil
+}
+
+// GetGifByPage returns the GIF of a GIPHY page, with the configured rendition
+func (p *giphy) GetGifByPage(ctx context.Context, page GifPage, query Query) (*GifResult, *model.AppError) {
+	if page.Provider != ProviderGiphy {
+		return nil, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURLGiphy+"/"+url.PathEscape(page.GifID), nil)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Could not generate URL", err)
+	}
+	q := req.URL.Query()
+	q.Add("api_key", p.apiKey)
+	req.URL.RawQuery = q.Encode()
+
+	r, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Error calling the Giphy API", err)
+	}
+	if r != nil && r.Body != nil {
+		defer r.Body.Close()
+	}
+	if r.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the Giphy API (HTTP Status: %v)", r.Status))
+	}
+	if r.Body == nil {
+		return nil, p.errorGenerator.FromMessage("Giphy GIF response body is empty")
+	}
+	var response giphyGifResult
+	if err = json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, p.errorGenerator.FromError("Could not parse Giphy GIF response body", err)
+	}
+	if response.Data.ID == "" {
+		return nil, nil
+	}
+	gif := response.Data.toGifResult()
+	gif.URL = selectRendition(gif.Renditions, p.renditionPreferences(), query.Budget)
+	if len(gif.URL) < 1 {
+		return nil, p.errorGenerator.FromMessage("No URL found for display style \"" + p.rendition + "\" in the response")
+	}
+	return gif, nil
+}
+
+// ReportShare calls the "onsent" pingback URL of a posted GIPHY GIF
+func (p *giphy) ReportShare(ctx context.Context, share Share) *model.AppError {
+	if share.Provider != ProviderGiphy || share.ReportURL == "" {
+		return nil
+	}
+	reportURL, err := url.Parse(share.ReportURL)
+	// The URL comes from the post actions, only the GIPHY analytics are called
+	if err != nil || reportURL.Scheme != "https" || !(reportURL.Hostname() == "giphy.com" || strings.HasSuffix(reportURL.Hostname(), ".giphy.com")) {
+		return p.errorGenerator.FromMessage("Invalid GIPHY pingback URL: " + share.ReportURL)
+	}
+	q := reportURL.Query()
+	q.Set("ts", strconv.FormatInt(model.GetMillis(), 10))
+	reportURL.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", reportURL.String(), nil)
+	if err != nil {
+		return p.errorGenerator.FromError("Could not generate URL", err)
+	}
+	r, err := p.httpClient.Do(req)
+	if err != nil {
+		return p.errorGenerator.FromError("Error calling the GIPHY pingback", err)
+	}
+	if r != nil && r.Body != nil {
+		defer r.Body.Close()
+	}
+	if r.StatusCode < 200 || r.StatusCode >= 300 {
+		return p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the GIPHY pingback (HTTP Status: %v)", r.Status))
+	}
+	return nil
+}
+
+func (g *giphyGif) toGifResult() *GifResult {
+	result := &GifResult{
+		ID:         g.ID,
+		Provider:   ProviderGiphy,
+		Title:      g.Title,
+		AltText:    g.AltText,
+		PageURL:    g.URL,
+		Renditions: map[string]Rendition{},
+		// Called when the GIF is posted, as asked by the GIPHY API terms
+		ShareReportURL: g.Analytics.OnSent.URL,
+	}
+	for name, image := range g.Images {
+		if image.URL == "" {
+			continue
+		}
+		width, _ := strconv.Atoi(image.Width)
+		height, _ := strconv.Atoi(image.Height)
+		size, _ := strconv.Atoi(image.Size)
+		result.Renditions[name] = Rendition{URL: image.URL, Width: width, Height: height, Size: size}
+	}
+	return result
 }
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -66,70 +67,97 @@ func generateGiphyProviderForTest(mockHTTPResponse *http.Response) *giphy {
 	return provider.(*giphy)
 }
 
-func TestGiphyProviderGetGifURLShouldReturnUrlWhenSearchSucceeds(t *testing.T) {
+func TestGiphyProviderGetGifShouldReturnUrlWhenSearchSucceeds(t *testing.T) {
 	p := generateGiphyProviderForTest(newServerResponseOK(defaultGiphyResponseBody))
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
-	assert.NotEmpty(t, url)
-	assert.Equal(t, url, "url")
+	assert.NotNil(t, gif)
+	assert.Equal(t, gif.URL, "url")
 }
 
-func TestGiphyProviderGetGifURLShouldFailIfSearchBodyIsEmpty(t *testing.T) {
+func TestGiphyProviderGetGifShouldReturnMetadataAndRenditions(t *testing.T) {
+	p := generateGiphyProviderForTest(newServerResponseOK(`{"data": [{
+		"id": "gif42", "url": "https://giphy.com/gifs/gif42", "title": "Cat GIF", "alt_text": "A cat jumps",
+		"images": {
+			"fixed_height_small": {"url": "https://media.giphy.com/small.gif", "width": "150", "height": "100", "size": "4242"},
+			"original": {"url": "https://media.giphy.com/original.gif", "width": "480", "height": "320", "size": "424242"}
+		},
+		"analytics": {"onsent": {"url": "https://giphy-analytics.giphy.com/v2/pingback_simple?analytics_response_payload=abc&action_type=SENT"}}
+	}]}`))
+	cursor := ""
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, &GifResult{
+		ID:       "gif42",
+		Provider: ProviderGiphy,
+		Title:    "Cat GIF",
+		AltText:  "A cat jumps",
+		PageURL:  "https://giphy.com/gifs/gif42",
+		URL:      "https://media.giphy.com/small.gif",
+		Renditions: map[string]Rendition{
+			"fixed_height_small": {URL: "https://media.giphy.com/small.gif", Width: 150, Height: 100, Size: 4242},
+			"original":           {URL: "https://media.giphy.com/original.gif", Width: 480, Height: 320, Size: 424242},
+		},
+		ShareReportURL: "https://giphy-analytics.giphy.com/v2/pingback_simple?analytics_response_payload=abc&action_type=SENT",
+	}, gif)
+}
+
+func TestGiphyProviderGetGifShouldFailIfSearchBodyIsEmpty(t *testing.T) {
 	p := generateGiphyProviderForTest(newServerResponseOK(""))
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "empty")
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGiphyProviderGetGifURLShouldFailWhenParseError(t *testing.T) {
+func TestGiphyProviderGetGifShouldFailWhenParseError(t *testing.T) {
 	p := generateGiphyProviderForTest(newServerResponseOK("This is not a valid JSON response"))
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGiphyProviderGetGifURLShouldReturnEmptyUrlWhenSearchReturnNoResult(t *testing.T) {
+func TestGiphyProviderGetGifShouldReturnEmptyUrlWhenSearchReturnNoResult(t *testing.T) {
 	p := generateGiphyProviderForTest(newServerResponseOK("{\"data\": [] }"))
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGiphyProviderGetGifURLShouldFailWhenNoURLForRendition(t *testing.T) {
+func TestGiphyProviderGetGifShouldFailWhenNoURLForRendition(t *testing.T) {
 	p := generateGiphyProviderForTest(newServerResponseOK(defaultGiphyResponseBody))
 	p.rendition = "unknown_rendition_style"
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "No URL found for display style")
 	assert.Contains(t, err.Error(), p.rendition)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGiphyProviderGetGifURLShouldFailWhenSearchBadStatus(t *testing.T) {
+func TestGiphyProviderGetGifShouldFailWhenSearchBadStatus(t *testing.T) {
 	serverResponse := newServerResponseKO(400)
 	p := generateGiphyProviderForTest(serverResponse)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), serverResponse.Status)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestGiphyProviderGetGifURLShouldFailWhenSearchTooManyRequestStatus(t *testing.T) {
+func TestGiphyProviderGetGifShouldFailWhenSearchTooManyRequestStatus(t *testing.T) {
 	serverResponse := newServerResponseKO(429)
 	p := generateGiphyProviderForTest(serverResponse)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), serverResponse.Status)
 	assert.Contains(t, err.Error(), "default Giphy API key")
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
 func generateGiphyProviderForURLBuildingTests() (*giphy, *MockHTTPClient, string) {
@@ -147,12 +175,12 @@ func TestGiphyProviderParameterAPIKey(t *testing.T) {
 		assert.Contains(t, req.URL.RawQuery, "api_key="+testGiphyAPIKey)
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 }
 
-func TestGiphyProviderGetGifURLWhenCursorIsEmpty(t *testing.T) {
+func TestGiphyProviderGetGifWhenCursorIsEmpty(t *testing.T) {
 	p, client, cursor := generateGiphyProviderForURLBuildingTests()
 
 	// Cursor : optional
@@ -161,13 +189,13 @@ func TestGiphyProviderGetGifURLWhenCursorIsEmpty(t *testing.T) {
 		assert.NotContains(t, req.URL.RawQuery, "offset")
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 	assert.Equal(t, "1", cursor)
 }
 
-func TestGiphyProviderGetGifURLWhenCursorIsZero(t *testing.T) {
+func TestGiphyProviderGetGifWhenCursorIsZero(t *testing.T) {
 	p, client, _ := generateGiphyProviderForURLBuildingTests()
 
 	// Initial value : 0
@@ -176,13 +204,13 @@ func TestGiphyProviderGetGifURLWhenCursorIsZero(t *testing.T) {
 		assert.Contains(t, req.URL.RawQuery, "offset=0")
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 	assert.Equal(t, "1", cursor)
 }
 
-func TestGiphyProviderGetGifURLWhenCursorIsNotANumber(t *testing.T) {
+func TestGiphyProviderGetGifWhenCursorIsNotANumber(t *testing.T) {
 	p, client, _ := generateGiphyProviderForURLBuildingTests()
 
 	// Initial value : not a number, that should be ignored
@@ -191,56 +219,78 @@ func TestGiphyProviderGetGifURLWhenCursorIsNotANumber(t *testing.T) {
 		assert.NotContains(t, "offset", req.URL.RawQuery)
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 	assert.Equal(t, "1", cursor)
 }
 
-func TestGiphyProviderGetGifURLShouldApplyRatingFilterWhenUnset(t *testing.T) {
+func TestGiphyProviderGetGifShouldApplyRatingFilterWhenUnset(t *testing.T) {
 	p, client, cursor := generateGiphyProviderForURLBuildingTests()
 	p.rating = ""
 	client.testRequestFunc = func(req *http.Request) bool {
 		assert.NotContains(t, req.URL.RawQuery, "rating")
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 }
 
-func TestGiphyProviderGetGifURLShouldApplyRatingFilterWhenSet(t *testing.T) {
+func TestGiphyProviderGetGifShouldApplyRatingFilterWhenSet(t *testing.T) {
 	p, client, cursor := generateGiphyProviderForURLBuildingTests()
 	p.rating = "RATING"
 	client.testRequestFunc = func(req *http.Request) bool {
 		assert.Contains(t, req.URL.RawQuery, "rating="+p.rating)
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 }
 
-func TestGiphyProviderGetGifURLShouldApplyLanguageFilterWhenUnset(t *testing.T) {
+func TestGiphyProviderGetGifShouldApplyLanguageFilterWhenUnset(t *testing.T) {
 	p, client, cursor := generateGiphyProviderForURLBuildingTests()
 	p.language = ""
 	client.testRequestFunc = func(req *http.Request) bool {
 		assert.NotContains(t, req.URL.RawQuery, "lang")
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 }
 
-func TestGiphyProviderGetGifURLShouldApplyLanguageFilterWhenSet(t *testing.T) {
+func TestGiphyProviderGetGifShouldApplyLanguageFilterWhenSet(t *testing.T) {
 	p, client, cursor := generateGiphyProviderForURLBuildingTests()
 	p.language = "Moldovalaque"
 	client.testRequestFunc = func(req *http.Request) bool {
 		assert.Contains(t, req.URL.RawQuery, "lang="+p.language)
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
+	assert.Nil(t, err)
+	assert.True(t, client.lastRequestPassTest)
+}
+
+func TestGiphyProviderGetGifShouldUseUserLocaleWhenSupported(t *testing.T) {
+	p, client, cursor := generateGiphyProviderForURLBuildingTests()
+	client.testRequestFunc = func(req *http.Request) bool {
+		assert.Contains(t, req.URL.RawQuery, "lang=pt")
+		return true
+	}
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat", Locale: "pt-BR"}, &cursor)
+	assert.Nil(t, err)
+	assert.True(t, client.lastRequestPassTest)
+}
+
+func TestGiphyProviderGetGifShouldFallbackToLanguageWhenLocaleUnsupported(t *testing.T) {
+	p, client, cursor := generateGiphyProviderForURLBuildingTests()
+	client.testRequestFunc = func(req *http.Request) bool {
+		assert.Contains(t, req.URL.RawQuery, "lang="+testGiphyLanguage)
+		return true
+	}
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat", Locale: "bg"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 }
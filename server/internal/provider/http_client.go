@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultConnectTimeout = 5 * time.Second
+	defaultRequestTimeout = 10 * time.Second
+	// Delay before the first retry, doubled for each following retry
+	defaultRetryBaseDelay = 200 * time.Millisecond
+)
+
+// HTTPClientOptions defines how the providers call their API
+type HTTPClientOptions struct {
+	// Maximum duration to establish a connection, including the TLS handshake
+	ConnectTimeout time.Duration
+	// Maximum duration of a whole request, including reading the response
+	RequestTimeout time.Duration
+	// URL of the proxy to use, or empty to use the proxy from the HTTP_PROXY/HTTPS_PROXY environment variables
+	ProxyURL string
+	// PEM certificates trusted in addition to the system ones, ex: the CA of a corporate proxy
+	CACertificates string
+	// Number of retries of an idempotent request after a timeout or a server error
+	MaxRetries int
+}
+
+// HTTPClientOptionsFromConfiguration returns the HTTP client options set in the configuration, with default timeouts
+func HTTPClientOptionsFromConfiguration(configuration pluginConf.Configuration) HTTPClientOptions {
+	options := HTTPClientOptions{
+		ConnectTimeout: time.Duration(configuration.HTTPConnectTimeoutSeconds) * time.Second,
+		RequestTimeout: time.Duration(configuration.HTTPRequestTimeoutSeconds) * time.Second,
+		ProxyURL:       strings.TrimSpace(configuration.HTTPProxyURL),
+		CACertificates: strings.TrimSpace(configuration.HTTPCACertificates),
+		MaxRetries:     configuration.HTTPMaxRetries,
+	}
+	if options.ConnectTimeout <= 0 {
+		options.ConnectTimeout = defaultConnectTimeout
+	}
+	if options.RequestTimeout <= 0 {
+		options.RequestTimeout = defaultRequestTimeout
+	}
+	return options
+}
+
+// NewHTTPClient creates the HTTP client used by the providers to call their API
+func NewHTTPClient(options HTTPClientOptions) (HTTPClient, error) {
+	proxy := http.ProxyFromEnvironment
+	if options.ProxyURL != "" {
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil || proxyURL.Host == "" {
+			return nil, errors.Errorf("invalid proxy URL '%s'", options.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if options.CACertificates != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(options.CACertificates)) {
+			return nil, errors.New("no valid PEM certificate found in the additional CA certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   options.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   options.ConnectTimeout,
+		ResponseHeaderTimeout: options.RequestTimeout,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &retryingHTTPClient{
+		client:     &http.Client{Transport: transport, Timeout: options.RequestTimeout},
+		maxRetries: options.MaxRetries,
+		baseDelay:  defaultRetryBaseDelay,
+	}, nil
+}
+
+// retryingHTTPClient retries the idempotent requests that fail because of a timeout or a server error,
+// waiting an exponential delay with jitter between the attempts
+type retryingHTTPClient struct {
+	client     *http.Client
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+func (c *retryingHTTPClient) Get(s string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, s, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+func (c *retryingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	for attempt := 0; ; attempt++ {
+		r, err := c.client.Do(req)
+		if !idempotent || attempt >= c.maxRetries || !isRetryable(r, err) {
+			return r, err
+		}
+		if r != nil && r.Body != nil {
+			r.Body.Close()
+		}
+		if waitErr := c.wait(req, attempt); waitErr != nil {
+			return nil, waitErr
+		}
+	}
+}
+
+// wait sleeps before the next attempt, unless the request is canceled
+func (c *retryingHTTPClient) wait(req *http.Request, attempt int) error {
+	delay := c.baseDelay << uint(attempt)
+	if delay > 0 {
+		delay += time.Duration(rand.Int63n(int64(delay)))
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// isRetryable returns true if the request failed because of a timeout or a server error
+func isRetryable(r *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+	return r.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestHTTPClient returns a client that retries without waiting
+func newTestHTTPClient(t *testing.T, options HTTPClientOptions) *retryingHTTPClient {
+	client, err := NewHTTPClient(options)
+	assert.Nil(t, err)
+	retryingClient := client.(*retryingHTTPClient)
+	retryingClient.baseDelay = 0
+	return retryingClient
+}
+
+// newFlakyServer returns a server that fails with the status until it has been called failures times
+func newFlakyServer(failures int32, status int, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestHTTPClientOptionsFromConfigurationShouldUseDefaultTimeouts(t *testing.T) {
+	options := HTTPClientOptionsFromConfiguration(pluginConf.Configuration{HTTPMaxRetries: 3, HTTPProxyURL: " http://proxy:3128 "})
+	assert.Equal(t, HTTPClientOptions{ConnectTimeout: defaultConnectTimeout, RequestTimeout: defaultRequestTimeout, ProxyURL: "http://proxy:3128", MaxRetries: 3}, options)
+
+	options = HTTPClientOptionsFromConfiguration(pluginConf.Configuration{HTTPConnectTimeoutSeconds: 1, HTTPRequestTimeoutSeconds: 2})
+	assert.Equal(t, time.Second, options.ConnectTimeout)
+	assert.Equal(t, 2*time.Second, options.RequestTimeout)
+}
+
+func TestNewHTTPClientShouldRejectInvalidSettings(t *testing.T) {
+	_, err := NewHTTPClient(HTTPClientOptions{ProxyURL: "proxy:3128:x"})
+	assert.NotNil(t, err)
+	_, err = NewHTTPClient(HTTPClientOptions{CACertificates: "not a certificate"})
+	assert.NotNil(t, err)
+}
+
+func TestHTTPClientShouldRetryServerErrors(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(2, http.StatusBadGateway, &calls)
+	defer server.Close()
+	client := newTestHTTPClient(t, HTTPClientOptions{RequestTimeout: time.Second, MaxRetries: 2})
+
+	r, err := client.Get(server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestHTTPClientShouldStopRetryingAfterMaxRetries(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(5, http.StatusServiceUnavailable, &calls)
+	defer server.Close()
+	client := newTestHTTPClient(t, HTTPClientOptions{RequestTimeout: time.Second, MaxRetries: 1})
+
+	r, err := client.Get(server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, r.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestHTTPClientShouldNotRetryClientErrorsOrNonIdempotentRequests(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(5, http.StatusTooManyRequests, &calls)
+	defer server.Close()
+	client := newTestHTTPClient(t, HTTPClientOptions{RequestTimeout: time.Second, MaxRetries: 3})
+
+	r, err := client.Get(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, r.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	var postCalls int32
+	postServer := newFlakyServer(5, http.StatusInternalServerError, &postCalls)
+	defer postServer.Close()
+	req, _ := http.NewRequest(http.MethodPost, postServer.URL, nil)
+	r, err = client.Do(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&postCalls))
+}
+
+func TestHTTPClientShouldRetryTimeouts(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := newTestHTTPClient(t, HTTPClientOptions{RequestTimeout: 50 * time.Millisecond, MaxRetries: 1})
+
+	r, err := client.Get(server.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestHTTPClientShouldNotRetryCanceledRequests(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client := newTestHTTPClient(t, HTTPClientOptions{RequestTimeout: time.Second, MaxRetries: 3})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	_, err := client.Do(req)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestHTTPClientShouldUseTheProxy(t *testing.T) {
+	var proxiedURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedURL = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+	client := newTestHTTPClient(t, HTTPClientOptions{RequestTimeout: time.Second, ProxyURL: proxy.URL})
+
+	r, err := client.Get("http://api.giphy.test/v1/gifs/search")
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, "http://api.giphy.test/v1/gifs/search", proxiedURL)
+}
+
+func TestHTTPClientShouldTrustTheAdditionalCACertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, err := newTestHTTPClient(t, HTTPClientOptions{RequestTimeout: time.Second}).Get(server.URL)
+	assert.NotNil(t, err)
+
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	r, err := newTestHTTPClient(t, HTTPClientOptions{RequestTimeout: time.Second, CACertificates: string(certificate)}).Get(server.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+}
@@ -0,0 +1,58 @@
+package provider
+
+import "strings"
+
+// giphyLanguages lists the language codes supported by the Giphy API
+// (see https://developers.giphy.com/docs/optional-settings/#language-support)
+var giphyLanguages = map[string]bool{
+	"en": true, "es": true, "pt": true, "id": true, "fr": true, "ar": true, "tr": true, "th": true,
+	"vi": true, "de": true, "it": true, "ja": true, "zh-CN": true, "zh-TW": true, "ru": true, "ko": true,
+	"pl": true, "nl": true, "ro": true, "hu": true, "sv": true, "cs": true, "hi": true, "bn": true,
+	"da": true, "fa": true, "tl": true, "fi": true, "he": true, "ms": true, "no": true, "uk": true,
+}
+
+// tenorLanguages lists the ISO 639-1 language codes supported by the Tenor API
+// (see https://developers.google.com/tenor/guides/localization)
+var tenorLanguages = map[string]bool{
+	"en": true, "es": true, "pt": true, "id": true, "fr": true, "ar": true, "tr": true, "th": true,
+	"vi": true, "de": true, "it": true, "ja": true, "zh": true, "ru": true, "ko": true, "pl": true,
+	"nl": true, "ro": true, "hu": true, "sv": true, "cs": true, "hi": true, "bn": true, "da": true,
+	"fa": true, "tl": true, "fi": true, "he": true, "ms": true, "no": true, "uk": true, "bg": true,
+}
+
+// splitLocale splits a Mattermost locale code (ex: "pt-BR", "en") into its language and region parts
+func splitLocale(locale string) (language, region string) {
+	parts := strings.SplitN(strings.ReplaceAll(locale, "_", "-"), "-", 2)
+	language = strings.ToLower(parts[0])
+	if len(parts) > 1 {
+		region = strings.ToUpper(parts[1])
+	}
+	return language, region
+}
+
+// toGiphyLanguage converts a Mattermost locale to a Giphy language code, or returns fallback if the language is not supported
+func toGiphyLanguage(locale, fallback string) string {
+	language, region := splitLocale(locale)
+	if region != "" && giphyLanguages[language+"-"+region] {
+		return language + "-" + region
+	}
+	if giphyLanguages[language] {
+		return language
+	}
+	if language == "zh" {
+		return "zh-CN"
+	}
+	return fallback
+}
+
+// toTenorLocale converts a Mattermost locale to a Tenor locale (ex: "pt_BR"), or returns fallback if the language is not supported
+func toTenorLocale(locale, fallback string) string {
+	language, region := splitLocale(locale)
+	if !tenorLanguages[language] {
+		return fallback
+	}
+	if region != "" {
+		return language + "_" + region
+	}
+	return language
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToGiphyLanguage(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		expected string
+	}{
+		{locale: "", expected: "fallback"},
+		{locale: "fr", expected: "fr"},
+		{locale: "pt-BR", expected: "pt"},
+		{locale: "en-AU", expected: "en"},
+		{locale: "zh-CN", expected: "zh-CN"},
+		{locale: "zh-TW", expected: "zh-TW"},
+		{locale: "zh_TW", expected: "zh-TW"},
+		{locale: "bg", expected: "fallback"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, toGiphyLanguage(testCase.locale, "fallback"), "Testing: "+testCase.locale)
+	}
+}
+
+func TestToTenorLocale(t *testing.T) {
+	testCases := []struct {
+		locale   string
+		expected string
+	}{
+		{locale: "", expected: "fallback"},
+		{locale: "fr", expected: "fr"},
+		{locale: "pt-BR", expected: "pt_BR"},
+		{locale: "zh-CN", expected: "zh_CN"},
+		{locale: "bg", expected: "bg"},
+		{locale: "xx", expected: "fallback"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, toTenorLocale(testCase.locale, "fallback"), "Testing: "+testCase.locale)
+	}
+}
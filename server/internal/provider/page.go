@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// PageResolver is implemented by the GIF providers that can find the GIF shown by one of their web pages,
+// ex: a link to a GIPHY or Tenor page copied from the browser
+type PageResolver interface {
+	// GetGifByPage returns the GIF of the page, or nil if the page is not from this provider or its GIF does not exist
+	GetGifByPage(ctx context.Context, page GifPage, query Query) (*GifResult, *model.AppError)
+}
+
+// GifPage is a web page of a provider that shows one GIF
+type GifPage struct {
+	// Provider of the page, and ID of its GIF for this provider
+	Provider string
+	GifID    string
+	// Words of the page URL that describe the GIF, ex: "happy cat" for https://giphy.com/gifs/happy-cat-abc123
+	Keywords string
+}
+
+var (
+	// Paths of the GIPHY GIF pages, ex: /gifs/happy-cat-abc123, /stickers/abc123, /embed/abc123
+	giphyPagePathRegexp = regexp.MustCompile(`^/(?:gifs|stickers|embed)/(?:([^/]*)-)?([A-Za-z0-9]+)/?$`)
+	// Paths of the Tenor GIF pages, with an optional language, ex: /view/happy-cat-gif-12345678, /fr/view/happy-cat-gif-12345678
+	tenorPagePathRegexp = regexp.MustCompile(`^/(?:[a-zA-Z]{2}(?:-[a-zA-Z]{2})?/)?view/(?:([^/]*)-)?([0-9]+)/?$`)
+)
+
+// ParseGifPage returns the GIF page of a GIPHY or Tenor URL, ok is false if the text is not the URL of such a page
+func ParseGifPage(text string) (page GifPage, ok bool) {
+	pageURL, err := url.Parse(strings.TrimSpace(text))
+	if err != nil || (pageURL.Scheme != "https" && pageURL.Scheme != "http") {
+		return GifPage{}, false
+	}
+	var matches []string
+	switch strings.TrimPrefix(strings.ToLower(pageURL.Hostname()), "www.") {
+	case "giphy.com":
+		page.Provider = ProviderGiphy
+		matches = giphyPagePathRegexp.FindStringSubmatch(pageURL.Path)
+	case "tenor.com":
+		page.Provider = ProviderTenor
+		matches = tenorPagePathRegexp.FindStringSubmatch(pageURL.Path)
+	}
+	if matches == nil {
+		return GifPage{}, false
+	}
+	page.GifID = matches[2]
+	words := strings.FieldsFunc(matches[1], func(r rune) bool { return r == '-' })
+	// The Tenor page names end with "gif", ex: happy-cat-gif
+	if page.Provider == ProviderTenor && len(words) > 0 && strings.EqualFold(words[len(words)-1], "gif") {
+		words = words[:len(words)-1]
+	}
+	page.Keywords = strings.Join(words, " ")
+	return page, true
+}
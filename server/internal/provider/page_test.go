@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/test"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubPageResolver finds the GIF of the pages of its provider
+type stubPageResolver struct {
+	stubProvider
+	attribution string
+}
+
+func (p *stubPageResolver) GetGifByPage(ctx context.Context, page GifPage, query Query) (*GifResult, *model.AppError) {
+	if page.Provider != p.name {
+		return nil, nil
+	}
+	return &GifResult{ID: page.GifID, Provider: p.name, URL: "https://gif.fr/" + page.GifID}, nil
+}
+
+func (p *stubPageResolver) GetAttributionMessage() string {
+	return p.attribution
+}
+
+func TestParseGifPage(t *testing.T) {
+	for text, expected := range map[string]GifPage{
+		"https://giphy.com/gifs/cat-happy-abc123":              {Provider: ProviderGiphy, GifID: "abc123", Keywords: "cat happy"},
+		"https://www.giphy.com/gifs/abc123/":                   {Provider: ProviderGiphy, GifID: "abc123"},
+		"https://giphy.com/stickers/dance-xyz789":              {Provider: ProviderGiphy, GifID: "xyz789", Keywords: "dance"},
+		"https://giphy.com/embed/abc123":                       {Provider: ProviderGiphy, GifID: "abc123"},
+		"https://tenor.com/view/happy-cat-gif-12345678":        {Provider: ProviderTenor, GifID: "12345678", Keywords: "happy cat"},
+		"https://tenor.com/fr/view/chat-heureux-gif-12345678":  {Provider: ProviderTenor, GifID: "12345678", Keywords: "chat heureux"},
+		"  https://tenor.com/view/12345678?utm_source=share  ": {Provider: ProviderTenor, GifID: "12345678"},
+	} {
+		page, ok := ParseGifPage(text)
+		assert.True(t, ok, text)
+		assert.Equal(t, expected, page, text)
+	}
+	for _, text := range []string{
+		"happy cat",
+		"https://giphy.com/explore/cat",
+		"https://media.giphy.com/media/abc123/giphy.gif",
+		"https://tenor.com/search/cat-gifs",
+		"https://tenor.com.evil.com/view/cat-gif-12345678",
+		"ftp://giphy.com/gifs/abc123",
+	} {
+		_, ok := ParseGifPage(text)
+		assert.False(t, ok, text)
+	}
+}
+
+func TestGiphyProviderGetGifByPageShouldReturnTheGifWithTheRendition(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{newServerResponseOK(`{"data": {
+		"id": "abc123", "title": "Happy Cat GIF", "images": {"fixed_height_small": {"url": "https://media.giphy.com/small.gif"}}
+	}}`)}}
+	gifProvider, _ := NewGiphyProvider(client, test.MockErrorGenerator(), testGiphyAPIKey, testGiphyLanguage, testGiphyRating, testGiphyRendition, testRootURL)
+
+	gif, err := gifProvider.(PageResolver).GetGifByPage(context.Background(), GifPage{Provider: ProviderGiphy, GifID: "abc123"}, Query{})
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, gif) {
+		assert.Equal(t, "abc123", gif.ID)
+		assert.Equal(t, "https://media.giphy.com/small.gif", gif.URL)
+	}
+	if assert.Len(t, client.requests, 1) {
+		assert.Equal(t, "/v1/gifs/abc123", client.requests[0].URL.Path)
+		assert.Equal(t, testGiphyAPIKey, client.requests[0].URL.Query().Get("api_key"))
+	}
+}
+
+func TestGiphyProviderGetGifByPageShouldReturnNothingForUnknownOrOtherPages(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{newServerResponseKO(http.StatusNotFound)}}
+	gifProvider, _ := NewGiphyProvider(client, test.MockErrorGenerator(), testGiphyAPIKey, testGiphyLanguage, testGiphyRating, testGiphyRendition, testRootURL)
+	resolver := gifProvider.(PageResolver)
+
+	gif, err := resolver.GetGifByPage(context.Background(), GifPage{Provider: ProviderTenor, GifID: "12345678"}, Query{})
+	assert.Nil(t, err)
+	assert.Nil(t, gif)
+	assert.Empty(t, client.requests)
+
+	gif, err = resolver.GetGifByPage(context.Background(), GifPage{Provider: ProviderGiphy, GifID: "unknown"}, Query{})
+	assert.Nil(t, err)
+	assert.Nil(t, gif)
+}
+
+func TestTenorProviderGetGifByPageShouldReturnTheGifWithTheRendition(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{newServerResponseOK(`{"results": [{
+		"id": "12345678", "media_formats": {"` + testTenorRendition + `": {"url": "https://media.tenor.com/cat.gif"}}
+	}]}`)}}
+	gifProvider, _ := NewTenorProvider(client, test.MockErrorGenerator(), testTenorAPIKey, testTenorLanguage, testTenorRating, testTenorRendition)
+
+	gif, err := gifProvider.(PageResolver).GetGifByPage(context.Background(), GifPage{Provider: ProviderTenor, GifID: "12345678"}, Query{})
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, gif) {
+		assert.Equal(t, "12345678", gif.ID)
+		assert.Equal(t, "https://media.tenor.com/cat.gif", gif.URL)
+	}
+	if assert.Len(t, client.requests, 1) {
+		assert.Equal(t, "/v2/posts", client.requests[0].URL.Path)
+		assert.Equal(t, "12345678", client.requests[0].URL.Query().Get("ids"))
+		assert.Equal(t, testTenorAPIKey, client.requests[0].URL.Query().Get("key"))
+	}
+}
+
+func TestTenorProviderGetGifByPageShouldFailWhenBadStatus(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{newServerResponseKO(http.StatusBadRequest)}}
+	gifProvider, _ := NewTenorProvider(client, test.MockErrorGenerator(), testTenorAPIKey, testTenorLanguage, testTenorRating, testTenorRendition)
+
+	gif, err := gifProvider.(PageResolver).GetGifByPage(context.Background(), GifPage{Provider: ProviderTenor, GifID: "12345678"}, Query{})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, gif)
+}
+
+func TestFederatedProviderGetGifByPageShouldAskTheProviderOfThePage(t *testing.T) {
+	gifProvider := NewFederatedProvider([]GifProvider{
+		&stubPageResolver{stubProvider: stubProvider{name: ProviderGiphy}, attribution: "Via GIPHY"},
+		&stubProvider{name: "gfycat"},
+		&stubPageResolver{stubProvider: stubProvider{name: ProviderTenor}, attribution: "Via Tenor"},
+	}, test.MockErrorGenerator(), time.Second)
+
+	gif, err := gifProvider.(PageResolver).GetGifByPage(context.Background(), GifPage{Provider: ProviderTenor, GifID: "12345678"}, Query{})
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, gif) {
+		assert.Equal(t, ProviderTenor, gif.Provider)
+		assert.Equal(t, "Via Tenor", gif.Attribution)
+	}
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RenditionBudget limits the renditions that can be chosen for a GIF. Zero values mean no limit.
+type RenditionBudget struct {
+	// Maximum size in bytes
+	MaxBytes int
+	// Maximum width and height in pixels
+	MaxDimension int
+}
+
+// fits returns true if the rendition fits in the budget. Unknown sizes and dimensions always fit.
+func (b RenditionBudget) fits(r Rendition) bool {
+	if b.MaxBytes > 0 && r.Size > b.MaxBytes {
+		return false
+	}
+	if b.MaxDimension > 0 && (r.Width > b.MaxDimension || r.Height > b.MaxDimension) {
+		return false
+	}
+	return true
+}
+
+// parseRenditionPreferences returns the renditions of a comma-separated list, in order
+func parseRenditionPreferences(renditions string) []string {
+	preferences := []string{}
+	for _, rendition := range strings.Split(renditions, ",") {
+		if rendition = strings.TrimSpace(rendition); rendition != "" {
+			preferences = append(preferences, rendition)
+		}
+	}
+	return preferences
+}
+
+// selectRendition returns the URL of the first preferred rendition that fits in the budget. If none fits,
+// the largest animated GIF rendition that fits is chosen (never a still frame or a preview), and then the
+// smallest preferred rendition, so that a GIF is displayed even if the budget is too small. An empty string is returned if there is no suitable rendition.
+func selectRendition(renditions map[string]Rendition, preferences []string, budget RenditionBudget) string {
+	for _, preference := range preferences {
+		if rendition, ok := renditions[preference]; ok && rendition.URL != "" && budget.fits(rendition) {
+			return rendition.URL
+		}
+	}
+
+	// Sort the names so that the choice does not depend on the map order
+	names := make([]string, 0, len(renditions))
+	for name := range renditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var best *Rendition
+	for _, name := range names {
+		rendition := renditions[name]
+		if !isGifURL(rendition.URL) || isStillOrPreview(name, rendition.URL) || !budget.fits(rendition) {
+			continue
+		}
+		if best == nil || rendition.isLargerThan(*best) {
+			best = &rendition
+		}
+	}
+	if best != nil {
+		return best.URL
+	}
+
+	for _, preference := range preferences {
+		if rendition, ok := renditions[preference]; ok && rendition.URL != "" && (best == nil || best.isLargerThan(rendition)) {
+			rendition := rendition
+			best = &rendition
+		}
+	}
+	if best != nil {
+		return best.URL
+	}
+	return ""
+}
+
+func (r Rendition) isLargerThan(other Rendition) bool {
+	if r.Width*r.Height != other.Width*other.Height {
+		return r.Width*r.Height > other.Width*other.Height
+	}
+	return r.Size > other.Size
+}
+
+// isStillOrPreview returns true if the rendition is a still frame or a low quality preview of the GIF,
+// like GIPHY's original_still (whose URL ends with _s.gif) and preview_gif, or Tenor's gifpreview
+func isStillOrPreview(name, gifURL string) bool {
+	name = strings.ToLower(name)
+	if strings.Contains(name, "still") || strings.Contains(name, "preview") || strings.Contains(name, "poster") {
+		return true
+	}
+	parsedURL, err := url.Parse(gifURL)
+	return err == nil && strings.HasSuffix(strings.ToLower(parsedURL.Path), "_s.gif")
+}
+
+// isGifURL returns true if the URL is an animated GIF that Mattermost can display, unlike videos or still images
+func isGifURL(gifURL string) bool {
+	parsedURL, err := url.Parse(gifURL)
+	return err == nil && strings.HasSuffix(strings.ToLower(parsedURL.Path), ".gif")
+}
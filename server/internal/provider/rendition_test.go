@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRenditions = map[string]Rendition{
+	"original":           {URL: "https://media.fr/original.gif", Width: 480, Height: 480, Size: 4000000},
+	"fixed_height":       {URL: "https://media.fr/fixed_height.gif", Width: 200, Height: 200, Size: 500000},
+	"fixed_height_small": {URL: "https://media.fr/fixed_height_small.gif", Width: 100, Height: 100, Size: 100000},
+	"original_mp4":       {URL: "https://media.fr/original.mp4", Width: 480, Height: 480, Size: 300000},
+	"original_still":     {URL: "https://media.fr/original_s.gif", Width: 480, Height: 480, Size: 40000},
+	"preview_gif":        {URL: "https://media.fr/preview.gif", Width: 300, Height: 300, Size: 40000},
+	"480w_still":         {URL: "https://media.fr/480w.gif", Width: 480, Height: 480, Size: 40000},
+}
+
+func TestParseRenditionPreferences(t *testing.T) {
+	assert.Equal(t, []string{"fixed_height"}, parseRenditionPreferences("fixed_height"))
+	assert.Equal(t, []string{"original", "fixed_height", "downsized"}, parseRenditionPreferences(" original, fixed_height,,downsized "))
+	assert.Equal(t, []string{}, parseRenditionPreferences(""))
+}
+
+func TestSelectRendition(t *testing.T) {
+	testCases := []struct {
+		label       string
+		preferences []string
+		budget      RenditionBudget
+		expectedURL string
+	}{
+		{label: "First preference without budget", preferences: []string{"original", "fixed_height"}, expectedURL: "https://media.fr/original.gif"},
+		{label: "Missing preference", preferences: []string{"downsized", "fixed_height"}, expectedURL: "https://media.fr/fixed_height.gif"},
+		{label: "Preference over the size budget", preferences: []string{"original", "fixed_height"}, budget: RenditionBudget{MaxBytes: 1000000}, expectedURL: "https://media.fr/fixed_height.gif"},
+		{label: "Preference over the dimension budget", preferences: []string{"original", "fixed_height"}, budget: RenditionBudget{MaxDimension: 150}, expectedURL: "https://media.fr/fixed_height_small.gif"},
+		{label: "Largest GIF that fits when no preference fits", preferences: []string{"original"}, budget: RenditionBudget{MaxBytes: 600000}, expectedURL: "https://media.fr/fixed_height.gif"},
+		{label: "Largest GIF when no preference is available", preferences: []string{"downsized"}, expectedURL: "https://media.fr/original.gif"},
+		{label: "Animated GIF rather than a still or preview that fits", preferences: []string{"original"}, budget: RenditionBudget{MaxBytes: 1000000}, expectedURL: "https://media.fr/fixed_height.gif"},
+		{label: "Smallest preference when nothing fits", preferences: []string{"original", "fixed_height"}, budget: RenditionBudget{MaxBytes: 1000}, expectedURL: "https://media.fr/fixed_height.gif"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expectedURL, selectRendition(testRenditions, testCase.preferences, testCase.budget), testCase.label)
+	}
+	assert.Equal(t, "", selectRendition(map[string]Rendition{}, []string{"original"}, RenditionBudget{}))
+}
+
+func TestGiphyProviderGetGifShouldFallbackToNextRendition(t *testing.T) {
+	p := generateGiphyProviderForTest(newServerResponseOK(defaultGiphyResponseBody))
+	p.rendition = "downsized," + testGiphyRendition
+	cursor := ""
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, "url", gif.URL)
+}
+
+func TestTenorProviderGetGifShouldRequestAllPreferredRenditions(t *testing.T) {
+	p := generateTenorProviderForTest(newServerResponseOK(defaultTenorResponseBody))
+	p.rendition = "mediumgif,tinygif"
+	client := p.httpClient.(*MockHTTPClient)
+	client.testRequestFunc = func(req *http.Request) bool {
+		return req.URL.Query().Get("media_filter") == "mediumgif,tinygif"
+	}
+	cursor := ""
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
+	assert.Nil(t, err)
+	assert.True(t, client.lastRequestPassTest)
+	assert.Equal(t, "https://fakeurl/mediumgif", gif.URL)
+}
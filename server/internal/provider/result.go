@@ -0,0 +1,32 @@
+package provider
+
+// GifResult is a GIF found by a provider, with all the renditions and metadata returned by the provider API
+type GifResult struct {
+	// ID of the GIF for the provider
+	ID string
+	// Name of the provider that found the GIF
+	Provider string
+	// Text to display near the GIF, set when the GIF can come from several providers
+	Attribution string
+	Title       string
+	// Accessible description of the GIF, if the provider has one
+	AltText string
+	// URL of the GIF page on the provider website
+	PageURL string
+	// URL of the rendition chosen in the plugin configuration
+	URL string
+	// All the renditions of the GIF, by provider rendition name
+	Renditions map[string]Rendition
+	// URL to call when the GIF is posted, for the providers that give one with each GIF
+	ShareReportURL string
+}
+
+// Rendition is a version of a GIF with a given format, size or quality. Unknown values are set to 0.
+type Rendition struct {
+	URL string
+	// Dimensions in pixels
+	Width  int
+	Height int
+	// Size in bytes
+	Size int
+}
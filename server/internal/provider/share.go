@@ -0,0 +1,25 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// ShareReporter is implemented by the GIF providers that want to know which of their GIFs are posted,
+// ex: Tenor asks to register the shares to improve the ranking of its search results
+type ShareReporter interface {
+	// ReportShare tells the provider that a GIF it found was posted. The shares of the GIFs found by other providers are ignored.
+	ReportShare(ctx context.Context, share Share) *model.AppError
+}
+
+// Share is a GIF found by a search and posted by a user
+type Share struct {
+	// Provider that found the GIF, and ID of the GIF for this provider
+	Provider string
+	GifID    string
+	// Query that found the GIF
+	Query Query
+	// URL to call to report the share, for the providers that give one with each GIF
+	ReportURL string
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/test"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const testGiphyPingbackURL = "https://giphy-analytics.giphy.com/v2/pingback_simple?analytics_response_payload=abc&action_type=SENT"
+
+// stubShareReporter records the shares reported to it
+type stubShareReporter struct {
+	stubProvider
+	shares []Share
+}
+
+func (p *stubShareReporter) ReportShare(ctx context.Context, share Share) *model.AppError {
+	p.shares = append(p.shares, share)
+	return nil
+}
+
+func TestGiphyProviderReportShareShouldCallThePingbackURL(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{newServerResponseOK("")}}
+	gifProvider, _ := NewGiphyProvider(client, test.MockErrorGenerator(), testGiphyAPIKey, testGiphyLanguage, testGiphyRating, testGiphyRendition, testRootURL)
+
+	err := gifProvider.(ShareReporter).ReportShare(context.Background(), Share{Provider: ProviderGiphy, GifID: "gif42", ReportURL: testGiphyPingbackURL})
+
+	assert.Nil(t, err)
+	if assert.Len(t, client.requests, 1) {
+		assert.Equal(t, "giphy-analytics.giphy.com", client.requests[0].URL.Host)
+		assert.Equal(t, "/v2/pingback_simple", client.requests[0].URL.Path)
+		assert.Equal(t, "abc", client.requests[0].URL.Query().Get("analytics_response_payload"))
+		assert.NotEmpty(t, client.requests[0].URL.Query().Get("ts"))
+	}
+}
+
+func TestGiphyProviderReportShareShouldIgnoreOtherSharesAndRejectOtherHosts(t *testing.T) {
+	client := &mockHTTPClientSequence{}
+	gifProvider, _ := NewGiphyProvider(client, test.MockErrorGenerator(), testGiphyAPIKey, testGiphyLanguage, testGiphyRating, testGiphyRendition, testRootURL)
+	reporter := gifProvider.(ShareReporter)
+
+	assert.Nil(t, reporter.ReportShare(context.Background(), Share{Provider: ProviderTenor, GifID: "gif42", ReportURL: testGiphyPingbackURL}))
+	assert.Nil(t, reporter.ReportShare(context.Background(), Share{Provider: ProviderGiphy, GifID: "gif42"}))
+	assert.NotNil(t, reporter.ReportShare(context.Background(), Share{Provider: ProviderGiphy, GifID: "gif42", ReportURL: "https://evil.com/giphy.com"}))
+	assert.NotNil(t, reporter.ReportShare(context.Background(), Share{Provider: ProviderGiphy, GifID: "gif42", ReportURL: "http://giphy-analytics.giphy.com/v2/pingback_simple"}))
+	assert.Empty(t, client.requests)
+}
+
+func TestTenorProviderReportShareShouldRegisterTheShare(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{newServerResponseOK("{}")}}
+	gifProvider, _ := NewTenorProvider(client, test.MockErrorGenerator(), testTenorAPIKey, testTenorLanguage, testTenorRating, testTenorRendition)
+
+	err := gifProvider.(ShareReporter).ReportShare(context.Background(), Share{Provider: ProviderTenor, GifID: "gif42", Query: Query{Keywords: "cat"}})
+
+	assert.Nil(t, err)
+	if assert.Len(t, client.requests, 1) {
+		assert.Equal(t, "/v2/registershare", client.requests[0].URL.Path)
+		assert.Equal(t, testTenorAPIKey, client.requests[0].URL.Query().Get("key"))
+		assert.Equal(t, "gif42", client.requests[0].URL.Query().Get("id"))
+		assert.Equal(t, "cat", client.requests[0].URL.Query().Get("q"))
+	}
+}
+
+func TestTenorProviderReportShareShouldFailWhenBadStatus(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{newServerResponseKO(http.StatusBadRequest)}}
+	gifProvider, _ := NewTenorProvider(client, test.MockErrorGenerator(), testTenorAPIKey, testTenorLanguage, testTenorRating, testTenorRendition)
+
+	err := gifProvider.(ShareReporter).ReportShare(context.Background(), Share{Provider: ProviderTenor, GifID: "gif42"})
+
+	assert.NotNil(t, err)
+}
+
+func TestFederatedProviderReportShareShouldForwardTheShare(t *testing.T) {
+	reporter := &stubShareReporter{stubProvider: stubProvider{name: "tenor"}}
+	gifProvider := NewFederatedProvider([]GifProvider{&stubProvider{name: "giphy"}, reporter}, test.MockErrorGenerator(), time.Second)
+	share := Share{Provider: "tenor", GifID: "gif42", Query: Query{Keywords: "cat"}}
+
+	err := gifProvider.(ShareReporter).ReportShare(context.Background(), share)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Share{share}, reporter.shares)
+}
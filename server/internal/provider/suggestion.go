@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// SuggestionProvider is implemented by the GIF providers that can suggest search terms,
+// ex: to offer other searches when the keywords find no GIF
+type SuggestionProvider interface {
+	// GetSuggestions returns at most limit search terms that complete the keywords of the query or are related to them
+	GetSuggestions(ctx context.Context, query Query, limit int) ([]string, *model.AppError)
+}
+
+// appendSuggestions adds the terms that are not already suggested and are not the keywords, up to the limit
+func appendSuggestions(suggestions []string, keywords string, terms []string, limit int) []string {
+	for _, term := range terms {
+		if len(suggestions) >= limit {
+			break
+		}
+		term = strings.TrimSpace(term)
+		if term == "" || strings.EqualFold(term, strings.TrimSpace(keywords)) {
+			continue
+		}
+		known := false
+		for _, suggestion := range suggestions {
+			known = known || strings.EqualFold(suggestion, term)
+		}
+		if !known {
+			suggestions = append(suggestions, term)
+		}
+	}
+	return suggestions
+}
@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/test"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSuggestionProvider is a provider that suggests fixed terms
+type stubSuggestionProvider struct {
+	stubProvider
+	terms []string
+	err   *model.AppError
+}
+
+func (p *stubSuggestionProvider) GetSuggestions(ctx context.Context, query Query, limit int) ([]string, *model.AppError) {
+	return p.terms, p.err
+}
+
+func TestAppendSuggestionsShouldSkipDuplicatesAndKeywords(t *testing.T) {
+	suggestions := appendSuggestions([]string{"cat"}, "Kitty", []string{"CAT", "kitty", " ", "cute cat", "kitten", "cat nap"}, 3)
+
+	assert.Equal(t, []string{"cat", "cute cat", "kitten"}, suggestions)
+}
+
+func TestGiphyProviderGetSuggestionsShouldCompleteWithRelatedTerms(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{
+		newServerResponseOK(`{"data": [{"name": "happy cats"}]}`),
+		newServerResponseOK(`{"data": [{"name": "happy cats"}, {"name": "kitten"}, {"name": "joy"}]}`),
+	}}
+	gifProvider, _ := NewGiphyProvider(client, test.MockErrorGenerator(), testGiphyAPIKey, testGiphyLanguage, testGiphyRating, testGiphyRendition, testRootURL)
+
+	suggestions, err := gifProvider.(SuggestionProvider).GetSuggestions(context.Background(), Query{Keywords: "happy cat"}, 2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"happy cats", "kitten"}, suggestions)
+	if assert.Len(t, client.requests, 2) {
+		assert.Equal(t, "/v1/gifs/search/tags", client.requests[0].URL.Path)
+		assert.Equal(t, "happy cat", client.requests[0].URL.Query().Get("q"))
+		assert.Equal(t, testGiphyAPIKey, client.requests[0].URL.Query().Get("api_key"))
+		assert.Equal(t, "/v1/tags/related/happy cat", client.requests[1].URL.Path)
+	}
+}
+
+func TestGiphyProviderGetSuggestionsShouldFailWhenBadStatus(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{newServerResponseKO(http.StatusForbidden)}}
+	gifProvider, _ := NewGiphyProvider(client, test.MockErrorGenerator(), testGiphyAPIKey, testGiphyLanguage, testGiphyRating, testGiphyRendition, testRootURL)
+
+	suggestions, err := gifProvider.(SuggestionProvider).GetSuggestions(context.Background(), Query{Keywords: "cat"}, 2)
+
+	assert.NotNil(t, err)
+	assert.Nil(t, suggestions)
+}
+
+func TestTenorProviderGetSuggestionsShouldStopWhenTheLimitIsReached(t *testing.T) {
+	client := &mockHTTPClientSequence{responses: []*http.Response{
+		newServerResponseOK(`{"results": ["cats", "catnip"]}`),
+	}}
+	gifProvider, _ := NewTenorProvider(client, test.MockErrorGenerator(), testTenorAPIKey, testTenorLanguage, testTenorRating, testTenorRendition)
+
+	suggestions, err := gifProvider.(SuggestionProvider).GetSuggestions(context.Background(), Query{Keywords: "cat", Locale: "fr"}, 2)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cats", "catnip"}, suggestions)
+	if assert.Len(t, client.requests, 1) {
+		assert.Equal(t, "/v2/autocomplete", client.requests[0].URL.Path)
+		assert.Equal(t, testTenorAPIKey, client.requests[0].URL.Query().Get("key"))
+		assert.Equal(t, "2", client.requests[0].URL.Query().Get("limit"))
+	}
+}
+
+func TestFederatedProviderGetSuggestionsShouldMergeTheSuggestions(t *testing.T) {
+	gifProvider := NewFederatedProvider([]GifProvider{
+		&stubSuggestionProvider{stubProvider: stubProvider{name: "giphy"}, terms: []string{"cats", "kitten"}},
+		&stubProvider{name: "gfycat"},
+		&stubSuggestionProvider{stubProvider: stubProvider{name: "tenor"}, err: test.MockErrorGenerator().FromMessage("Tenor is down")},
+		&stubSuggestionProvider{stubProvider: stubProvider{name: "other"}, terms: []string{"Kitten", "catnip", "meow"}},
+	}, test.MockErrorGenerator(), time.Second)
+
+	suggestions, err := gifProvider.(SuggestionProvider).GetSuggestions(context.Background(), Query{Keywords: "cat"}, 3)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cats", "kitten", "catnip"}, suggestions)
+}
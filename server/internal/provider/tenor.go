@@ -1,9 +1,12 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	pluginError "github.com/moussetc/mattermost-plugin-giphy/server/internal/error"
 
@@ -47,12 +50,27 @@ const (
 )
 
 type tenorSearchResult struct {
-	Next    string `json:"next"`
-	Results []struct {
-		Media map[string]struct {
-			URL string `json:"url"`
-		} `json:"media_formats"`
-	} `json:"results"`
+	Next    string     `json:"next"`
+	Results []tenorGif `json:"results"`
+}
+
+type tenorGif struct {
+	ID          string                `json:"id"`
+	Title       string                `json:"title"`
+	Description string                `json:"content_description"`
+	ItemURL     string                `json:"itemurl"`
+	Media       map[string]tenorMedia `json:"media_formats"`
+}
+
+type tenorMedia struct {
+	URL  string `json:"url"`
+	Dims []int  `json:"dims"`
+	Size int    `json:"size"`
+}
+
+// tenorTermsResult is the response of the autocomplete and search suggestions endpoints
+type tenorTermsResult struct {
+	Results []string `json:"results"`
 }
 
 type tenorSearchError struct {
@@ -64,33 +82,33 @@ func (p *tenor) GetAttributionMessage() string {
 	return "Via Tenor"
 }
 
-// Return the URL of a GIF that matches the query, or an empty string if no GIF matches the query, or an error if the search failed
-func (p *tenor) GetGifURL(request string, cursor *string) (string, *model.AppError) {
-	req, err := http.NewRequest("GET", baseURLTenor+"/search", nil)
+// Return the GIF that matches the query, or nil if no GIF matches the query, or an error if the search failed
+func (p *tenor) GetGif(ctx context.Context, query Query, cursor *string) (*GifResult, *model.AppError) {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURLTenor+"/search", nil)
 	if err != nil {
-		return "", p.errorGenerator.FromError("Could not generate URL", err)
+		return nil, p.errorGenerator.FromError("Could not generate URL", err)
 	}
 
 	q := req.URL.Query()
 
 	q.Add("key", p.apiKey)
-	q.Add("q", request)
+	q.Add("q", query.Keywords)
 	q.Add("ar_range", "all")
 	if cursor != nil && *cursor != "" {
 		q.Add("pos", *cursor)
 	}
 	q.Add("limit", "1")
 	q.Add("contentfilter", p.rating)
-	q.Add("media_filter", p.rendition)
-	if len(p.language) > 0 {
-		q.Add("locale", p.language)
+	q.Add("media_filter", strings.Join(p.renditionPreferences(), ","))
+	if language := toTenorLocale(query.Locale, p.language); len(language) > 0 {
+		q.Add("locale", language)
 	}
 
 	req.URL.RawQuery = q.Encode()
 
 	r, err := p.httpClient.Do(req)
 	if err != nil {
-		return "", p.errorGenerator.FromError("Error calling the Tenor API", err)
+		return nil, p.errorGenerator.FromError("Error calling the Tenor API", err)
 	}
 	if r != nil && r.Body != nil {
 		defer r.Body.Close()
@@ -106,29 +124,178 @@ func (p *tenor) GetGifURL(request string, cursor *string) (string, *model.AppErr
 			}
 		}
 		errorDetails += ")"
-		return "", p.errorGenerator.FromMessage(errorDetails)
+		return nil, p.errorGenerator.FromMessage(errorDetails)
 	}
 
 	var response tenorSearchResult
 	if r.Body == nil {
-		return "", p.errorGenerator.FromMessage("Tenor search response body is empty")
+		return nil, p.errorGenerator.FromMessage("Tenor search response body is empty")
 	}
 
 	decoder := json.NewDecoder(r.Body)
 	if err = decoder.Decode(&response); err != nil {
-		return "", p.errorGenerator.FromError("Could not parse Tenor search response body", err)
+		return nil, p.errorGenerator.FromError("Could not parse Tenor search response body", err)
 	}
 
 	if len(response.Results) < 1 {
-		return "", nil
+		return nil, nil
 	}
-	url := response.Results[0].Media[p.rendition].URL
+	gif := response.Results[0].toGifResult()
+	gif.URL = selectRendition(gif.Renditions, p.renditionPreferences(), query.Budget)
 
-	if len(url) < 1 {
-		return "", p.errorGenerator.FromMessage("No URL found for display style \"" + p.rendition + "\" in the response")
+	if len(gif.URL) < 1 {
+		return nil, p.errorGenerator.FromMessage("No URL found for display style \"" + p.rendition + "\" in the response")
 	}
 	*cursor = response.Next
-	return url, nil
+	return gif, nil
+}
+
+// GetSuggestions returns the search terms that complete the keywords, followed by the terms related to them
+func (p *tenor) GetSuggestions(ctx context.Context, query Query, limit int) ([]string, *model.AppError) {
+	suggestions := []string{}
+	for _, endpoint := range []string{baseURLTenor + "/autocomplete", baseURLTenor + "/search_suggestions"} {
+		if len(suggestions) >= limit {
+			break
+		}
+		terms, appErr := p.getTerms(ctx, endpoint, query, limit)
+		if appErr != nil {
+			return nil, appErr
+		}
+		suggestions = appendSuggestions(suggestions, query.Keywords, terms, limit)
+	}
+	return suggestions, nil
+}
+
+// getTerms returns the search terms returned by a Tenor endpoint
+func (p *tenor) getTerms(ctx context.Context, endpoint string, query Query, limit int) ([]string, *model.AppError) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Could not generate URL", err)
+	}
+	q := req.URL.Query()
+	q.Add("key", p.apiKey)
+	q.Add("q", query.Keywords)
+	q.Add("limit", strconv.Itoa(limit))
+	if language := toTenorLocale(query.Locale, p.language); len(language) > 0 {
+		q.Add("locale", language)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	r, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Error calling the Tenor API", err)
+	}
+	if r != nil && r.Body != nil {
+		defer r.Body.Close()
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the Tenor API (HTTP Status: %v)", r.Status))
+	}
+	if r.Body == nil {
+		return nil, p.errorGenerator.FromMessage("Tenor suggestions response body is empty")
+	}
+	var response tenorTermsResult
+	if err = json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, p.errorGenerator.FromError("Could not parse Tenor suggestions response body", err)
+	}
+	return response.Results, nil
+}
+
+// GetGifByPage returns the GIF of a Tenor page, with the configured rendition
+func (p *tenor) GetGifByPage(ctx context.Context, page GifPage, query Query) (*GifResult, *model.AppError) {
+	if page.Provider != ProviderTenor {
+		return nil, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURLTenor+"/posts", nil)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Could not generate URL", err)
+	}
+	q := req.URL.Query()
+	q.Add("key", p.apiKey)
+	q.Add("ids", page.GifID)
+	q.Add("media_filter", strings.Join(p.renditionPreferences(), ","))
+	req.URL.RawQuery = q.Encode()
+
+	r, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, p.errorGenerator.FromError("Error calling the Tenor API", err)
+	}
+	if r != nil && r.Body != nil {
+		defer r.Body.Close()
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, p.errorGenerator.FromMessage(fmt.Sprintf("Error calling the Tenor API (HTTP Status: %v)", r.Status))
+	}
+	if r.Body == nil {
+		return nil, p.errorGenerator.FromMessage("Tenor posts response body is empty")
+	}
+	var response tenorSearchResult
+	if err = json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, p.errorGenerator.FromError("Could not parse Tenor posts response body", err)
+	}
+	if len(response.Results) < 1 {
+		return nil, nil
+	}
+	gif := response.Results[0].toGifResult()
+	gif.URL = selectRendition(gif.Renditions, p.renditionPreferences(), query.Budget)
+	if len(gif.URL) < 1 {
+		return nil, p.errorGenerator.FromMessage("No URL found for display style \"" + p.rendition + "\" in the response")
+	}
+	return gif, nil
+}
+
+// ReportShare registers the share of a posted Tenor GIF, which improves its ranking in the search results
+func (p *tenor) ReportShare(ctx context.Context, share Share) *model.AppError {
+	if share.Provider != ProviderTenor || share.GifID == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURLTenor+"/registershare", nil)
+	if err != nil {
+		return p.errorGenerator.FromError("Could not generate URL", err)
+	}
+	q := req.URL.Query()
+	q.Add("key", p.apiKey)
+	q.Add("id", share.GifID)
+	q.Add("q", share.Query.Keywords)
+	if language := toTenorLocale(share.Query.Locale, p.language); len(language) > 0 {
+		q.Add("locale", language)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	r, err := p.httpClient.Do(req)
+	if err != nil {
+		return p.errorGenerator.FromError("Error calling the Tenor API", err)
+	}
+	if r != nil && r.Body != nil {
+		defer r.Body.Close()
+	}
+	if r.StatusCode != http.StatusOK {
+		return p.errorGenerator.FromMessage(fmt.Sprintf("Error registering the Tenor share (HTTP Status: %v)", r.Status))
+	}
+	return nil
+}
+
+func (g *tenorGif) toGifResult() *GifResult {
+	result := &GifResult{
+		ID:         g.ID,
+		Provider:   ProviderTenor,
+		Title:      g.Title,
+		AltText:    g.Description,
+		PageURL:    g.ItemURL,
+		Renditions: map[string]Rendition{},
+	}
+	for name, media := range g.Media {
+		if media.URL == "" {
+			continue
+		}
+		rendition := Rendition{URL: media.URL, Size: media.Size}
+		if len(media.Dims) == 2 {
+			rendition.Width = media.Dims[0]
+			rendition.Height = media.Dims[1]
+		}
+		result.Renditions[name] = rendition
+	}
+	return result
 }
 
 func convertRatingToContentFilter(rating string) string {
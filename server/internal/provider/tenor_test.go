@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -226,71 +227,91 @@ func generateTenorProviderForTest(mockHTTPResponse *http.Response) *tenor {
 	return provider.(*tenor)
 }
 
-func TestTenorProviderGetGifURLShouldReturnUrlWhenSearchSucceeds(t *testing.T) {
+func TestTenorProviderGetGifShouldReturnUrlWhenSearchSucceeds(t *testing.T) {
 	p := generateTenorProviderForTest(newServerResponseOK(defaultTenorResponseBody))
 	p.rendition = "tinygif"
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
-	assert.NotEmpty(t, url)
-	assert.Equal(t, url, "https://fakeurl/tinygif")
+	assert.NotNil(t, gif)
+	assert.Equal(t, gif.URL, "https://fakeurl/tinygif")
 }
 
-func TestTenorProviderGetGifURLShouldFailIfSearchBodyIsEmpty(t *testing.T) {
+func TestTenorProviderGetGifShouldReturnMetadataAndRenditions(t *testing.T) {
+	p := generateTenorProviderForTest(newServerResponseOK(`{"results": [{
+		"id": "4242", "title": "Cat", "content_description": "A cat jumps", "itemurl": "https://tenor.com/view/cat-4242",
+		"media_formats": {"tinygif": {"url": "https://fakeurl/tinygif", "dims": [220, 110], "size": 42}}
+	}]}`))
+	p.rendition = "tinygif"
+	cursor := ""
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
+	assert.Nil(t, err)
+	assert.Equal(t, &GifResult{
+		ID:         "4242",
+		Provider:   ProviderTenor,
+		Title:      "Cat",
+		AltText:    "A cat jumps",
+		PageURL:    "https://tenor.com/view/cat-4242",
+		URL:        "https://fakeurl/tinygif",
+		Renditions: map[string]Rendition{"tinygif": {URL: "https://fakeurl/tinygif", Width: 220, Height: 110, Size: 42}},
+	}, gif)
+}
+
+func TestTenorProviderGetGifShouldFailIfSearchBodyIsEmpty(t *testing.T) {
 	p := generateTenorProviderForTest(newServerResponseOK(""))
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "empty")
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestTenorProviderGetGifURLShouldFailWhenParseError(t *testing.T) {
+func TestTenorProviderGetGifShouldFailWhenParseError(t *testing.T) {
 	p := generateTenorProviderForTest(newServerResponseOK("This is not a valid JSON response"))
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestTenorProviderGetGifURLShouldReturnEmptyUrlWhenSearchReturnNoResult(t *testing.T) {
+func TestTenorProviderGetGifShouldReturnEmptyUrlWhenSearchReturnNoResult(t *testing.T) {
 	p := generateTenorProviderForTest(newServerResponseOK("{ \"weburl\": \"https://fakeurl/casdfsdfsdfsdfsdfst-gifs\", \"results\": [], \"next\": \"0\" }"))
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestTenorProviderGetGifURLShouldFailWhenNoURLForRendition(t *testing.T) {
+func TestTenorProviderGetGifShouldFailWhenNoURLForRendition(t *testing.T) {
 	p := generateTenorProviderForTest(newServerResponseOK(defaultTenorResponseBody))
 	p.rendition = "NotExistingDisplayStyle"
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "No URL found for display style")
 	assert.Contains(t, err.Error(), p.rendition)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestTenorProviderGetGifURLShouldFailWhenSearchBadStatusWithoutMessage(t *testing.T) {
+func TestTenorProviderGetGifShouldFailWhenSearchBadStatusWithoutMessage(t *testing.T) {
 	serverResponse := newServerResponseKO(400)
 	p := generateTenorProviderForTest(serverResponse)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), serverResponse.Status)
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
-func TestTenorProviderGetGifURLShouldFailWhenSearchBadStatusWithMessage(t *testing.T) {
+func TestTenorProviderGetGifShouldFailWhenSearchBadStatusWithMessage(t *testing.T) {
 	serverResponse := newServerResponseKOWithBody(429, "{ \"error\": \"Please use a registered API Key\" }")
 	p := generateTenorProviderForTest(serverResponse)
 	cursor := ""
-	url, err := p.GetGifURL("cat", &cursor)
+	gif, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), serverResponse.Status)
 	assert.Contains(t, err.Error(), "Please use a registered API Key")
-	assert.Empty(t, url)
+	assert.Nil(t, gif)
 }
 
 func generatTenorProviderForURLBuildingTests() (*tenor, *MockHTTPClient, string) {
@@ -300,37 +321,48 @@ func generatTenorProviderForURLBuildingTests() (*tenor, *MockHTTPClient, string)
 	return provider.(*tenor), client, ""
 }
 
-func TestTenorProviderGetGifURLShouldApplyRatingFilterWhenSet(t *testing.T) {
+func TestTenorProviderGetGifShouldApplyRatingFilterWhenSet(t *testing.T) {
 	p, client, cursor := generatTenorProviderForURLBuildingTests()
 	client.testRequestFunc = func(req *http.Request) bool {
 		assert.Contains(t, req.URL.RawQuery, "contentfilter=off")
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 }
 
-func TestTenorProviderGetGifURLShouldApplyLanguageFilterWhenUnset(t *testing.T) {
+func TestTenorProviderGetGifShouldApplyLanguageFilterWhenUnset(t *testing.T) {
 	p, client, cursor := generatTenorProviderForURLBuildingTests()
 	p.language = ""
 	client.testRequestFunc = func(req *http.Request) bool {
 		assert.NotContains(t, req.URL.RawQuery, "locale")
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 }
 
-func TestTenorProviderGetGifURLShouldApplyLanguageFilterWhenSet(t *testing.T) {
+func TestTenorProviderGetGifShouldApplyLanguageFilterWhenSet(t *testing.T) {
 	p, client, cursor := generatTenorProviderForURLBuildingTests()
 	p.language = "Moldovalaque"
 	client.testRequestFunc = func(req *http.Request) bool {
 		assert.Contains(t, req.URL.RawQuery, "locale="+p.language)
 		return true
 	}
-	_, err := p.GetGifURL("cat", &cursor)
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat"}, &cursor)
+	assert.Nil(t, err)
+	assert.True(t, client.lastRequestPassTest)
+}
+
+func TestTenorProviderGetGifShouldUseUserLocaleWhenSupported(t *testing.T) {
+	p, client, cursor := generatTenorProviderForURLBuildingTests()
+	client.testRequestFunc = func(req *http.Request) bool {
+		assert.Contains(t, req.URL.RawQuery, "locale=pt_BR")
+		return true
+	}
+	_, err := p.GetGif(context.Background(), Query{Keywords: "cat", Locale: "pt-BR"}, &cursor)
 	assert.Nil(t, err)
 	assert.True(t, client.lastRequestPassTest)
 }
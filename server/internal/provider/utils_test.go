@@ -50,3 +50,21 @@ func newServerResponseKOWithBody(statusCode int, body string) *http.Response {
 		Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
 	}
 }
+
+// mockHTTPClientSequence returns the responses in order, and records the requests
+type mockHTTPClientSequence struct {
+	responses []*http.Response
+	requests  []*http.Request
+}
+
+func (c *mockHTTPClientSequence) Do(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req)
+	response := c.responses[0]
+	c.responses = c.responses[1:]
+	return response, nil
+}
+
+func (c *mockHTTPClientSequence) Get(s string) (*http.Response, error) {
+	req, _ := http.NewRequest(http.MethodGet, s, nil)
+	return c.Do(req)
+}
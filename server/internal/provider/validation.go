@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"strings"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+)
+
+// knownRenditions lists the renditions returned by the API of each provider
+var knownRenditions = map[string][]string{
+	ProviderGiphy: {
+		"fixed_height", "fixed_height_still", "fixed_height_downsampled", "fixed_height_small", "fixed_height_small_still",
+		"fixed_width", "fixed_width_still", "fixed_width_downsampled", "fixed_width_small", "fixed_width_small_still",
+		"downsized", "downsized_still", "downsized_large", "downsized_medium", "downsized_small",
+		"original", "original_still", "original_mp4", "looping", "preview", "preview_gif", "preview_webp", "480w_still", "hd",
+	},
+	ProviderTenor: {
+		"gif", "mediumgif", "tinygif", "nanogif", "gifpreview", "tinygifpreview", "nanogifpreview",
+		"mp4", "loopedmp4", "tinymp4", "nanomp4", "webm", "tinywebm", "nanowebm",
+	},
+	ProviderGfycat: {
+		"gifUrl", "gif100px", "max1mbGif", "max2mbGif", "max5mbGif", "posterUrl", "thumb100PosterUrl",
+		"mobilePosterUrl", "miniPosterUrl", "mp4Url", "webmUrl", "webpUrl", "mobileUrl", "miniUrl",
+	},
+}
+
+// getConfiguredRendition returns the display style configured for the provider
+func getConfiguredRendition(configuration pluginConf.Configuration, provider string) (setting, rendition string) {
+	switch provider {
+	case ProviderGiphy:
+		return "GIPHY display style", configuration.Rendition
+	case ProviderTenor:
+		return "Tenor display style", configuration.RenditionTenor
+	default:
+		return "Gfycat display style", configuration.RenditionGfycat
+	}
+}
+
+func isKnownRendition(provider, rendition string) bool {
+	for _, known := range knownRenditions[provider] {
+		if rendition == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRenditions checks that the configured display styles and the fallback ones exist for the configured provider
+// and the federated ones. The fallbacks are shared by all the providers, so unknown fallbacks are only warnings.
+func ValidateRenditions(configuration pluginConf.Configuration) []pluginConf.ValidationIssue {
+	issues := []pluginConf.ValidationIssue{}
+	providers := configuration.GetFederatedProviders()
+	if len(providers) == 0 {
+		providers = []string{configuration.Provider}
+	}
+	for _, provider := range providers {
+		issues = append(issues, validateProviderRenditions(configuration, provider)...)
+	}
+	return issues
+}
+
+func validateProviderRenditions(configuration pluginConf.Configuration, provider string) []pluginConf.ValidationIssue {
+	issues := []pluginConf.ValidationIssue{}
+	if _, ok := knownRenditions[provider]; !ok {
+		return issues
+	}
+	setting, rendition := getConfiguredRendition(configuration, provider)
+	if rendition == "" {
+		issues = append(issues, pluginConf.ValidationIssue{Setting: setting, Message: "the display style must be configured", Blocking: true})
+	} else if !isKnownRendition(provider, rendition) {
+		issues = append(issues, pluginConf.ValidationIssue{
+			Setting:  setting,
+			Message:  "unknown display style '" + rendition + "' for " + provider + ", use one of: " + strings.Join(knownRenditions[provider], ", "),
+			Blocking: true,
+		})
+	}
+	for _, fallback := range parseRenditionPreferences(configuration.RenditionFallbacks) {
+		if !isKnownRendition(provider, fallback) {
+			issues = append(issues, pluginConf.ValidationIssue{
+				Setting: "Fallback display styles",
+				Message: "unknown display style '" + fallback + "' for " + provider + ", it will be ignored",
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"testing"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRenditions(t *testing.T) {
+	testCases := []struct {
+		testLabel        string
+		configuration    pluginConf.Configuration
+		expectedIssues   int
+		expectedBlocking bool
+	}{
+		{testLabel: "Valid GIPHY rendition", configuration: pluginConf.Configuration{Provider: ProviderGiphy, Rendition: "fixed_height", RenditionTenor: "unknown"}},
+		{testLabel: "Valid Tenor rendition and fallbacks", configuration: pluginConf.Configuration{Provider: ProviderTenor, RenditionTenor: "mediumgif", RenditionFallbacks: "tinygif, nanogif"}},
+		{testLabel: "Valid Gfycat rendition", configuration: pluginConf.Configuration{Provider: ProviderGfycat, RenditionGfycat: "max2mbGif"}},
+		{testLabel: "Empty rendition", configuration: pluginConf.Configuration{Provider: ProviderGiphy}, expectedIssues: 1, expectedBlocking: true},
+		{testLabel: "Rendition of another provider", configuration: pluginConf.Configuration{Provider: ProviderTenor, RenditionTenor: "fixed_height"}, expectedIssues: 1, expectedBlocking: true},
+		{testLabel: "Unknown fallbacks", configuration: pluginConf.Configuration{Provider: ProviderGiphy, Rendition: "fixed_height", RenditionFallbacks: "downsized,tinygif,mediumgif"}, expectedIssues: 2},
+		{testLabel: "Unknown provider", configuration: pluginConf.Configuration{Provider: "imgur"}},
+	}
+	for _, testCase := range testCases {
+		issues := ValidateRenditions(testCase.configuration)
+		assert.Len(t, issues, testCase.expectedIssues, testCase.testLabel)
+		assert.Equal(t, testCase.expectedBlocking, pluginConf.HasBlockingIssue(issues), testCase.testLabel)
+	}
+}
@@ -0,0 +1,147 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Spec is a parsed cron-like specification made of five fields:
+// minute (0-59), hour (0-23), day of month (1-31), month (1-12) and day of week (0-7, 0 and 7 being Sunday).
+// Each field accepts "*", a value, a range ("1-5"), a step ("*/15", "0-30/10") or a comma-separated list of those.
+type Spec struct {
+	raw      string
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+	// As in cron, when both the day of month and day of week are restricted, a time matches if either matches
+	anyDay     bool
+	anyWeekday bool
+}
+
+// FieldCount is the number of fields of a specification
+const FieldCount = 5
+
+// maxSearchDays bounds the search of the next run, so that impossible specifications (ex: 31st of February) end
+const maxSearchDays = 366 * 5
+
+// Parse reads a cron-like specification such as "0 9 * * 1-5" (every weekday at 9:00)
+func Parse(spec string) (*Spec, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != FieldCount {
+		return nil, fmt.Errorf("the schedule must have %d fields (minute hour day-of-month month day-of-week), found %d", FieldCount, len(fields))
+	}
+	s := &Spec{raw: strings.Join(fields, " ")}
+	var err error
+	if s.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, errors.Wrap(err, "invalid minute")
+	}
+	if s.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, errors.Wrap(err, "invalid hour")
+	}
+	if s.days, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, errors.Wrap(err, "invalid day of month")
+	}
+	if s.months, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, errors.Wrap(err, "invalid month")
+	}
+	if s.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, errors.Wrap(err, "invalid day of week")
+	}
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+	s.anyDay = strings.HasPrefix(fields[2], "*")
+	s.anyWeekday = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+func parseField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in \"%s\"", part)
+			}
+			rangePart = part[:i]
+		}
+		start, end := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value \"%s\"", part)
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value \"%s\"", part)
+				}
+			} else if step > 1 {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("\"%s\" is out of the %d-%d range", part, min, max)
+		}
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (s *Spec) matchDay(t time.Time) bool {
+	dayMatches := s.days[t.Day()]
+	weekdayMatches := s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekdayMatches
+	case s.anyWeekday:
+		return dayMatches
+	default:
+		return dayMatches || weekdayMatches
+	}
+}
+
+// Next returns the first time strictly after the given time that matches the specification,
+// or the zero time if none can be found
+func (s *Spec) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(0, 0, maxSearchDays)
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] || !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// RunsPerHour returns the maximum number of times the specification matches within an hour
+func (s *Spec) RunsPerHour() int {
+	return len(s.minutes)
+}
+
+// String returns the normalized specification
+func (s *Spec) String() string {
+	return s.raw
+}
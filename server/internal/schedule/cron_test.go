@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		spec          string
+		expectedError bool
+	}{
+		{spec: "", expectedError: true},
+		{spec: "0 9 * *", expectedError: true},
+		{spec: "0 9 * * * *", expectedError: true},
+		{spec: "60 9 * * *", expectedError: true},
+		{spec: "0 24 * * *", expectedError: true},
+		{spec: "0 9 0 * *", expectedError: true},
+		{spec: "0 9 * 13 *", expectedError: true},
+		{spec: "0 9 * * 8", expectedError: true},
+		{spec: "0 9 * * 5-1", expectedError: true},
+		{spec: "*/0 9 * * *", expectedError: true},
+		{spec: "a 9 * * *", expectedError: true},
+		{spec: "0 9 * * *", expectedError: false},
+		{spec: "0 9 * * 1-5", expectedError: false},
+		{spec: "*/15 8-18/2 1,15 * 0,7", expectedError: false},
+		{spec: "  30   9 * *   mon ", expectedError: true},
+	}
+	for _, testCase := range testCases {
+		spec, err := Parse(testCase.spec)
+		if testCase.expectedError {
+			assert.NotNil(t, err, "Testing: "+testCase.spec)
+			assert.Nil(t, spec, "Testing: "+testCase.spec)
+		} else {
+			assert.Nil(t, err, "Testing: "+testCase.spec)
+			assert.NotNil(t, spec, "Testing: "+testCase.spec)
+		}
+	}
+}
+
+func TestRunsPerHour(t *testing.T) {
+	for spec, expected := range map[string]int{
+		"0 9 * * *":    1,
+		"30 * * * *":   1,
+		"0,30 9 * * *": 2,
+		"*/15 * * * *": 4,
+		"* * * * *":    60,
+	} {
+		parsed, err := Parse(spec)
+		assert.Nil(t, err, spec)
+		assert.Equal(t, expected, parsed.RunsPerHour(), spec)
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Wednesday
+	now := time.Date(2022, time.June, 15, 10, 42, 30, 0, time.UTC)
+	testCases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{spec: "* * * * *", expected: time.Date(2022, time.June, 15, 10, 43, 0, 0, time.UTC)},
+		{spec: "0 9 * * *", expected: time.Date(2022, time.June, 16, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 12 * * *", expected: time.Date(2022, time.June, 15, 12, 0, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", expected: time.Date(2022, time.June, 15, 10, 45, 0, 0, time.UTC)},
+		{spec: "0 9 * * 1-5", expected: time.Date(2022, time.June, 16, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 9 * * 0", expected: time.Date(2022, time.June, 19, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 9 * * 7", expected: time.Date(2022, time.June, 19, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 9 1 * *", expected: time.Date(2022, time.July, 1, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 9 1 * 5", expected: time.Date(2022, time.June, 17, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 1 *", expected: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 2 *", expected: time.Time{}},
+	}
+	for _, testCase := range testCases {
+		spec, err := Parse(testCase.spec)
+		assert.Nil(t, err, "Testing: "+testCase.spec)
+		assert.Equal(t, testCase.expected, spec.Next(now), "Testing: "+testCase.spec)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+)
+
+// Contains what's related to inserting the user text (keywords, captions) in the Markdown of the GIF posts
+
+var (
+	// Escapes the characters that have a meaning in Markdown, so that they are displayed as is
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "~", `\~`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+		"!", `\!`, "#", `\#`, "|", `\|`, "<", `\<`, ">", `\>`,
+	)
+	// Percent-encodes the characters that could end the Markdown link or image of a GIF URL, or start a mention
+	markdownURLEscaper = strings.NewReplacer(
+		" ", "%20", "\t", "%09", "\n", "%0A", "(", "%28", ")", "%29", "[", "%5B", "]", "%5D", "<", "%3C", ">", "%3E",
+		"!", "%21", "@", "%40", "*", "%2A",
+	)
+	// Markdown images and links, whose text is kept when the Markdown is stripped
+	markdownLinkRegexp = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	// @-mentions of users, groups, or the whole channel (@channel, @all, @here). Whatever precedes the '@', since
+	// Mattermost also finds mentions after the '.', '-' and '_' that start or split a word.
+	mentionRegexp = regexp.MustCompile(`@([\p{L}\p{N}_])`)
+)
+
+// escapeMarkdown returns the text with its Markdown displayed as is and its mentions neutralized,
+// for the keywords of the GIFs that are always inserted as plain text
+func escapeMarkdown(text string) string {
+	return neutralizeMentions(markdownEscaper.Replace(text))
+}
+
+// escapeMarkdownURL returns the URL of a GIF that can be inserted as is in the Markdown of a post
+func escapeMarkdownURL(url string) string {
+	return markdownURLEscaper.Replace(url)
+}
+
+// neutralizeMentions inserts a zero-width space after the '@' of the mentions, so that they do not notify anyone
+func neutralizeMentions(text string) string {
+	return mentionRegexp.ReplaceAllString(text, "@\u200b${1}")
+}
+
+// sanitizeCaption returns the caption allowed by the caption policy of the configuration,
+// the mentions being neutralized if the policy is not set
+func sanitizeCaption(captionPolicy, caption string) string {
+	switch captionPolicy {
+	case pluginConf.CaptionPolicyAllow:
+		return caption
+	case pluginConf.CaptionPolicyStrip:
+		return escapeMarkdown(markdownLinkRegexp.ReplaceAllString(caption, "$1"))
+	default:
+		return neutralizeMentions(caption)
+	}
+}
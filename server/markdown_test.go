@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeMarkdown(t *testing.T) {
+	assert.Equal(t, "happy kitty", escapeMarkdown("happy kitty"))
+	assert.Equal(t, `\!\[x\]\(https://evil.com/x.png\) \*\*bold\*\* \_snake\_case\_ \#title`, escapeMarkdown("![x](https://evil.com/x.png) **bold** _snake_case_ #title"))
+	assert.Equal(t, "hi @\u200bchannel", escapeMarkdown("hi @channel"))
+}
+
+func TestNeutralizeMentions(t *testing.T) {
+	assert.Equal(t, "@\u200ball @\u200bhere, (@\u200bjohn.doe)", neutralizeMentions("@all @here, (@john.doe)"))
+	assert.Equal(t, "me@\u200bexample.com @ noon", neutralizeMentions("me@example.com @ noon"))
+	assert.Equal(t, ".@\u200bchannel", neutralizeMentions(".@channel"))
+	assert.Equal(t, "_@\u200bhere", neutralizeMentions("_@here"))
+	assert.Equal(t, "go-@\u200ball", neutralizeMentions("go-@all"))
+	assert.Equal(t, "x.@\u200bchannel", neutralizeMentions("x.@channel"))
+}
+
+func TestEscapeMarkdownURL(t *testing.T) {
+	assert.Equal(t, testGifURL, escapeMarkdownURL(testGifURL))
+	assert.Equal(t, "https://a.com/x.gif%29%40channel%21%5Bx%5D%28https://b.com/y%29", escapeMarkdownURL("https://a.com/x.gif)@channel![x](https://b.com/y)"))
+}
+
+func TestSanitizeCaption(t *testing.T) {
+	caption := "**Welcome** [home](https://x.com) @channel"
+	assert.Equal(t, caption, sanitizeCaption(pluginConf.CaptionPolicyAllow, caption))
+	assert.Equal(t, "**Welcome** [home](https://x.com) @\u200bchannel", sanitizeCaption(pluginConf.CaptionPolicyNoMentions, caption))
+	assert.Equal(t, sanitizeCaption(pluginConf.CaptionPolicyNoMentions, caption), sanitizeCaption("", caption))
+	assert.Equal(t, `\*\*Welcome\*\* home @`+"\u200b"+`channel`, sanitizeCaption(pluginConf.CaptionPolicyStrip, caption))
+	assert.Equal(t, "cat", sanitizeCaption(pluginConf.CaptionPolicyStrip, "![cat](https://evil.com/track.gif)"))
+}
+
+func TestGenerateGifCaptionShouldEscapeTheKeywords(t *testing.T) {
+	caption := generateGifCaption(pluginConf.DisplayModeEmbedded, pluginConf.AltTextModeKeywords, pluginConf.CaptionPolicyAllow, "cat](https://evil.com) @all", "", testGifURL, "", "test")
+
+	assert.Contains(t, caption, `**/gif [cat\]\(https://evil.com\) @`+"\u200b"+`all](`+testGifURL+`)**`)
+	assert.Contains(t, caption, `![GIF for 'cat\]\(https://evil.com\) @`+"\u200b"+`all'](`+testGifURL+`)`)
+	assert.NotContains(t, caption, "@all")
+}
+
+func TestGenerateGifCaptionShouldLeaveOutAnEmptyAttribution(t *testing.T) {
+	for _, displayMode := range []string{pluginConf.DisplayModeEmbedded, pluginConf.DisplayModeFullURL} {
+		caption := generateGifCaption(displayMode, "", "", testKeywords, "", testGifURL, "", "")
+
+		assert.NotContains(t, caption, "\n** \n", displayMode)
+		assert.False(t, strings.HasSuffix(caption, "\n"), displayMode)
+	}
+}
+
+func TestGenerateGifCaptionShouldEncodeTheGifURL(t *testing.T) {
+	gifURL := "https://a.com/x.gif)@channel![x](https://b.com/y)"
+	for _, displayMode := range []string{pluginConf.DisplayModeEmbedded, pluginConf.DisplayModeFullURL} {
+		caption := generateGifCaption(displayMode, pluginConf.AltTextModeKeywords, pluginConf.CaptionPolicyAllow, testKeywords, "", gifURL, "", "test")
+
+		assert.Contains(t, caption, "https://a.com/x.gif%29%40channel%21%5Bx%5D%28https://b.com/y%29", displayMode)
+		assert.NotContains(t, caption, "@channel", displayMode)
+	}
+}
+
+func TestHandleSendShouldApplyTheCaptionPolicy(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("DeleteEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	mockRecentGifs(api, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+	setMockConfiguration(&p, &pluginConf.Configuration{CaptionPolicy: pluginConf.CaptionPolicyNoMentions})
+	setMockGifProvider(&p, newMockGifProvider())
+	request := generateTestIntegrationRequest()
+	request.Caption = "Hey @channel, **look**"
+
+	(&defaultHTTPHandler{}).handleSend(&p, p.getSnapshot(), httptest.NewRecorder(), request)
+
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return strings.Contains(post.Message, "Hey @\u200bchannel, **look**") && !strings.Contains(post.Message, "@channel")
+	}))
+}
+
+// Keywords whose Markdown and mention must be neutralized in all the messages of the plugin
+const testMarkdownKeywords = "**boom** | @all"
+
+const testEscapedKeywords = `\*\*boom\*\* \| @` + "\u200b" + `all`
+
+func TestHandleNoGifFoundShouldEscapeTheKeywords(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil)
+
+	_, err := p.handleNoGifFound(context.Background(), p.getSnapshot(), provider.Query{Keywords: testMarkdownKeywords}, "", false, &model.CommandArgs{UserId: testUserID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	api.AssertCalled(t, "SendEphemeralPost", testUserID, mock.MatchedBy(func(post *model.Post) bool {
+		return strings.Contains(post.Message, testEscapedKeywords)
+	}))
+}
+
+func TestHandleSaveShouldEscapeTheKeywords(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", favoritesKeyPrefix+testUserID).Return(nil, nil)
+	api.On("KVCompareAndSet", favoritesKeyPrefix+testUserID, mock.Anything, mock.Anything).Return(true, nil)
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil)
+	request := generateTestIntegrationRequest()
+	request.Keywords = testMarkdownKeywords
+
+	(&defaultHTTPHandler{}).handleSave(p, p.getSnapshot(), httptest.NewRecorder(), request)
+
+	api.AssertCalled(t, "SendEphemeralPost", testUserID, mock.MatchedBy(func(post *model.Post) bool {
+		return strings.Contains(post.Message, testEscapedKeywords)
+	}))
+}
+
+func TestListSchedulesShouldEscapeTheKeywords(t *testing.T) {
+	api, p := initMockAPI()
+	mockScheduleIndex(api, "s1")
+	api.On("KVGet", scheduleKeyPrefix+"s1").Return(mockStoredSchedule(&gifSchedule{ID: "s1", ChannelID: testChannelID, Spec: "0 9 * * *", Keywords: testMarkdownKeywords}), nil)
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule list"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, testEscapedKeywords)
+}
+
+func TestGenerateInlineGifShouldEscapeTheKeywords(t *testing.T) {
+	_, p := initMockAPI()
+	setMockGifProvider(p, &mockGifProviderSequence{})
+
+	message := p.generateInlineGif(context.Background(), p.getSnapshot(), testMarkdownKeywords, "")
+
+	assert.Equal(t, "*(No GIFs found for '"+testEscapedKeywords+"')*", message)
+}
+
+func TestHandleShuffleShouldEscapeTheKeywordsWhenNoGifIsFound(t *testing.T) {
+	_, p := initMockAPI()
+	setMockGifProvider(p, &mockGifProvider{""})
+	var notified string
+	defaultNotifyUserOfError := notifyUserOfError
+	notifyUserOfError = func(api plugin.API, botId string, message string, err *model.AppError, request *model.PostActionIntegrationRequest) {
+		notified = message
+	}
+	t.Cleanup(func() { notifyUserOfError = defaultNotifyUserOfError })
+	request := generateTestIntegrationRequest()
+	request.Keywords = testMarkdownKeywords
+
+	(&defaultHTTPHandler{}).handleShuffle(p, p.getSnapshot(), httptest.NewRecorder(), request)
+
+	assert.Contains(t, notified, testEscapedKeywords)
+}
+
+func TestExecuteCommandAliasShouldEscapeTheInvalidParameters(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToTeam", testUserID, testTeamID, model.PermissionViewTeam).Return(true)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif alias add **boom**|@all " + testGifURL, UserId: testUserID, TeamId: testTeamID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, `Invalid alias name '\*\*boom\*\*\|@`+"\u200b"+`all'`)
+}
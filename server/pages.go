@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to posting the GIF of a GIPHY or Tenor page URL pasted as keywords
+
+// executeCommandGifFromPage posts the GIF of a GIF page of the provider, or previews it.
+// The preview cannot be shuffled since the page shows only one GIF.
+func (p *Plugin) executeCommandGifFromPage(ctx context.Context, snapshot *pluginSnapshot, page provider.GifPage, query provider.Query, caption string, withPreview bool, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	resolver, ok := snapshot.getSearchProvider(withPreview).(provider.PageResolver)
+	if !ok {
+		return ephemeralResponse("The configured GIF provider cannot read the GIF of a page URL, use keywords instead."), nil
+	}
+	gif, appErr := resolver.GetGifByPage(ctx, page, query)
+	if appErr != nil {
+		p.API.LogWarn("Error while trying to get the GIF of the page: " + appErr.Error())
+		return nil, appErr
+	}
+	if gif == nil {
+		return ephemeralResponse("No GIF found for this page: it does not exist anymore, or it is not from the configured GIF provider."), nil
+	}
+
+	keywords := page.Keywords
+	if keywords == "" {
+		keywords = gif.Title
+	}
+	pageGif := newSearchResultGif(gif, keywords)
+	if withPreview {
+		p.sendPreviewPost(snapshot, pageGif, caption, "", query.Locale, sourceSearch, []string{gifKey(pageGif.ID, pageGif.URL)}, args)
+		return &model.CommandResponse{}, nil
+	}
+	p.recordRecentGif(snapshot, args.UserId, pageGif)
+	p.recordChannelGif(snapshot, args.ChannelId, pageGif)
+	p.reportShare(snapshot, false, provider.Share{Provider: gif.Provider, GifID: gif.ID, Query: provider.Query{Keywords: keywords, Locale: query.Locale}, ReportURL: gif.ShareReportURL})
+	return generateGifCommandResponse(snapshot, pageGif, caption), nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testGiphyPageURL = "https://giphy.com/gifs/cat-happy-abc123"
+
+// mockPageResolver finds the GIF of the pages of one provider
+type mockPageResolver struct {
+	mockGifProvider
+	gif *provider.GifResult
+}
+
+func (m *mockPageResolver) GetGifByPage(ctx context.Context, page provider.GifPage, query provider.Query) (*provider.GifResult, *model.AppError) {
+	if page.Provider != m.gif.Provider || page.GifID != m.gif.ID {
+		return nil, nil
+	}
+	return m.gif, nil
+}
+
+func newMockPageResolver() *mockPageResolver {
+	return &mockPageResolver{gif: &provider.GifResult{ID: "abc123", Provider: provider.ProviderGiphy, URL: testGifURL, Attribution: "Via GIPHY"}}
+}
+
+func TestExecuteCommandShouldPostTheGifOfAPageURL(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockPageResolver())
+	mockRecentGifs(api, nil)
+	args := *testArgs
+	args.Command = "/gif " + testGiphyPageURL
+
+	response, err := p.ExecuteCommand(nil, &args)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, response) {
+		assert.Equal(t, model.CommandResponseTypeInChannel, response.ResponseType)
+		assert.Contains(t, response.Text, testGifURL)
+		assert.Contains(t, response.Text, "cat happy")
+		assert.Contains(t, response.Text, "Via GIPHY")
+		assert.NotContains(t, response.Text, testGiphyPageURL)
+	}
+}
+
+func TestExecuteCommandShouldPreviewTheGifOfAPageURL(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockPageResolver())
+	var preview *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		preview = args.Get(1).(*model.Post)
+	})
+	args := *testArgs
+	args.Command = "/gifs " + testGiphyPageURL
+
+	_, err := p.ExecuteCommand(nil, &args)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, preview) {
+		assert.Contains(t, preview.Message, testGifURL)
+	}
+}
+
+func TestExecuteCommandShouldExplainWhenThePageIsNotFound(t *testing.T) {
+	_, p := initMockAPI()
+	setMockGifProvider(p, newMockPageResolver())
+	args := *testArgs
+	args.Command = "/gif https://tenor.com/view/happy-cat-gif-12345678"
+
+	response, err := p.ExecuteCommand(nil, &args)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, response) {
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+		assert.True(t, strings.HasPrefix(response.Text, "No GIF found for this page"))
+	}
+}
+
+func TestExecuteCommandShouldExplainWhenTheProviderCannotReadPages(t *testing.T) {
+	_, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	args := *testArgs
+	args.Command = "/gif " + testGiphyPageURL
+
+	response, err := p.ExecuteCommand(nil, &args)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, response) {
+		assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	manifest "github.com/moussetc/mattermost-plugin-giphy"
-	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
 	pluginError "github.com/moussetc/mattermost-plugin-giphy/server/internal/error"
-	provider "github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
 
+	"github.com/mattermost/mattermost-plugin-api/cluster"
 	"github.com/mattermost/mattermost-server/v6/model"
 	"github.com/mattermost/mattermost-server/v6/plugin"
 
@@ -23,6 +24,19 @@ const (
 	contextGifURL   = "gifURL"
 	contextCursor   = "cursor"
 	contextRootID   = "rootId"
+	contextLanguage = "language"
+	contextSource   = "source"
+	// Description of the GIF from the provider, used as alternative text
+	contextDescription = "description"
+	// ID of the GIF at the provider
+	contextGifID = "gifId"
+	// GIFs already shown in a preview session
+	contextSeen = "seen"
+	// Provider that found the GIF, and its attribution message
+	contextProvider    = "provider"
+	contextAttribution = "attribution"
+	// URL given by the provider to report that the GIF was posted
+	contextShareReportURL = "shareReportUrl"
 )
 
 // Plugin is a Mattermost plugin that adds a /gif slash command
@@ -30,14 +44,20 @@ const (
 type Plugin struct {
 	plugin.MattermostPlugin
 
-	configurationLock sync.RWMutex
-	configuration     *pluginConf.Configuration
+	// Serializes the configuration changes
+	configurationLock sync.Mutex
+	// Current *pluginSnapshot, read without lock by the hooks and the HTTP handlers
+	snapshot atomic.Value
 
 	errorGenerator pluginError.PluginError
-	gifProvider    provider.GifProvider
 	httpHandler    pluginHTTPHandler
-	botID          string
 	rootURL        string
+	scheduleJob    *cluster.Job
+	// Triggers of the commands registered with the current configuration
+	registeredTriggers []string
+	// Shuffles in progress, by preview post
+	previewSearches previewSearches
+	prefetchedGifs  prefetchedGifs
 }
 
 // OnActivate register the plugin commands
@@ -47,36 +67,66 @@ func (p *Plugin) OnActivate() error {
 		rootURL = strings.TrimSuffix(*siteURL, "/")
 	}
 	p.rootURL = fmt.Sprintf("%s/plugins/%s", rootURL, manifest.Manifest.Id)
+	// Also registers the commands
 	if err := p.OnConfigurationChange(); err != nil {
 		return errors.Wrap(err, "Could not load plugin configuration")
 	}
 	p.httpHandler = &defaultHTTPHandler{}
-	return p.RegisterCommands()
+	return p.startScheduleJob()
+}
+
+// OnDeactivate stops the background jobs
+func (p *Plugin) OnDeactivate() error {
+	if p.scheduleJob != nil {
+		return p.scheduleJob.Close()
+	}
+	return nil
 }
 
 // ExecuteCommand dispatch the command based on the trigger word
 func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
-	config := p.getConfiguration()
+	// The whole command uses the same snapshot, even if the configuration changes in the meantime
+	snapshot := p.getSnapshot()
 
-	if strings.HasPrefix(args.Command, "/"+config.CommandTriggerGifWithPreview) {
-		keywords, caption, parseErr := parseCommandLine(args.Command, config.CommandTriggerGifWithPreview)
-		if parseErr != nil {
-			return nil, p.errorGenerator.FromMessage(parseErr.Error())
-		}
-		return p.executeCommandGifWithPreview(keywords, caption, args)
+	trigger := getCommandTrigger(args.Command)
+	supported, withPreview := getTriggerMode(snapshot.configuration, trigger)
+	if !supported {
+		return nil, p.errorGenerator.FromMessage("Command trigger " + args.Command + "is not supported by this plugin.")
 	}
-	if strings.HasPrefix(args.Command, "/"+config.CommandTriggerGif) {
-		keywords, caption, parseErr := parseCommandLine(args.Command, config.CommandTriggerGif)
-		if parseErr != nil {
-			return nil, p.errorGenerator.FromMessage(parseErr.Error())
-		}
-		return p.executeCommandGif(keywords, caption, args)
+
+	if subcommand, parameters := parseSubcommand(args.Command, trigger); subcommand != "" {
+		return subcommands[subcommand](p, snapshot, parameters, args)
 	}
 
-	return nil, p.errorGenerator.FromMessage("Command trigger " + args.Command + "is not supported by this plugin.")
+	commandLine, language := parseLanguageOption(args.Command, trigger)
+	commandLine, keywordsAsCaption := parseCaptionOption(commandLine, trigger)
+	keywords, caption, parseErr := parseCommandLine(commandLine, trigger)
+	if parseErr != nil {
+		return nil, p.errorGenerator.FromMessage(parseErr.Error())
+	}
+	if keywordsAsCaption && caption == "" {
+		caption = keywords
+	}
+	if alias, isAlias := parseAliasToken(keywords); isAlias {
+		return p.executeCommandGifFromAlias(snapshot, alias, caption, withPreview, args)
+	}
+	userAgent := ""
+	if c != nil {
+		userAgent = c.UserAgent
+	}
+	ctx, cancel := newSearchContext()
+	defer cancel()
+	query := p.newQuery(snapshot, keywords, p.getUserLanguage(snapshot, args.UserId, language), userAgent)
+	if page, isPage := provider.ParseGifPage(keywords); isPage {
+		return p.executeCommandGifFromPage(ctx, snapshot, page, query, caption, withPreview, args)
+	}
+	if withPreview {
+		return p.executeCommandGifWithPreview(ctx, snapshot, query, caption, args)
+	}
+	return p.executeCommandGif(ctx, snapshot, query, caption, args)
 }
 
 // ServeHTTP serve the post actions for the shuffle command
 func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
-	p.handleHTTPRequest(w, r)
+	p.handleHTTPRequest(p.getSnapshot(), w, r)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,7 @@ import (
 	"testing"
 
 	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
 	"github.com/moussetc/mattermost-plugin-giphy/server/internal/test"
 
 	"github.com/stretchr/testify/assert"
@@ -42,13 +44,25 @@ func mockLoadConfig(conf pluginConf.Configuration) func(dest interface{}) error
 
 type mockHTTPHandler struct{}
 
-func (h *mockHTTPHandler) handleCancel(p *Plugin, w http.ResponseWriter, request *integrationRequest) {
+func (h *mockHTTPHandler) handleCancel(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
 	w.WriteHeader(http.StatusOK)
 }
-func (h *mockHTTPHandler) handleShuffle(p *Plugin, w http.ResponseWriter, request *integrationRequest) {
+func (h *mockHTTPHandler) handleShuffle(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
 	w.WriteHeader(http.StatusOK)
 }
-func (h *mockHTTPHandler) handleSend(p *Plugin, w http.ResponseWriter, request *integrationRequest) {
+func (h *mockHTTPHandler) handleSend(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	w.WriteHeader(http.StatusOK)
+}
+func (h *mockHTTPHandler) handleSave(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	w.WriteHeader(http.StatusOK)
+}
+func (h *mockHTTPHandler) handleRespond(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	w.WriteHeader(http.StatusOK)
+}
+func (h *mockHTTPHandler) handleSuggestion(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *integrationRequest) {
+	w.WriteHeader(http.StatusOK)
+}
+func (h *mockHTTPHandler) handleRespondDialog(p *Plugin, snapshot *pluginSnapshot, w http.ResponseWriter, request *model.SubmitDialogRequest) {
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -58,9 +72,9 @@ func initMockAPI() (api *plugintest.API, p *Plugin) {
 	pluginConfig := generateMockPluginConfig()
 	api.On("LoadPluginConfiguration", mock.AnythingOfType("*configuration.Configuration")).Return(mockLoadConfig(pluginConfig))
 	p = &Plugin{}
-	p.configuration = &pluginConfig
+	setMockConfiguration(p, &pluginConfig)
 	p.SetAPI(api)
-	p.botID = "botId42"
+	setMockBotID(p, "botId42")
 	p.httpHandler = &mockHTTPHandler{}
 	p.errorGenerator = test.MockErrorGenerator()
 	return api, p
@@ -123,14 +137,15 @@ func TestOnActivateOK(t *testing.T) {
 }
 
 func TestExecuteGifCommandToSendPost(t *testing.T) {
-	_, p := initMockAPI()
+	api, p := initMockAPI()
+	mockRecentGifs(api, nil)
 
 	url := "http://fakeURL"
-	p.gifProvider = &mockGifProvider{url}
+	setMockGifProvider(p, &mockGifProvider{url})
 
 	command := model.CommandArgs{
 		Command: "/gif cute doggo",
-		UserId:  "userid",
+		UserId:  testUserID,
 	}
 
 	response, err := p.ExecuteCommand(&plugin.Context{}, &command)
@@ -143,7 +158,7 @@ func TestExecuteGifCommandToSendPost(t *testing.T) {
 func TestExecuteShuffleCommandToReturnCommandResponse(t *testing.T) {
 	api, p := initMockAPI()
 	url := "http://fakeURL"
-	p.gifProvider = &mockGifProvider{url}
+	setMockGifProvider(p, &mockGifProvider{url})
 
 	command := model.CommandArgs{
 		Command: "/gifs cute doggo",
@@ -160,7 +175,7 @@ func TestExecuteCommandFailWhenCommandHandlerFails(t *testing.T) {
 	api.On("LogWarn", mock.AnythingOfType("string")).Return(nil)
 
 	errorMessage := "ARGHHHH"
-	p.gifProvider = &mockGifProviderFail{errorMessage}
+	setMockGifProvider(p, &mockGifProviderFail{errorMessage})
 
 	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/gif cute doggo"})
 	assert.NotNil(t, err)
@@ -192,20 +207,41 @@ func TestServeHTTP(t *testing.T) {
 	assert.Equal(t, 200, result.StatusCode)
 }
 
+// setMockGifProvider publishes a snapshot with the GIF provider, keeping the current configuration
+func setMockGifProvider(p *Plugin, gifProvider provider.GifProvider) {
+	snapshot := *p.getSnapshot()
+	snapshot.gifProvider = gifProvider
+	p.setSnapshot(&snapshot)
+}
+
+// setMockConfiguration publishes a snapshot with the configuration, keeping the current GIF provider and bot
+func setMockConfiguration(p *Plugin, configuration *pluginConf.Configuration) {
+	snapshot := *p.getSnapshot()
+	snapshot.configuration = configuration
+	p.setSnapshot(&snapshot)
+}
+
+// setMockBotID publishes a snapshot with the bot ID, keeping the current configuration
+func setMockBotID(p *Plugin, botID string) {
+	snapshot := *p.getSnapshot()
+	snapshot.botID = botID
+	p.setSnapshot(&snapshot)
+}
+
 // mockGifProviderFail always fail to provide a GIF URL
 type mockGifProviderFail struct {
 	errorMessage string
 }
 
-func (m *mockGifProviderFail) GetGifURL(request string, cursor *string) (string, *model.AppError) {
-	return "", (test.MockErrorGenerator()).FromError(m.errorMessage, errors.New(m.errorMessage))
+func (m *mockGifProviderFail) GetGif(ctx context.Context, query provider.Query, cursor *string) (*provider.GifResult, *model.AppError) {
+	return nil, (test.MockErrorGenerator()).FromError(m.errorMessage, errors.New(m.errorMessage))
 }
 
 func (m *mockGifProviderFail) GetAttributionMessage() string {
 	return "test"
 }
 
-// mockGifProvider always provides the same fake GIF URL
+// mockGifProvider always provides the same fake GIF URL, or no GIF if the URL is empty
 type mockGifProvider struct {
 	mockURL string
 }
@@ -214,10 +250,28 @@ func newMockGifProvider() *mockGifProvider {
 	return &mockGifProvider{"fakeURL"}
 }
 
-func (m *mockGifProvider) GetGifURL(request string, cursor *string) (string, *model.AppError) {
-	return m.mockURL, nil
+func (m *mockGifProvider) GetGif(ctx context.Context, query provider.Query, cursor *string) (*provider.GifResult, *model.AppError) {
+	if m.mockURL == "" {
+		return nil, nil
+	}
+	return &provider.GifResult{URL: m.mockURL}, nil
 }
 
 func (m *mockGifProvider) GetAttributionMessage() string {
 	return "test"
 }
+
+func TestExecuteAliasCommandWithCaptionOptionToPreviewGif(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.ParsedCommandTriggerAliases = []string{"giphy"}
+	setMockGifProvider(p, &mockGifProvider{"http://fakeURL"})
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil, nil)
+
+	response, err := p.ExecuteCommand(&plugin.Context{}, &model.CommandArgs{Command: "/giphy #caption cute doggo", UserId: testUserID})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+	api.AssertCalled(t, "SendEphemeralPost", testUserID, mock.MatchedBy(func(post *model.Post) bool {
+		return strings.HasPrefix(post.Message, "cute doggo") && post.Attachments()[0].Actions[0].Integration.Context[contextCaption] == "cute doggo"
+	}))
+}
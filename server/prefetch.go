@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to searching the next GIF of a preview before the user shuffles it
+
+const (
+	// The prefetched GIFs of the previews not shuffled within this duration are forgotten
+	prefetchTTL = 2 * time.Minute
+	// Maximum number of previews with a prefetched GIF, the oldest ones are forgotten first
+	maxPrefetchedPreviews = 200
+)
+
+// prefetchedGifs keeps the next GIF of the previews of this server, searched in the background
+// so that a shuffle can answer at once. Only one GIF is searched ahead of each preview,
+// so an abandoned preview costs at most one provider call.
+type prefetchedGifs struct {
+	lock     sync.Mutex
+	previews map[string]*prefetchedGif
+}
+
+type prefetchedGif struct {
+	// Cursor the GIF was searched from, a shuffle with another cursor does not use the GIF
+	cursor string
+	// Snapshot the GIF was searched with, a shuffle after a configuration change does not use the GIF
+	snapshot  *pluginSnapshot
+	expiresAt time.Time
+	cancel    context.CancelFunc
+	// Closed when the search is done
+	ready      chan struct{}
+	gif        *savedGif
+	nextCursor string
+	err        *model.AppError
+}
+
+// start searches in the background the GIF that follows the cursor in the preview post,
+// replacing the GIF prefetched before for the post
+func (b *prefetchedGifs) start(snapshot *pluginSnapshot, postID, cursor string, search func(ctx context.Context, cursor *string) (*savedGif, *model.AppError)) {
+	ctx, cancel := newSearchContext()
+	prefetched := &prefetchedGif{cursor: cursor, snapshot: snapshot, expiresAt: time.Now().Add(prefetchTTL), cancel: cancel, ready: make(chan struct{})}
+
+	b.lock.Lock()
+	if b.previews == nil {
+		b.previews = map[string]*prefetchedGif{}
+	}
+	b.removeLocked(postID)
+	b.removeExpiredLocked()
+	if len(b.previews) >= maxPrefetchedPreviews {
+		b.removeOldestLocked()
+	}
+	b.previews[postID] = prefetched
+	b.lock.Unlock()
+
+	go func() {
+		defer cancel()
+		defer close(prefetched.ready)
+		nextCursor := cursor
+		prefetched.gif, prefetched.err = search(ctx, &nextCursor)
+		prefetched.nextCursor = nextCursor
+	}()
+}
+
+// take returns the GIF prefetched for the cursor of the preview post with the snapshot, waiting for its search if it is
+// still in progress, and the cursor that follows the GIF. ok is false if there is no usable prefetched GIF.
+func (b *prefetchedGifs) take(ctx context.Context, snapshot *pluginSnapshot, postID, cursor string) (gif *savedGif, nextCursor string, ok bool) {
+	b.lock.Lock()
+	prefetched, found := b.previews[postID]
+	if found {
+		delete(b.previews, postID)
+	}
+	b.lock.Unlock()
+	if !found || prefetched.cursor != cursor || prefetched.snapshot != snapshot || time.Now().After(prefetched.expiresAt) {
+		if found {
+			prefetched.cancel()
+		}
+		return nil, "", false
+	}
+
+	select {
+	case <-prefetched.ready:
+	case <-ctx.Done():
+		prefetched.cancel()
+		return nil, "", false
+	}
+	if prefetched.err != nil {
+		return nil, "", false
+	}
+	return prefetched.gif, prefetched.nextCursor, true
+}
+
+// discard forgets the GIF prefetched for the preview post, aborting its search if it is still in progress
+func (b *prefetchedGifs) discard(postID string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.removeLocked(postID)
+}
+
+func (b *prefetchedGifs) removeLocked(postID string) {
+	if prefetched, ok := b.previews[postID]; ok {
+		prefetched.cancel()
+		delete(b.previews, postID)
+	}
+}
+
+func (b *prefetchedGifs) removeExpiredLocked() {
+	now := time.Now()
+	for postID, prefetched := range b.previews {
+		if now.After(prefetched.expiresAt) {
+			b.removeLocked(postID)
+		}
+	}
+}
+
+func (b *prefetchedGifs) removeOldestLocked() {
+	oldestPostID := ""
+	var oldest time.Time
+	for postID, prefetched := range b.previews {
+		if oldestPostID == "" || prefetched.expiresAt.Before(oldest) {
+			oldestPostID, oldest = postID, prefetched.expiresAt
+		}
+	}
+	b.removeLocked(oldestPostID)
+}
+
+// prefetchPreviewGif searches in the background the GIF that a shuffle of the search preview post would show next
+func (p *Plugin) prefetchPreviewGif(snapshot *pluginSnapshot, postID string, query provider.Query, cursor, channelID string, seen []string) {
+	if postID == "" || cursor == "" || !snapshot.configuration.PrefetchPreviewGifs {
+		return
+	}
+	p.prefetchedGifs.start(snapshot, postID, cursor, func(ctx context.Context, cursor *string) (*savedGif, *model.AppError) {
+		return p.getPreviewGif(ctx, snapshot, sourceSearch, query, "", "", channelID, seen, cursor)
+	})
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockGifProviderCounting returns the GIFs of the sequence and counts the searches
+type mockGifProviderCounting struct {
+	mockGifProviderSequence
+	calls int32
+}
+
+func (m *mockGifProviderCounting) GetGif(ctx context.Context, query provider.Query, cursor *string) (*provider.GifResult, *model.AppError) {
+	atomic.AddInt32(&m.calls, 1)
+	return m.mockGifProviderSequence.GetGif(ctx, query, cursor)
+}
+
+// testSnapshot is the snapshot the tests of the prefetched GIFs are made with
+var testSnapshot = &pluginSnapshot{}
+
+// searchTestGif returns a search that finds the GIF named after the cursor
+func searchTestGif(ctx context.Context, cursor *string) (*savedGif, *model.AppError) {
+	gif := &savedGif{URL: "https://gif.fr/" + *cursor}
+	*cursor += "+1"
+	return gif, nil
+}
+
+func TestPrefetchedGifsShouldReturnThePrefetchedGifOnce(t *testing.T) {
+	prefetched := prefetchedGifs{}
+	prefetched.start(testSnapshot, testPostID, "1", searchTestGif)
+
+	gif, cursor, ok := prefetched.take(context.Background(), testSnapshot, testPostID, "1")
+
+	assert.True(t, ok)
+	if assert.NotNil(t, gif) {
+		assert.Equal(t, "https://gif.fr/1", gif.URL)
+	}
+	assert.Equal(t, "1+1", cursor)
+	_, _, ok = prefetched.take(context.Background(), testSnapshot, testPostID, "1")
+	assert.False(t, ok)
+}
+
+func TestPrefetchedGifsShouldIgnoreAnotherCursorOrAnExpiredGif(t *testing.T) {
+	prefetched := prefetchedGifs{}
+	prefetched.start(testSnapshot, testPostID, "1", searchTestGif)
+	_, _, ok := prefetched.take(context.Background(), testSnapshot, testPostID, "2")
+	assert.False(t, ok)
+	assert.Empty(t, prefetched.previews)
+
+	prefetched.start(testSnapshot, testPostID, "1", searchTestGif)
+	prefetched.previews[testPostID].expiresAt = time.Now().Add(-time.Second)
+	_, _, ok = prefetched.take(context.Background(), testSnapshot, testPostID, "1")
+	assert.False(t, ok)
+}
+
+func TestPrefetchedGifsShouldIgnoreAGifPrefetchedWithAnotherSnapshot(t *testing.T) {
+	prefetched := prefetchedGifs{}
+	prefetched.start(testSnapshot, testPostID, "1", searchTestGif)
+
+	_, _, ok := prefetched.take(context.Background(), &pluginSnapshot{}, testPostID, "1")
+
+	assert.False(t, ok)
+	assert.Empty(t, prefetched.previews)
+}
+
+func TestPrefetchedGifsShouldAbortTheSearchWhenDiscarded(t *testing.T) {
+	prefetched := prefetchedGifs{}
+	searchCtx := make(chan context.Context, 1)
+	prefetched.start(testSnapshot, testPostID, "1", func(ctx context.Context, cursor *string) (*savedGif, *model.AppError) {
+		searchCtx <- ctx
+		<-ctx.Done()
+		return nil, nil
+	})
+	ctx := <-searchCtx
+
+	prefetched.discard(testPostID)
+
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Empty(t, prefetched.previews)
+}
+
+func TestPrefetchedGifsShouldStopWaitingWhenTheShuffleIsCanceled(t *testing.T) {
+	prefetched := prefetchedGifs{}
+	prefetched.start(testSnapshot, testPostID, "1", func(ctx context.Context, cursor *string) (*savedGif, *model.AppError) {
+		<-ctx.Done()
+		return nil, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, ok := prefetched.take(ctx, testSnapshot, testPostID, "1")
+
+	assert.False(t, ok)
+}
+
+func TestPrefetchedGifsShouldForgetTheOldestPreviews(t *testing.T) {
+	prefetched := prefetchedGifs{}
+	for i := 0; i <= maxPrefetchedPreviews; i++ {
+		prefetched.start(testSnapshot, "post"+strconv.Itoa(i), "1", searchTestGif)
+		time.Sleep(time.Microsecond)
+	}
+
+	assert.Len(t, prefetched.previews, maxPrefetchedPreviews)
+	assert.NotContains(t, prefetched.previews, "post0")
+	assert.Contains(t, prefetched.previews, "post"+strconv.Itoa(maxPrefetchedPreviews))
+}
+
+func TestHandleShuffleShouldShowTheGifPrefetchedByThePreview(t *testing.T) {
+	api, p := initMockAPI()
+	p.getSnapshot().configuration.PrefetchPreviewGifs = true
+	gifProvider := &mockGifProviderCounting{mockGifProviderSequence: mockGifProviderSequence{gifs: []*provider.GifResult{
+		{ID: "gif1", URL: testGifURL},
+		{ID: "gif2", URL: "https://gif.fr/next"},
+	}}}
+	setMockGifProvider(p, gifProvider)
+	var preview *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		preview = args.Get(1).(*model.Post)
+	})
+	var shuffled *model.Post
+	api.On("UpdateEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		shuffled = args.Get(1).(*model.Post)
+	})
+
+	_, err := p.executeCommandGifWithPreview(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, "", testArgs)
+	assert.Nil(t, err)
+	if !assert.NotNil(t, preview) || !assert.NotEmpty(t, preview.Id) {
+		return
+	}
+	request := generateTestIntegrationRequest()
+	request.PostId = preview.Id
+	request.Cursor = "1"
+	request.Seen = []string{"gif1"}
+	w := httptest.NewRecorder()
+	(&defaultHTTPHandler{}).handleShuffle(p, p.getSnapshot(), w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	if assert.NotNil(t, shuffled) {
+		assert.Contains(t, shuffled.Message, "https://gif.fr/next")
+	}
+	// The shuffle used the GIF prefetched after the preview was sent, and there is nothing more to prefetch
+	assert.Equal(t, int32(2), atomic.LoadInt32(&gifProvider.calls))
+	assert.Empty(t, p.prefetchedGifs.previews)
+}
+
+func TestExecuteCommandGifWithPreviewShouldNotPrefetchWhenDisabled(t *testing.T) {
+	api, p := initMockAPI()
+	gifProvider := &mockGifProviderCounting{mockGifProviderSequence: mockGifProviderSequence{gifs: []*provider.GifResult{
+		{ID: "gif1", URL: testGifURL},
+		{ID: "gif2", URL: "https://gif.fr/next"},
+	}}}
+	setMockGifProvider(p, gifProvider)
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil)
+
+	_, err := p.executeCommandGifWithPreview(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords}, "", testArgs)
+
+	assert.Nil(t, err)
+	assert.Empty(t, p.prefetchedGifs.previews)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&gifProvider.calls))
+}
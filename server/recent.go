@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to the history of the GIFs recently sent by each user
+
+const (
+	subcommandRecent = "recent"
+
+	recentKeyPrefix = "recent_"
+	// Maximum number of GIFs in the history of a user, the oldest ones are removed first
+	maxRecentGifs = 20
+)
+
+func (p *Plugin) getRecentGifs(userID string) ([]*savedGif, *model.AppError) {
+	return p.getSavedGifs(recentKeyPrefix + userID)
+}
+
+// recordRecentGif adds a GIF sent by the user to their history, keeping its provider, attribution and ID.
+// Failures are only logged since they must not prevent the GIF from being sent.
+func (p *Plugin) recordRecentGif(snapshot *pluginSnapshot, userID string, sentGif *savedGif) {
+	gif := *sentGif
+	if gif.Provider == "" {
+		gif.Provider = snapshot.configuration.Provider
+	}
+	gif.SavedAt = model.GetMillis()
+	appErr := p.updateSavedGifs(recentKeyPrefix+userID, func(gifs []*savedGif) []*savedGif {
+		return prependSavedGif(gifs, &gif, maxRecentGifs)
+	})
+	if appErr != nil {
+		p.API.LogWarn("Unable to add the GIF to the user's recent GIFs", "userID", userID, "error", appErr.Error())
+	}
+}
+
+// executeCommandRecent returns an ephemeral post to browse the GIFs recently sent by the user, matching the optional filter
+func (p *Plugin) executeCommandRecent(snapshot *pluginSnapshot, parameters []string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	filter := strings.Trim(strings.Join(parameters, " "), "\"")
+	emptyMessage := "You haven't sent any GIFs recently."
+	if filter != "" {
+		emptyMessage = "None of your recent GIFs matches '" + escapeMarkdown(filter) + "'."
+	}
+	return p.executeCommandCollectionPreview(snapshot, sourceRecent, "", filter, "", emptyMessage, args)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+// mockRecentGifs mocks the history of the GIFs sent by the test user
+func mockRecentGifs(api *plugintest.API, gifs []*savedGif) {
+	var data []byte
+	if gifs != nil {
+		data = mockStoredGifs(gifs)
+	}
+	api.On("KVGet", recentKeyPrefix+testUserID).Return(data, nil)
+	api.On("KVCompareAndSet", recentKeyPrefix+testUserID, mock.Anything, mock.Anything).Return(true, nil)
+}
+
+func TestExecuteCommandGifShouldRecordRecentGif(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	mockRecentGifs(api, testSavedGifs)
+
+	_, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif cute doggo", UserId: testUserID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	api.AssertCalled(t, "KVCompareAndSet", recentKeyPrefix+testUserID, mockStoredGifs(testSavedGifs), mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil &&
+			len(gifs) == len(testSavedGifs)+1 &&
+			gifs[0].URL == p.getSnapshot().gifProvider.(*mockGifProvider).mockURL &&
+			gifs[0].Keywords == "cute doggo"
+	}))
+}
+
+func TestExecuteCommandGifShouldSearchQuotedKeywordsStartingWithRecent(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	mockRecentGifs(api, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gif \"recent news\"", UserId: testUserID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.Equal(t, model.CommandResponseTypeInChannel, response.ResponseType)
+	assert.Contains(t, response.Text, "recent news")
+}
+
+func TestRecordRecentGifShouldKeepHistoryBounded(t *testing.T) {
+	api, p := initMockAPI()
+	history := []*savedGif{}
+	for i := 0; i < maxRecentGifs; i++ {
+		history = append(history, &savedGif{URL: "https://gif.fr/" + string(rune('a'+i))})
+	}
+	mockRecentGifs(api, history)
+
+	p.recordRecentGif(p.getSnapshot(), testUserID, &savedGif{URL: testGifURL, Keywords: testKeywords})
+
+	api.AssertCalled(t, "KVCompareAndSet", recentKeyPrefix+testUserID, mock.Anything, mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil &&
+			len(gifs) == maxRecentGifs &&
+			gifs[0].URL == testGifURL &&
+			gifs[maxRecentGifs-1].URL == history[maxRecentGifs-2].URL
+	}))
+}
+
+func TestRecordRecentGifShouldOnlyLogErrors(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", recentKeyPrefix+testUserID).Return(nil, &model.AppError{Message: "KV down"})
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	p.recordRecentGif(p.getSnapshot(), testUserID, &savedGif{URL: testGifURL, Keywords: testKeywords})
+
+	api.AssertCalled(t, "LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleSendShouldRecordRecentGif(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	mockRecentGifs(api, nil)
+	api.On("DeleteEphemeralPost", testUserID, testPostID).Return(nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	h := &defaultHTTPHandler{}
+	w := httptest.NewRecorder()
+
+	h.handleSend(p, p.getSnapshot(), w, generateTestIntegrationRequest())
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertCalled(t, "KVCompareAndSet", recentKeyPrefix+testUserID, []byte(nil), mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil && len(gifs) == 1 && gifs[0].URL == testGifURL
+	}))
+}
+
+func TestHandleSendShouldKeepTheProviderAttributionAndIDOfTheGif(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	mockRecentGifs(api, nil)
+	api.On("DeleteEphemeralPost", testUserID, testPostID).Return(nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	request := generateTestIntegrationRequest()
+	request.GifID = testGifID
+	request.Provider = "tenor"
+	request.Attribution = "Via Tenor"
+
+	(&defaultHTTPHandler{}).handleSend(p, p.getSnapshot(), httptest.NewRecorder(), request)
+
+	api.AssertCalled(t, "KVCompareAndSet", recentKeyPrefix+testUserID, []byte(nil), mock.MatchedBy(func(data []byte) bool {
+		var gifs []*savedGif
+		return json.Unmarshal(data, &gifs) == nil && len(gifs) == 1 &&
+			gifs[0].ID == testGifID && gifs[0].Provider == "tenor" && gifs[0].Attribution == "Via Tenor"
+	}))
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		context := post.Attachments()[0].Actions[0].Integration.Context
+		return context[contextGifID] == testGifID && context[contextProvider] == "tenor" && context[contextAttribution] == "Via Tenor"
+	}))
+}
+
+func TestExecuteCommandRecentShouldSendPreviewOfLastGif(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	mockRecentGifs(api, testSavedGifs)
+	var preview *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		preview = args.Get(1).(*model.Post)
+	})
+
+	_, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gifs recent", UserId: testUserID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, preview)
+	assert.Contains(t, preview.Message, testSavedGifs[0].URL)
+	assert.Equal(t, sourceRecent, preview.Attachments()[0].Actions[0].Integration.Context[contextSource])
+}
+
+func TestExecuteCommandRecentShouldExplainWhenHistoryIsEmpty(t *testing.T) {
+	api, p := initMockAPI()
+	mockRecentGifs(api, nil)
+
+	response, err := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/gifs recent", UserId: testUserID, ChannelId: testChannelID})
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "recently")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Contains what's related to searching less specific keywords when a search finds no GIF
+
+const (
+	// Maximum number of relaxed searches after a search that found no GIF, to limit the calls to the GIF provider
+	maxRelaxedQueries = 3
+	// Maximum number of search terms suggested when no GIF is found
+	maxSuggestions = 5
+)
+
+// Words dropped first when relaxing the keywords
+var relaxationStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "at": true, "for": true, "in": true, "is": true, "my": true, "of": true,
+	"on": true, "or": true, "so": true, "that": true, "the": true, "this": true, "to": true, "very": true, "with": true, "your": true,
+}
+
+// relaxQuery returns less specific versions of the keywords, from the closest to the most relaxed:
+// without punctuation, with singular forms, without the stop words, then without the shortest words
+func relaxQuery(keywords string) []string {
+	relaxed := []string{}
+	previous := strings.ToLower(strings.Join(strings.Fields(keywords), " "))
+	add := func(words []string) {
+		candidate := strings.Join(words, " ")
+		if candidate != "" && strings.ToLower(candidate) != previous && len(relaxed) < maxRelaxedQueries {
+			relaxed = append(relaxed, candidate)
+			previous = strings.ToLower(candidate)
+		}
+	}
+
+	words := splitWords(keywords)
+	add(words)
+	for i, word := range words {
+		words[i] = singularize(word)
+	}
+	add(words)
+	significant := []string{}
+	for _, word := range words {
+		if !relaxationStopWords[strings.ToLower(word)] {
+			significant = append(significant, word)
+		}
+	}
+	if len(significant) > 0 {
+		words = significant
+		add(words)
+	}
+	for len(words) > 1 {
+		words = removeShortestWord(words)
+		add(words)
+	}
+	return relaxed
+}
+
+// splitWords returns the words of the keywords without punctuation, the apostrophes being removed inside the words
+func splitWords(keywords string) []string {
+	keywords = strings.NewReplacer("'", "", "’", "").Replace(keywords)
+	return strings.FieldsFunc(keywords, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// singularize returns the singular form of an English plural word, or the word if it does not look plural
+func singularize(word string) string {
+	lower := strings.ToLower(word)
+	switch {
+	case len(word) <= 3:
+		return word
+	case strings.HasSuffix(lower, "ies") && len(word) > 4:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") && !strings.HasSuffix(lower, "us") && !strings.HasSuffix(lower, "is"):
+		return word[:len(word)-1]
+	default:
+		return word
+	}
+}
+
+// removeShortestWord removes the shortest word, considered the least important one, the last one if several have the same length
+func removeShortestWord(words []string) []string {
+	shortest := 0
+	for i, word := range words {
+		if len([]rune(word)) <= len([]rune(words[shortest])) {
+			shortest = i
+		}
+	}
+	result := append([]string{}, words[:shortest]...)
+	return append(result, words[shortest+1:]...)
+}
+
+// searchRelaxedGif returns the first new GIF matching the query, or else matching a relaxed version of the keywords,
+// and the keywords that found it
+func (p *Plugin) searchRelaxedGif(ctx context.Context, snapshot *pluginSnapshot, query provider.Query, cursor *string, channelID string, seen []string, preview bool) (*provider.GifResult, string, *model.AppError) {
+	gif, appErr := p.searchNewGif(ctx, snapshot, query, cursor, channelID, seen, preview)
+	if appErr != nil || gif != nil {
+		return gif, query.Keywords, appErr
+	}
+	for _, keywords := range relaxQuery(query.Keywords) {
+		relaxedQuery := query
+		relaxedQuery.Keywords = keywords
+		*cursor = ""
+		gif, appErr = p.searchNewGif(ctx, snapshot, relaxedQuery, cursor, channelID, seen, preview)
+		if appErr != nil || gif != nil {
+			return gif, keywords, appErr
+		}
+	}
+	*cursor = ""
+	return nil, query.Keywords, nil
+}
+
+// getSuggestions returns search terms to try instead of the keywords, if the GIF provider can suggest some
+func (p *Plugin) getSuggestions(ctx context.Context, snapshot *pluginSnapshot, query provider.Query, preview bool) []string {
+	suggestionProvider, ok := snapshot.getSearchProvider(preview).(provider.SuggestionProvider)
+	if !ok {
+		return nil
+	}
+	suggestions, appErr := suggestionProvider.GetSuggestions(ctx, query, maxSuggestions)
+	if appErr != nil {
+		p.API.LogWarn("Could not get the search suggestions: " + appErr.Error())
+		return nil
+	}
+	return suggestions
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockGifProviderKeywords only finds a GIF for the known keywords, and suggests search terms
+type mockGifProviderKeywords struct {
+	urls        map[string]string
+	suggestions []string
+	searches    []string
+}
+
+func (m *mockGifProviderKeywords) GetGif(ctx context.Context, query provider.Query, cursor *string) (*provider.GifResult, *model.AppError) {
+	m.searches = append(m.searches, query.Keywords)
+	*cursor = ""
+	if url, ok := m.urls[query.Keywords]; ok {
+		return &provider.GifResult{URL: url}, nil
+	}
+	return nil, nil
+}
+
+func (m *mockGifProviderKeywords) GetAttributionMessage() string {
+	return "test"
+}
+
+func (m *mockGifProviderKeywords) GetSuggestions(ctx context.Context, query provider.Query, limit int) ([]string, *model.AppError) {
+	return m.suggestions, nil
+}
+
+func TestRelaxQuery(t *testing.T) {
+	assert.Equal(t, []string{"The happiest kitties", "The happiest kitty", "happiest kitty"}, relaxQuery("The happiest kitties!!!"))
+	assert.Equal(t, []string{"dancing cat", "dancing"}, relaxQuery("dancing cats"))
+	assert.Equal(t, []string{"dont stop", "dont"}, relaxQuery("don't stop"))
+	assert.Empty(t, relaxQuery("cat"))
+	assert.Empty(t, relaxQuery("?!"))
+}
+
+func TestSingularize(t *testing.T) {
+	for plural, singular := range map[string]string{
+		"kitties": "kitty",
+		"Boxes":   "Box",
+		"kisses":  "kiss",
+		"cats":    "cat",
+		"dogs":    "dog",
+		"glass":   "glass",
+		"octopus": "octopus",
+		"this":    "this",
+		"yes":     "yes",
+	} {
+		assert.Equal(t, singular, singularize(plural), plural)
+	}
+}
+
+func TestExecuteCommandGifWithPreviewShouldSearchRelaxedKeywords(t *testing.T) {
+	api, p := initMockAPI()
+	gifProvider := &mockGifProviderKeywords{urls: map[string]string{"dancing": testGifURL}}
+	setMockGifProvider(p, gifProvider)
+	var preview *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		preview = args.Get(1).(*model.Post)
+	})
+
+	_, err := p.executeCommandGifWithPreview(context.Background(), p.getSnapshot(), provider.Query{Keywords: "dancing cats"}, "", testArgs)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dancing cats", "dancing cat", "dancing"}, gifProvider.searches)
+	if assert.NotNil(t, preview) {
+		assert.Contains(t, preview.Message, testGifURL)
+		assert.Equal(t, "dancing", preview.Attachments()[0].Actions[0].Integration.Context[contextKeywords])
+	}
+}
+
+func TestExecuteCommandGifWithPreviewShouldSuggestOtherSearchesWhenNoGifIsFound(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, &mockGifProviderKeywords{suggestions: []string{"kitten", "cat nap"}})
+	var post *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		post = args.Get(1).(*model.Post)
+	})
+
+	_, err := p.executeCommandGifWithPreview(context.Background(), p.getSnapshot(), provider.Query{Keywords: "kittyy", Locale: "fr"}, testCaption, testArgs)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, post) {
+		assert.Contains(t, post.Message, "No GIFs found for 'kittyy'")
+		actions := post.Attachments()[0].Actions
+		if assert.Len(t, actions, 2) {
+			assert.Equal(t, "kitten", actions[0].Name)
+			assert.Contains(t, actions[0].Integration.URL, URLSuggestion)
+			assert.Equal(t, "cat nap", actions[1].Integration.Context[contextKeywords])
+			assert.Equal(t, testCaption, actions[1].Integration.Context[contextCaption])
+			assert.Equal(t, "fr", actions[1].Integration.Context[contextLanguage])
+		}
+	}
+}
+
+func TestHandleSuggestionShouldReplaceThePostByAPreview(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, &mockGifProviderKeywords{urls: map[string]string{"kitten": testGifURL}})
+	api.On("DeleteEphemeralPost", testUserID, testPostID).Return()
+	var preview *model.Post
+	api.On("SendEphemeralPost", testUserID, mock.AnythingOfType("*model.Post")).Return(nil).Run(func(args mock.Arguments) {
+		preview = args.Get(1).(*model.Post)
+	})
+	request := &integrationRequest{Keywords: "kitten", Caption: testCaption, RootID: testRootID, PostActionIntegrationRequest: testPostActionIntegrationRequest}
+	w := httptest.NewRecorder()
+
+	(&defaultHTTPHandler{}).handleSuggestion(p, p.getSnapshot(), w, request)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	api.AssertCalled(t, "DeleteEphemeralPost", testUserID, testPostID)
+	if assert.NotNil(t, preview) {
+		assert.Contains(t, preview.Message, testGifURL)
+		assert.Contains(t, preview.Message, testCaption)
+		assert.Equal(t, testRootID, preview.RootId)
+	}
+}
+
+func TestParseRequestShouldNotRequireAGifForASuggestion(t *testing.T) {
+	body := `{"user_id": "` + testUserID + `", "context": {"keywords": "kitten"}}`
+	r := httptest.NewRequest("POST", URLSuggestion, strings.NewReader(body))
+
+	request, err := parseRequest(r)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, request) {
+		assert.Equal(t, "kitten", request.Keywords)
+	}
+}
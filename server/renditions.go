@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	pluginConf "github.com/moussetc/mattermost-plugin-giphy/server/internal/configuration"
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+)
+
+// Contains what's related to the choice of the GIF renditions depending on the user's device
+
+// Parts of the user agents of the mobile apps and browsers
+var mobileUserAgentMarkers = []string{"mobile", "android", "iphone", "ipad"}
+
+func isMobileUserAgent(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, marker := range mobileUserAgentMarkers {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// getRenditionBudget returns the limits of the GIF renditions for the device of the user who sends the GIF, since the
+// devices of the users who will see it are unknown. The desktop limits are used when the device is unknown,
+// and when there are no mobile limits.
+func getRenditionBudget(config *pluginConf.Configuration, userAgent string) provider.RenditionBudget {
+	budget := provider.RenditionBudget{
+		MaxBytes:     config.MaxGifSizeKB * 1024,
+		MaxDimension: config.MaxGifDimension,
+	}
+	if isMobileUserAgent(userAgent) {
+		if config.MobileMaxGifSizeKB > 0 {
+			budget.MaxBytes = config.MobileMaxGifSizeKB * 1024
+		}
+		if config.MobileMaxGifDimension > 0 {
+			budget.MaxDimension = config.MobileMaxGifDimension
+		}
+	}
+	return budget
+}
+
+// newQuery returns the query for GIFs matching the keywords, for the user's language and device.
+// The emoji of the keywords are replaced by words.
+func (p *Plugin) newQuery(snapshot *pluginSnapshot, keywords, language, userAgent string) provider.Query {
+	return provider.Query{
+		Keywords: p.normalizeKeywords(keywords),
+		Locale:   language,
+		Budget:   getRenditionBudget(snapshot.configuration, userAgent),
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testDesktopUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36"
+	testMobileUserAgent  = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148"
+)
+
+func TestIsMobileUserAgent(t *testing.T) {
+	assert.False(t, isMobileUserAgent(testDesktopUserAgent))
+	assert.False(t, isMobileUserAgent(""))
+	assert.True(t, isMobileUserAgent(testMobileUserAgent))
+	assert.True(t, isMobileUserAgent("Mattermost Mobile/2.0.0+456 (Android; 13; Pixel 7)"))
+}
+
+func TestGetRenditionBudget(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.MaxGifSizeKB = 2048
+	p.getSnapshot().configuration.MaxGifDimension = 480
+	p.getSnapshot().configuration.MobileMaxGifSizeKB = 512
+
+	assert.Equal(t, provider.RenditionBudget{MaxBytes: 2048 * 1024, MaxDimension: 480}, getRenditionBudget(p.getSnapshot().configuration, testDesktopUserAgent))
+	assert.Equal(t, provider.RenditionBudget{MaxBytes: 512 * 1024, MaxDimension: 480}, getRenditionBudget(p.getSnapshot().configuration, testMobileUserAgent))
+}
+
+func TestGetRenditionBudgetShouldBeUnlimitedByDefault(t *testing.T) {
+	_, p := initMockAPI()
+
+	assert.Equal(t, provider.RenditionBudget{}, getRenditionBudget(p.getSnapshot().configuration, testMobileUserAgent))
+}
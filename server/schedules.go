@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/schedule"
+
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+	"github.com/mattermost/mattermost-server/v6/model"
+
+	"github.com/pkg/errors"
+)
+
+// Contains what's related to the scheduled GIF posts ("GIF of the day")
+
+const (
+	subcommandSchedule = "schedule"
+
+	scheduleActionList   = "list"
+	scheduleActionPause  = "pause"
+	scheduleActionResume = "resume"
+	scheduleActionDelete = "delete"
+
+	scheduleKeyPrefix = "schedule_"
+	// Key of the list of the IDs of all the schedules, so that the job doesn't have to list the whole KV store
+	scheduleIndexKey = "schedules"
+	scheduleJobKey   = "gif_schedules"
+	// Format used to display the schedule times, which are always in UTC
+	scheduleTimeFormat = "2006-01-02 15:04 MST"
+	// Limits of the schedules, so that a channel can't be flooded with GIFs: a schedule posts at most once an hour
+	maxScheduleRunsPerHour = 1
+	maxSchedulesPerChannel = 5
+)
+
+// gifSchedule is a recurring GIF post in a channel
+type gifSchedule struct {
+	ID        string
+	ChannelID string
+	CreatorID string
+	Spec      string
+	Keywords  string
+	Language  string
+	// Cursor of the last posted GIF, so that each post shows a different GIF
+	Cursor  string
+	Paused  bool
+	NextRun int64
+}
+
+func getScheduleUsage(trigger string) string {
+	return fmt.Sprintf("Usage (times are in UTC):\n"+
+		"* `/%[1]s schedule <minute> <hour> <day of month> <month> <day of week> <keywords>`: post a GIF on a cron-like schedule, ex: `/%[1]s schedule 0 9 * * 1-5 good morning` every weekday at 9:00. A schedule posts at most once an hour, and a channel has at most %[2]d schedules.\n"+
+		"* `/%[1]s schedule list`: list the schedules of this channel\n"+
+		"* `/%[1]s schedule pause|resume|delete <schedule ID>`: manage a schedule (channel admins and schedule creator only)", trigger, maxSchedulesPerChannel)
+}
+
+// executeCommandSchedule handles the /gif schedule subcommand
+func (p *Plugin) executeCommandSchedule(snapshot *pluginSnapshot, parameters []string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	trigger := getCommandTrigger(args.Command)
+	if len(parameters) == 0 {
+		return ephemeralResponse(getScheduleUsage(trigger)), nil
+	}
+	switch parameters[0] {
+	case scheduleActionList:
+		return p.listSchedules(args)
+	case scheduleActionPause, scheduleActionResume, scheduleActionDelete:
+		if len(parameters) != 2 {
+			return ephemeralResponse(getScheduleUsage(trigger)), nil
+		}
+		return p.updateSchedule(parameters[0], parameters[1], args)
+	}
+	if len(parameters) <= schedule.FieldCount {
+		return ephemeralResponse(getScheduleUsage(trigger)), nil
+	}
+	return p.createSchedule(snapshot, strings.Join(parameters[:schedule.FieldCount], " "), strings.Trim(strings.Join(parameters[schedule.FieldCount:], " "), "\""), args)
+}
+
+func (p *Plugin) createSchedule(snapshot *pluginSnapshot, specText, keywords string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	if !p.API.HasPermissionToChannel(args.UserId, args.ChannelId, model.PermissionCreatePost) {
+		return nil, p.errorGenerator.FromMessage("You are not allowed to post in this channel")
+	}
+	spec, err := schedule.Parse(specText)
+	if err != nil {
+		return ephemeralResponse("Invalid schedule: " + err.Error() + "\n" + getScheduleUsage(getCommandTrigger(args.Command))), nil
+	}
+	if spec.RunsPerHour() > maxScheduleRunsPerHour {
+		return ephemeralResponse("Invalid schedule: `" + spec.String() + "` posts more than once an hour, use a single minute"), nil
+	}
+	nextRun := spec.Next(time.Now().UTC())
+	if nextRun.IsZero() {
+		return ephemeralResponse("Invalid schedule: `" + spec.String() + "` never happens"), nil
+	}
+	schedules, appErr := p.getAllSchedules()
+	if appErr != nil {
+		return nil, appErr
+	}
+	channelSchedules := 0
+	for _, existing := range schedules {
+		if existing.ChannelID == args.ChannelId {
+			channelSchedules++
+		}
+	}
+	if channelSchedules >= maxSchedulesPerChannel {
+		return ephemeralResponse(fmt.Sprintf("This channel already has %d GIF schedules, delete one before creating another.", channelSchedules)), nil
+	}
+	s := &gifSchedule{
+		ID:        model.NewId(),
+		ChannelID: args.ChannelId,
+		CreatorID: args.UserId,
+		Spec:      spec.String(),
+		Keywords:  keywords,
+		Language:  p.getUserLanguage(snapshot, args.UserId, ""),
+		NextRun:   model.GetMillisForTime(nextRun),
+	}
+	// Indexed first: an indexed schedule that could not be saved is ignored
+	if appErr := p.updateScheduleIndex(func(ids []string) []string { return append(ids, s.ID) }); appErr != nil {
+		return nil, appErr
+	}
+	if appErr := p.saveSchedule(s); appErr != nil {
+		return nil, appErr
+	}
+	return ephemeralResponse(fmt.Sprintf("GIF schedule `%s` created: a GIF for '%s' will be posted on `%s`, next post on %s.", s.ID, escapeMarkdown(s.Keywords), s.Spec, formatScheduleTime(s.NextRun))), nil
+}
+
+func (p *Plugin) listSchedules(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	schedules, appErr := p.getAllSchedules()
+	if appErr != nil {
+		return nil, appErr
+	}
+	lines := []string{"| ID | Schedule (UTC) | Keywords | Next post |", "| --- | --- | --- | --- |"}
+	for _, s := range schedules {
+		if s.ChannelID != args.ChannelId {
+			continue
+		}
+		nextPost := formatScheduleTime(s.NextRun)
+		if s.Paused {
+			nextPost = "*paused*"
+		}
+		lines = append(lines, fmt.Sprintf("| `%s` | `%s` | %s | %s |", s.ID, s.Spec, escapeMarkdown(s.Keywords), nextPost))
+	}
+	if len(lines) == 2 {
+		return ephemeralResponse("There is no GIF schedule in this channel."), nil
+	}
+	return ephemeralResponse(strings.Join(lines, "\n")), nil
+}
+
+func (p *Plugin) updateSchedule(action, scheduleID string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	s, appErr := p.getSchedule(scheduleID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if s == nil || s.ChannelID != args.ChannelId {
+		return ephemeralResponse("No GIF schedule `" + scheduleID + "` found in this channel."), nil
+	}
+	if s.CreatorID != args.UserId && !p.API.HasPermissionToChannel(args.UserId, s.ChannelID, model.PermissionManageChannelRoles) {
+		return ephemeralResponse("Only channel admins and the creator of the schedule can " + action + " it."), nil
+	}
+
+	switch action {
+	case scheduleActionDelete:
+		if appErr = p.deleteSchedule(s); appErr != nil {
+			return nil, appErr
+		}
+		return ephemeralResponse("GIF schedule `" + s.ID + "` deleted."), nil
+	case scheduleActionPause:
+		s.Paused = true
+	case scheduleActionResume:
+		s.Paused = false
+		s.NextRun = model.GetMillisForTime(p.nextScheduleRun(s, time.Now().UTC()))
+	}
+	if appErr = p.saveSchedule(s); appErr != nil {
+		return nil, appErr
+	}
+	return ephemeralResponse("GIF schedule `" + s.ID + "` " + action + "d."), nil
+}
+
+// deleteSchedule deletes the schedule, then removes it from the index. An indexed schedule that doesn't exist
+// is ignored, so failing to update the index is only logged.
+func (p *Plugin) deleteSchedule(s *gifSchedule) *model.AppError {
+	if appErr := p.API.KVDelete(scheduleKeyPrefix + s.ID); appErr != nil {
+		return appErr
+	}
+	if appErr := p.updateScheduleIndex(func(ids []string) []string { return removeIndexEntry(ids, s.ID) }); appErr != nil {
+		p.API.LogWarn("Unable to remove the deleted GIF schedule from the index", "scheduleID", s.ID, "error", appErr.Error())
+	}
+	return nil
+}
+
+func formatScheduleTime(millis int64) string {
+	return model.GetTimeForMillis(millis).UTC().Format(scheduleTimeFormat)
+}
+
+func (p *Plugin) nextScheduleRun(s *gifSchedule, after time.Time) time.Time {
+	spec, err := schedule.Parse(s.Spec)
+	if err != nil {
+		p.API.LogWarn("Invalid stored GIF schedule", "scheduleID", s.ID, "error", err.Error())
+		return time.Time{}
+	}
+	return spec.Next(after)
+}
+
+func (p *Plugin) saveSchedule(s *gifSchedule) *model.AppError {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return p.errorGenerator.FromError("Could not serialize the GIF schedule", err)
+	}
+	return p.API.KVSet(scheduleKeyPrefix+s.ID, data)
+}
+
+func (p *Plugin) getSchedule(scheduleID string) (*gifSchedule, *model.AppError) {
+	data, appErr := p.API.KVGet(scheduleKeyPrefix + scheduleID)
+	if appErr != nil || data == nil {
+		return nil, appErr
+	}
+	var s gifSchedule
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, p.errorGenerator.FromError("Could not read the GIF schedule", err)
+	}
+	return &s, nil
+}
+
+// saveScheduleRun stores the cursor and the next run of a schedule after the job posted its GIF. The stored schedule
+// is only updated if it still exists and is not paused, so that the job doesn't undo a pause or a deletion made while it runs.
+func (p *Plugin) saveScheduleRun(run *gifSchedule) *model.AppError {
+	for attempt := 0; attempt < kvUpdateAttempts; attempt++ {
+		oldData, appErr := p.API.KVGet(scheduleKeyPrefix + run.ID)
+		if appErr != nil || oldData == nil {
+			return appErr
+		}
+		var s gifSchedule
+		if err := json.Unmarshal(oldData, &s); err != nil {
+			return p.errorGenerator.FromError("Could not read the GIF schedule", err)
+		}
+		if s.Paused {
+			return nil
+		}
+		s.Cursor = run.Cursor
+		s.NextRun = run.NextRun
+		s.Paused = run.Paused
+		newData, err := json.Marshal(&s)
+		if err != nil {
+			return p.errorGenerator.FromError("Could not serialize the GIF schedule", err)
+		}
+		updated, appErr := p.API.KVCompareAndSet(scheduleKeyPrefix+run.ID, oldData, newData)
+		if appErr != nil || updated {
+			return appErr
+		}
+	}
+	return p.errorGenerator.FromMessage("Could not save the GIF schedule because of concurrent updates")
+}
+
+// updateScheduleIndex atomically applies an update to the IDs of all the schedules, retrying if they are concurrently modified
+func (p *Plugin) updateScheduleIndex(update func(ids []string) []string) *model.AppError {
+	return p.updateKVIndex(scheduleIndexKey, "GIF schedules", update)
+}
+
+// getAllSchedules returns the schedules of the index, ignoring the indexed schedules that don't exist anymore
+func (p *Plugin) getAllSchedules() ([]*gifSchedule, *model.AppError) {
+	ids, appErr := p.getKVIndex(scheduleIndexKey, "GIF schedules")
+	if appErr != nil {
+		return nil, appErr
+	}
+	schedules := []*gifSchedule{}
+	for _, id := range ids {
+		s, appErr := p.getSchedule(id)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if s != nil {
+			schedules = append(schedules, s)
+		}
+	}
+	return schedules, nil
+}
+
+// startScheduleJob starts the job posting the scheduled GIFs. The cluster job relies on a KV lock
+// so that only one server of a cluster runs it at a given time.
+func (p *Plugin) startScheduleJob() error {
+	job, err := cluster.Schedule(p.API, scheduleJobKey, cluster.MakeWaitForRoundedInterval(time.Minute), p.runSchedules)
+	if err != nil {
+		return errors.Wrap(err, "Unable to schedule the GIF posts job")
+	}
+	p.scheduleJob = job
+	return nil
+}
+
+// runSchedules posts the GIFs of all the schedules that are due.
+// The schedules that fail to post because their channel was deleted or archived are deleted.
+func (p *Plugin) runSchedules() {
+	snapshot := p.getSnapshot()
+	schedules, appErr := p.getAllSchedules()
+	if appErr != nil {
+		p.API.LogError("Unable to read the GIF schedules", "error", appErr.Error())
+		return
+	}
+	now := time.Now().UTC()
+	for _, s := range schedules {
+		if s.Paused || s.NextRun > model.GetMillisForTime(now) {
+			continue
+		}
+		if appErr = p.postScheduledGif(snapshot, s); appErr != nil {
+			if p.isChannelGone(s.ChannelID) {
+				p.API.LogInfo("Deleting the GIF schedule of a deleted or archived channel", "scheduleID", s.ID, "channelID", s.ChannelID)
+				if appErr = p.deleteSchedule(s); appErr != nil {
+					p.API.LogWarn("Unable to delete the GIF schedule", "scheduleID", s.ID, "error", appErr.Error())
+				}
+				continue
+			}
+			p.API.LogWarn("Unable to post the scheduled GIF", "scheduleID", s.ID, "error", appErr.Error())
+		}
+		next := p.nextScheduleRun(s, now)
+		if next.IsZero() {
+			s.Paused = true
+		} else {
+			s.NextRun = model.GetMillisForTime(next)
+		}
+		if appErr = p.saveScheduleRun(s); appErr != nil {
+			p.API.LogError("Unable to save the GIF schedule", "scheduleID", s.ID, "error", appErr.Error())
+		}
+	}
+}
+
+// isChannelGone returns true if the channel was deleted or archived. Other errors, that could be temporary, return false.
+func (p *Plugin) isChannelGone(channelID string) bool {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return appErr.StatusCode == http.StatusNotFound
+	}
+	return channel.DeleteAt != 0
+}
+
+func (p *Plugin) postScheduledGif(snapshot *pluginSnapshot, s *gifSchedule) *model.AppError {
+	ctx, cancel := newSearchContext()
+	defer cancel()
+	query := p.newQuery(snapshot, s.Keywords, s.Language, "")
+	gif, appErr := p.searchNewGif(ctx, snapshot, query, &s.Cursor, s.ChannelID, nil, false)
+	if appErr != nil {
+		return appErr
+	}
+	if gif == nil && s.Cursor != "" {
+		// No more results: start again from the first GIF
+		s.Cursor = ""
+		gif, appErr = p.searchNewGif(ctx, snapshot, query, &s.Cursor, s.ChannelID, nil, false)
+		if appErr != nil {
+			return appErr
+		}
+	}
+	if gif == nil {
+		return p.errorGenerator.FromMessage("No GIFs found for '" + s.Keywords + "'")
+	}
+	if _, appErr = p.API.CreatePost(generateGifPost(snapshot.configuration, snapshot.botID, s.Keywords, "", gif.URL, getGifDescription(gif), s.ChannelID, "", snapshot.gifProvider.GetAttributionMessage())); appErr != nil {
+		return appErr
+	}
+	p.recordChannelGif(snapshot, s.ChannelID, newSearchResultGif(gif, s.Keywords))
+	return nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+)
+
+func generateScheduleCommandArgs(command string) *model.CommandArgs {
+	return &model.CommandArgs{
+		Command:   command,
+		ChannelId: testChannelID,
+		UserId:    testUserID,
+	}
+}
+
+func mockStoredSchedule(s *gifSchedule) []byte {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+// mockScheduleIndex stores the IDs of the schedules in the index, and accepts its updates
+func mockScheduleIndex(api *plugintest.API, ids ...string) {
+	data, _ := json.Marshal(ids)
+	api.On("KVGet", scheduleIndexKey).Return(data, nil)
+	api.On("KVCompareAndSet", scheduleIndexKey, data, mock.Anything).Return(true, nil)
+}
+
+func matchScheduleIndex(expectedIDs ...string) interface{} {
+	return mock.MatchedBy(func(data []byte) bool {
+		var ids []string
+		return json.Unmarshal(data, &ids) == nil && assert.ObjectsAreEqual(expectedIDs, ids)
+	})
+}
+
+func TestExecuteCommandScheduleShouldDisplayUsageWithoutParameters(t *testing.T) {
+	_, p := initMockAPI()
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, model.CommandResponseTypeEphemeral, response.ResponseType)
+	assert.Contains(t, response.Text, "Usage")
+}
+
+func TestExecuteCommandScheduleShouldCreateSchedule(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToChannel", testUserID, testChannelID, model.PermissionCreatePost).Return(true)
+	mockScheduleIndex(api, "s1")
+	api.On("KVGet", scheduleKeyPrefix+"s1").Return(mockStoredSchedule(&gifSchedule{ID: "s1", ChannelID: testChannelID, Spec: "0 9 * * *", Keywords: "coffee"}), nil)
+	var saved gifSchedule
+	api.On("KVSet", mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, scheduleKeyPrefix) }), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		_ = json.Unmarshal(args.Get(1).([]byte), &saved)
+	})
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule 0 9 * * 1-5 good morning"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "created")
+	assert.Equal(t, "0 9 * * 1-5", saved.Spec)
+	assert.Equal(t, "good morning", saved.Keywords)
+	assert.Equal(t, testChannelID, saved.ChannelID)
+	assert.Equal(t, testUserID, saved.CreatorID)
+	assert.False(t, saved.Paused)
+	assert.Greater(t, saved.NextRun, model.GetMillis())
+	api.AssertCalled(t, "KVCompareAndSet", scheduleIndexKey, mock.Anything, matchScheduleIndex("s1", saved.ID))
+}
+
+func TestExecuteCommandScheduleShouldRejectInvalidSpec(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToChannel", testUserID, testChannelID, model.PermissionCreatePost).Return(true)
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule 0 25 * * * good morning"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "Invalid schedule")
+	api.AssertNumberOfCalls(t, "KVSet", 0)
+}
+
+func TestExecuteCommandScheduleShouldRejectMoreThanOnePostAnHour(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToChannel", testUserID, testChannelID, model.PermissionCreatePost).Return(true)
+
+	for _, command := range []string{"/gifs schedule * * * * * spam", "/gifs schedule 0,30 9 * * * spam", "/gifs schedule */10 * * * * spam"} {
+		response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs(command))
+
+		assert.Nil(t, err)
+		assert.Contains(t, response.Text, "more than once an hour", command)
+	}
+	api.AssertNumberOfCalls(t, "KVSet", 0)
+}
+
+func TestExecuteCommandScheduleShouldLimitTheSchedulesOfAChannel(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("HasPermissionToChannel", testUserID, testChannelID, model.PermissionCreatePost).Return(true)
+	ids := []string{"other"}
+	api.On("KVGet", scheduleKeyPrefix+"other").Return(mockStoredSchedule(&gifSchedule{ID: "other", ChannelID: "otherChannel", Spec: "0 9 * * *"}), nil)
+	for i := 0; i < maxSchedulesPerChannel; i++ {
+		id := "s" + strconv.Itoa(i)
+		ids = append(ids, id)
+		api.On("KVGet", scheduleKeyPrefix+id).Return(mockStoredSchedule(&gifSchedule{ID: id, ChannelID: testChannelID, Spec: "0 9 * * *"}), nil)
+	}
+	mockScheduleIndex(api, ids...)
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule 0 9 * * 1-5 good morning"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "already has")
+	api.AssertNumberOfCalls(t, "KVSet", 0)
+	api.AssertNotCalled(t, "KVCompareAndSet", scheduleIndexKey, mock.Anything, mock.Anything)
+}
+
+func TestExecuteCommandScheduleShouldListChannelSchedules(t *testing.T) {
+	api, p := initMockAPI()
+	mockScheduleIndex(api, "s1", "s2")
+	api.On("KVGet", scheduleKeyPrefix+"s1").Return(mockStoredSchedule(&gifSchedule{ID: "s1", ChannelID: testChannelID, Spec: "0 9 * * *", Keywords: "coffee"}), nil)
+	api.On("KVGet", scheduleKeyPrefix+"s2").Return(mockStoredSchedule(&gifSchedule{ID: "s2", ChannelID: "otherChannel", Spec: "0 9 * * *", Keywords: "tea"}), nil)
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule list"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "coffee")
+	assert.NotContains(t, response.Text, "tea")
+}
+
+func TestExecuteCommandScheduleShouldRefuseToPauseOtherUsersScheduleForNonAdmin(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", scheduleKeyPrefix+"s1").Return(mockStoredSchedule(&gifSchedule{ID: "s1", ChannelID: testChannelID, CreatorID: "someoneElse"}), nil)
+	api.On("HasPermissionToChannel", testUserID, testChannelID, model.PermissionManageChannelRoles).Return(false)
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule pause s1"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "Only channel admins")
+	api.AssertNumberOfCalls(t, "KVSet", 0)
+}
+
+func TestExecuteCommandScheduleShouldPauseScheduleForChannelAdmin(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", scheduleKeyPrefix+"s1").Return(mockStoredSchedule(&gifSchedule{ID: "s1", ChannelID: testChannelID, CreatorID: "someoneElse"}), nil)
+	api.On("HasPermissionToChannel", testUserID, testChannelID, model.PermissionManageChannelRoles).Return(true)
+	api.On("KVSet", scheduleKeyPrefix+"s1", mock.MatchedBy(func(data []byte) bool {
+		var s gifSchedule
+		return json.Unmarshal(data, &s) == nil && s.Paused
+	})).Return(nil)
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule pause s1"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "paused")
+	api.AssertNumberOfCalls(t, "KVSet", 1)
+}
+
+func TestExecuteCommandScheduleShouldDeleteOwnSchedule(t *testing.T) {
+	api, p := initMockAPI()
+	api.On("KVGet", scheduleKeyPrefix+"s1").Return(mockStoredSchedule(&gifSchedule{ID: "s1", ChannelID: testChannelID, CreatorID: testUserID}), nil)
+	api.On("KVDelete", scheduleKeyPrefix+"s1").Return(nil)
+	mockScheduleIndex(api, "s1", "s2")
+
+	response, err := p.ExecuteCommand(nil, generateScheduleCommandArgs("/gifs schedule delete s1"))
+
+	assert.Nil(t, err)
+	assert.Contains(t, response.Text, "deleted")
+	api.AssertCalled(t, "KVDelete", scheduleKeyPrefix+"s1")
+	api.AssertCalled(t, "KVCompareAndSet", scheduleIndexKey, mock.Anything, matchScheduleIndex("s2"))
+}
+
+func TestRunSchedulesShouldPostDueSchedulesOnly(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	past := model.GetMillisForTime(time.Now().Add(-time.Minute))
+	future := model.GetMillisForTime(time.Now().Add(time.Hour))
+	mockScheduleIndex(api, "due", "later", "paused", "deleted")
+	api.On("KVGet", scheduleKeyPrefix+"deleted").Return(nil, nil)
+	api.On("KVGet", scheduleKeyPrefix+"due").Return(mockStoredSchedule(&gifSchedule{ID: "due", ChannelID: "dueChannel", Spec: "0 9 * * *", Keywords: "coffee", NextRun: past}), nil)
+	api.On("KVGet", scheduleKeyPrefix+"later").Return(mockStoredSchedule(&gifSchedule{ID: "later", ChannelID: "laterChannel", Spec: "0 9 * * *", Keywords: "tea", NextRun: future}), nil)
+	api.On("KVGet", scheduleKeyPrefix+"paused").Return(mockStoredSchedule(&gifSchedule{ID: "paused", ChannelID: "pausedChannel", Spec: "0 9 * * *", Keywords: "tea", NextRun: past, Paused: true}), nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	api.On("KVCompareAndSet", scheduleKeyPrefix+"due", mock.Anything, mock.Anything).Return(true, nil)
+
+	p.runSchedules()
+
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+	api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "dueChannel" &&
+			post.UserId == p.getSnapshot().botID &&
+			strings.Contains(post.Message, "coffee")
+	}))
+	api.AssertCalled(t, "KVCompareAndSet", scheduleKeyPrefix+"due", mock.Anything, mock.MatchedBy(func(data []byte) bool {
+		var s gifSchedule
+		return json.Unmarshal(data, &s) == nil && s.NextRun > model.GetMillis()
+	}))
+}
+
+func TestRunSchedulesShouldNotUndoAPauseOrADeletionMadeWhileRunning(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	past := model.GetMillisForTime(time.Now().Add(-time.Minute))
+	mockScheduleIndex(api, "paused", "deleted")
+	for _, id := range []string{"paused", "deleted"} {
+		api.On("KVGet", scheduleKeyPrefix+id).Return(mockStoredSchedule(&gifSchedule{ID: id, ChannelID: testChannelID, Spec: "0 9 * * *", Keywords: "coffee", NextRun: past}), nil).Once()
+	}
+	api.On("KVGet", scheduleKeyPrefix+"paused").Return(mockStoredSchedule(&gifSchedule{ID: "paused", ChannelID: testChannelID, Spec: "0 9 * * *", Keywords: "coffee", NextRun: past, Paused: true}), nil)
+	api.On("KVGet", scheduleKeyPrefix+"deleted").Return(nil, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+
+	p.runSchedules()
+
+	api.AssertNumberOfCalls(t, "CreatePost", 2)
+	api.AssertNotCalled(t, "KVCompareAndSet", scheduleKeyPrefix+"paused", mock.Anything, mock.Anything)
+	api.AssertNotCalled(t, "KVCompareAndSet", scheduleKeyPrefix+"deleted", mock.Anything, mock.Anything)
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+}
+
+func TestRunSchedulesShouldDeleteTheSchedulesOfDeletedOrArchivedChannels(t *testing.T) {
+	api, p := initMockAPI()
+	setMockGifProvider(p, newMockGifProvider())
+	past := model.GetMillisForTime(time.Now().Add(-time.Minute))
+	mockScheduleIndex(api, "archived", "deleted", "unavailable")
+	for _, id := range []string{"archived", "deleted", "unavailable"} {
+		channelID := id + "Channel"
+		api.On("KVGet", scheduleKeyPrefix+id).Return(mockStoredSchedule(&gifSchedule{ID: id, ChannelID: channelID, Spec: "0 9 * * *", Keywords: "coffee", NextRun: past}), nil)
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.ChannelId == channelID })).Return(nil, &model.AppError{Message: "cannot post"})
+	}
+	api.On("GetChannel", "archivedChannel").Return(&model.Channel{Id: "archivedChannel", DeleteAt: past}, nil)
+	api.On("GetChannel", "deletedChannel").Return(nil, &model.AppError{StatusCode: http.StatusNotFound})
+	api.On("GetChannel", "unavailableChannel").Return(nil, &model.AppError{StatusCode: http.StatusInternalServerError})
+	api.On("KVDelete", mock.Anything).Return(nil)
+	api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	api.On("KVCompareAndSet", scheduleKeyPrefix+"unavailable", mock.Anything, mock.Anything).Return(true, nil)
+
+	p.runSchedules()
+
+	api.AssertCalled(t, "KVDelete", scheduleKeyPrefix+"archived")
+	api.AssertCalled(t, "KVDelete", scheduleKeyPrefix+"deleted")
+	api.AssertNotCalled(t, "KVDelete", scheduleKeyPrefix+"unavailable")
+	api.AssertCalled(t, "KVCompareAndSet", scheduleKeyPrefix+"unavailable", mock.Anything, mock.Anything)
+	api.AssertNotCalled(t, "KVCompareAndSet", scheduleKeyPrefix+"archived", mock.Anything, mock.Anything)
+}
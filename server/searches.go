@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Contains what's related to the duration and the cancellation of the GIF searches
+
+const (
+	// Mattermost stops waiting for the response of a slash command or of a button action after this duration
+	integrationTimeout = 30 * time.Second
+	// The GIF searches are aborted a bit before, so that the user gets an error message instead of a timeout
+	searchTimeout = integrationTimeout - 5*time.Second
+	// The messages with GIF markers are held until their GIFs are found, so these searches get a much shorter deadline
+	inlineGifsTimeout = 5 * time.Second
+	// Maximum number of GIFs requested from the GIF provider by a search, shared by all the retries (relaxed keywords,
+	// GIFs already seen, GIFs without description) so that they don't multiply the calls to the provider
+	maxProviderCallsPerSearch = 20
+)
+
+// searchCallsKey is the context key of the number of calls to the GIF provider that a search can still make
+type searchCallsKey struct{}
+
+// newSearchContext returns the context of a GIF search, with a deadline and a maximum number of calls to the GIF provider
+func newSearchContext() (context.Context, context.CancelFunc) {
+	return newSearchContextWithTimeout(searchTimeout)
+}
+
+// newSearchContextWithTimeout returns the context of a GIF search that must end before the timeout
+func newSearchContextWithTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	remainingCalls := int32(maxProviderCallsPerSearch)
+	return context.WithValue(ctx, searchCallsKey{}, &remainingCalls), cancel
+}
+
+// takeProviderCall counts a call to the GIF provider for the search of the context, and returns false if the search
+// already made all its calls. The contexts that don't come from newSearchContext are not limited.
+func takeProviderCall(ctx context.Context) bool {
+	remainingCalls, ok := ctx.Value(searchCallsKey{}).(*int32)
+	return !ok || atomic.AddInt32(remainingCalls, -1) >= 0
+}
+
+// previewSearches keeps the cancel functions of the searches in progress for the preview posts,
+// so that canceling a preview aborts its shuffle. Only the searches of this server are known.
+type previewSearches struct {
+	lock     sync.Mutex
+	searches map[string]*previewSearch
+}
+
+type previewSearch struct {
+	cancel context.CancelFunc
+}
+
+// start returns the context of a new search for the preview post, which aborts the previous search of the post,
+// and the function to call when the search is done
+func (s *previewSearches) start(postID string) (context.Context, func()) {
+	ctx, cancel := newSearchContext()
+	search := &previewSearch{cancel: cancel}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.searches == nil {
+		s.searches = map[string]*previewSearch{}
+	}
+	if previous, ok := s.searches[postID]; ok {
+		previous.cancel()
+	}
+	s.searches[postID] = search
+
+	return ctx, func() {
+		cancel()
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.searches[postID] == search {
+			delete(s.searches, postID)
+		}
+	}
+}
+
+// cancel aborts the search in progress for the preview post, if there is one
+func (s *previewSearches) cancel(postID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if search, ok := s.searches[postID]; ok {
+		search.cancel()
+		delete(s.searches, postID)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSearchContextShouldHaveADeadlineBeforeTheIntegrationTimeout(t *testing.T) {
+	ctx, cancel := newSearchContext()
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.True(t, time.Until(deadline) < integrationTimeout)
+}
+
+func TestPreviewSearchesShouldCancelTheSearchOfThePost(t *testing.T) {
+	searches := previewSearches{}
+	ctx, done := searches.start(testPostID)
+	defer done()
+	otherCtx, otherDone := searches.start("otherPost")
+	defer otherDone()
+
+	searches.cancel(testPostID)
+
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Nil(t, otherCtx.Err())
+	assert.Len(t, searches.searches, 1)
+	searches.cancel("unknownPost")
+}
+
+func TestPreviewSearchesShouldCancelThePreviousSearchOfThePost(t *testing.T) {
+	searches := previewSearches{}
+	previousCtx, previousDone := searches.start(testPostID)
+	ctx, done := searches.start(testPostID)
+
+	assert.Equal(t, context.Canceled, previousCtx.Err())
+	previousDone()
+	assert.Nil(t, ctx.Err())
+	assert.Len(t, searches.searches, 1)
+
+	done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Empty(t, searches.searches)
+}
+
+func TestSearchRelaxedGifShouldStopAfterTheMaximumNumberOfProviderCalls(t *testing.T) {
+	_, p := initMockAPI()
+	p.getSnapshot().configuration.RequireGifDescription = true
+	gifs := []*provider.GifResult{}
+	for i := 0; i < 100; i++ {
+		gifs = append(gifs, &provider.GifResult{URL: "https://gif.fr/" + strconv.Itoa(i)})
+	}
+	gifProvider := &mockGifProviderCounting{mockGifProviderSequence: mockGifProviderSequence{gifs: gifs}}
+	setMockGifProvider(p, gifProvider)
+	ctx, cancel := newSearchContext()
+	defer cancel()
+	cursor := ""
+
+	gif, _, err := p.searchRelaxedGif(ctx, p.getSnapshot(), provider.Query{Keywords: "happy dancing kitties"}, &cursor, testChannelID, nil, false)
+
+	assert.Nil(t, err)
+	assert.Nil(t, gif)
+	assert.Equal(t, int32(maxProviderCallsPerSearch), gifProvider.calls)
+}
+
+func TestTakeProviderCallShouldNotLimitTheOtherContexts(t *testing.T) {
+	for i := 0; i <= maxProviderCallsPerSearch; i++ {
+		assert.True(t, takeProviderCall(context.Background()))
+	}
+}
+
+func TestNewSearchContextWithTimeoutShouldHaveTheInlineGifsDeadline(t *testing.T) {
+	ctx, cancel := newSearchContextWithTimeout(inlineGifsTimeout)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.True(t, time.Until(deadline) <= inlineGifsTimeout)
+	assert.True(t, takeProviderCall(ctx))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+)
+
+// Contains what's related to telling the GIF provider which of its GIFs are posted
+
+// Maximum duration of the report of a posted GIF to its provider
+const shareReportTimeout = 10 * time.Second
+
+// reportShare tells the GIF provider that the GIF it found was posted, if the provider wants to know.
+// The report is sent in the background so that it never delays the post.
+func (p *Plugin) reportShare(snapshot *pluginSnapshot, preview bool, share provider.Share) {
+	reporter, ok := snapshot.getSearchProvider(preview).(provider.ShareReporter)
+	if !ok || (share.GifID == "" && share.ReportURL == "") {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shareReportTimeout)
+		defer cancel()
+		if appErr := reporter.ReportShare(ctx, share); appErr != nil {
+			p.API.LogWarn("Could not report the posted GIF to the GIF provider: " + appErr.Error())
+		}
+	}()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moussetc/mattermost-plugin-giphy/server/internal/provider"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockShareReporter returns the GIFs of the sequence and sends the reported shares to a channel
+type mockShareReporter struct {
+	mockGifProviderSequence
+	shares chan provider.Share
+}
+
+func newMockShareReporter(gifs ...*provider.GifResult) *mockShareReporter {
+	return &mockShareReporter{mockGifProviderSequence: mockGifProviderSequence{gifs: gifs}, shares: make(chan provider.Share, 1)}
+}
+
+func (m *mockShareReporter) ReportShare(ctx context.Context, share provider.Share) *model.AppError {
+	m.shares <- share
+	return nil
+}
+
+// waitForShare returns the share reported in the background, or fails the test
+func waitForShare(t *testing.T, reporter *mockShareReporter) *provider.Share {
+	select {
+	case share := <-reporter.shares:
+		return &share
+	case <-time.After(time.Second):
+		assert.Fail(t, "The share was not reported")
+		return nil
+	}
+}
+
+func TestExecuteCommandGifShouldReportTheShare(t *testing.T) {
+	api, p := initMockAPI()
+	reporter := newMockShareReporter(&provider.GifResult{ID: "gif42", Provider: "tenor", URL: testGifURL, ShareReportURL: "https://report.fr"})
+	setMockGifProvider(p, reporter)
+	mockRecentGifs(api, nil)
+
+	_, err := p.executeCommandGif(context.Background(), p.getSnapshot(), provider.Query{Keywords: testKeywords, Locale: "fr"}, "", testArgs)
+
+	assert.Nil(t, err)
+	if share := waitForShare(t, reporter); share != nil {
+		assert.Equal(t, provider.Share{Provider: "tenor", GifID: "gif42", Query: provider.Query{Keywords: testKeywords, Locale: "fr"}, ReportURL: "https://report.fr"}, *share)
+	}
+}
+
+func TestHandleSendShouldReportTheShareOfASearchedGif(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("DeleteEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	mockRecentGifs(api, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+	reporter := newMockShareReporter()
+	setMockGifProvider(&p, reporter)
+	request := generateTestIntegrationRequest()
+	request.Source = sourceSearch
+	request.GifID = "gif42"
+	request.Provider = "tenor"
+
+	(&defaultHTTPHandler{}).handleSend(&p, p.getSnapshot(), httptest.NewRecorder(), request)
+
+	if share := waitForShare(t, reporter); share != nil {
+		assert.Equal(t, provider.Share{Provider: "tenor", GifID: "gif42", Query: provider.Query{Keywords: testKeywords, Locale: testLanguage}}, *share)
+	}
+}
+
+func TestHandleSendShouldNotReportTheShareOfASavedGif(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("DeleteEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+	mockRecentGifs(api, nil)
+	p := Plugin{}
+	p.SetAPI(api)
+	reporter := newMockShareReporter()
+	setMockGifProvider(&p, reporter)
+	request := generateTestIntegrationRequest()
+	request.Source = sourceFavorites
+	request.GifID = "gif42"
+
+	(&defaultHTTPHandler{}).handleSend(&p, p.getSnapshot(), httptest.NewRecorder(), request)
+
+	select {
+	case <-reporter.shares:
+		assert.Fail(t, "The share of a saved GIF was reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+}